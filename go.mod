@@ -4,8 +4,15 @@ go 1.15
 
 require (
 	github.com/gordonklaus/portaudio v0.0.0-20200911161147-bb74aa485641
+	github.com/gorilla/websocket v1.4.2
 	github.com/knadh/koanf v0.16.0
+	github.com/pion/interceptor v0.0.13
+	github.com/pion/rtcp v1.2.6
+	github.com/pion/rtp v1.6.5
+	github.com/pion/sdp/v3 v3.0.4
+	github.com/pion/webrtc/v3 v3.0.32
 	github.com/sirupsen/logrus v1.2.0
 	github.com/spf13/cobra v1.1.3
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
 	gopkg.in/hraban/opus.v2 v2.0.0-20210415224706-ab1467d63813
 )
@@ -0,0 +1,285 @@
+package call
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/signaling"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+var (
+	// ErrHungUp occurs when the remote peer leaves before the call is connected.
+	ErrHungUp = errors.New("remote peer has hung up")
+	// ErrSignalingClosed occurs when signaling connection is lost before the call is connected.
+	ErrSignalingClosed = errors.New("signaling connection is closed")
+	// ErrRoomFull occurs when joining a room which already has two participants.
+	ErrRoomFull = errors.New("room already has a call, use an SFU for more than two participants")
+)
+
+// Call is a one to one call negotiated through a kenny signaling server.
+// the client who joins the room later sends the offer to the first client in the room.
+// a room holds a single call: Connect fails if there are already two clients in it, and messages
+// from anyone other than the remote peer are ignored.
+type Call struct {
+	client *signaling.Client
+	peer   *transport.Peer
+
+	mu sync.Mutex
+	// remote is the remote peer's id, it's empty until an offer is sent or received.
+	remote string
+	// described is set when local description is sent, candidates are queued in local until then.
+	described bool
+	local     []webrtc.ICECandidateInit
+	// remoteDescribed is set when remote description is set, remote candidates are queued in pending until then.
+	remoteDescribed bool
+	pending         []webrtc.ICECandidateInit
+
+	hangup     chan struct{}
+	hangupOnce sync.Once
+	err        error
+}
+
+// New creates a Call which negotiates peer's connection through client.
+func New(client *signaling.Client, peer *transport.Peer) *Call {
+	c := &Call{
+		client: client,
+		peer:   peer,
+		hangup: make(chan struct{}),
+	}
+
+	peer.OnICECandidate(c.onICECandidate)
+
+	return c
+}
+
+// Remote returns the remote peer's id.
+func (c *Call) Remote() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.remote
+}
+
+// Hangup is closed when the remote peer leaves the room or the connection fails.
+func (c *Call) Hangup() <-chan struct{} {
+	return c.hangup
+}
+
+// Connect waits for a peer in the room, negotiates the connection and returns once it's established.
+// signaling messages are handled in background for the rest of the call.
+func (c *Call) Connect(ctx context.Context) error {
+	peers := c.client.Peers()
+	if len(peers) > 1 {
+		return fmt.Errorf("%w: room %s has %d participants", ErrRoomFull, c.client.Room(), len(peers))
+	}
+
+	go c.signal()
+
+	go func() {
+		select {
+		case <-c.peer.Done():
+			c.end(nil)
+		case <-c.hangup:
+		}
+	}()
+
+	if len(peers) == 1 {
+		if err := c.offer(peers[0]); err != nil {
+			return err
+		}
+	} else {
+		log.Infof("waiting for someone to join room %s", c.client.Room())
+	}
+
+	select {
+	case <-c.peer.Connected():
+		log.Infof("call with %s is connected", c.Remote())
+
+		return nil
+	case <-c.hangup:
+		if c.err != nil {
+			return c.err
+		}
+
+		return ErrHungUp
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Call) end(err error) {
+	c.hangupOnce.Do(func() {
+		c.err = err
+		close(c.hangup)
+	})
+}
+
+func (c *Call) offer(remote string) error {
+	c.mu.Lock()
+	c.remote = remote
+	c.mu.Unlock()
+
+	offer, err := c.peer.CreateOffer()
+	if err != nil {
+		return err
+	}
+
+	return c.describe(signaling.Message{Type: signaling.TypeOffer, To: remote, SDP: &offer})
+}
+
+func (c *Call) answer(m signaling.Message) error {
+	if err := c.setRemoteDescription(m); err != nil {
+		return err
+	}
+
+	answer, err := c.peer.CreateAnswer()
+	if err != nil {
+		return err
+	}
+
+	return c.describe(signaling.Message{Type: signaling.TypeAnswer, To: m.From, SDP: &answer})
+}
+
+// describe sends local description and then the candidates which were gathered before it.
+func (c *Call) describe(m signaling.Message) error {
+	if err := c.client.Send(m); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.described = true
+	local := c.local
+	c.local = nil
+	c.mu.Unlock()
+
+	for i := range local {
+		if err := c.client.Send(signaling.Message{
+			Type: signaling.TypeCandidate, To: m.To, Candidate: &local[i],
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Call) setRemoteDescription(m signaling.Message) error {
+	if err := c.peer.SetRemoteDescription(*m.SDP); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.remoteDescribed = true
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	for _, candidate := range pending {
+		if err := c.peer.AddICECandidate(candidate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Call) onICECandidate(candidate *webrtc.ICECandidate) {
+	if candidate == nil {
+		return
+	}
+
+	init := candidate.ToJSON()
+
+	c.mu.Lock()
+	if !c.described {
+		c.local = append(c.local, init)
+		c.mu.Unlock()
+
+		return
+	}
+	remote := c.remote
+	c.mu.Unlock()
+
+	if err := c.client.Send(signaling.Message{Type: signaling.TypeCandidate, To: remote, Candidate: &init}); err != nil {
+		log.Warnf("failed to send ice candidate: %s", err)
+	}
+}
+
+// signal handles signaling messages until the signaling connection is closed.
+//
+//nolint:cyclop
+func (c *Call) signal() {
+	for m := range c.client.Messages() {
+		remote := c.Remote()
+
+		if m.From != "" && remote != "" && m.From != remote {
+			log.Debugf("ignored %s message from %s, the call is with %s", m.Type, m.From, remote)
+
+			continue
+		}
+
+		var err error
+
+		switch m.Type {
+		case signaling.TypePeerJoin:
+			log.Infof("%s has joined the room", m.From)
+		case signaling.TypePeerLeave:
+			if remote != "" {
+				log.Infof("%s has left the room", m.From)
+				c.end(nil)
+			}
+		case signaling.TypeOffer:
+			if m.SDP == nil {
+				continue
+			}
+
+			c.mu.Lock()
+			c.remote = m.From
+			c.mu.Unlock()
+
+			err = c.answer(m)
+		case signaling.TypeAnswer:
+			if m.SDP != nil {
+				err = c.setRemoteDescription(m)
+			}
+		case signaling.TypeCandidate:
+			if m.Candidate != nil {
+				err = c.addICECandidate(*m.Candidate)
+			}
+		case signaling.TypeError:
+			log.Warnf("signaling server: %s", m.Error)
+		}
+
+		if err != nil {
+			c.end(err)
+
+			return
+		}
+	}
+
+	select {
+	case <-c.peer.Connected():
+		// the call goes on without signaling.
+		log.Warn("signaling connection is closed")
+	default:
+		c.end(ErrSignalingClosed)
+	}
+}
+
+func (c *Call) addICECandidate(candidate webrtc.ICECandidateInit) error {
+	c.mu.Lock()
+	if !c.remoteDescribed {
+		c.pending = append(c.pending, candidate)
+		c.mu.Unlock()
+
+		return nil
+	}
+	c.mu.Unlock()
+
+	return c.peer.AddICECandidate(candidate)
+}
@@ -0,0 +1,186 @@
+package call_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/smf8/kenny/internal/app/kenny/call"
+	"github.com/smf8/kenny/internal/app/kenny/signaling"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+func dial(t *testing.T, url string) *signaling.Client {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := signaling.Dial(ctx, url, "room")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func newPeer(t *testing.T) *transport.Peer {
+	t.Helper()
+
+	p, err := transport.NewPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+// receive returns the client's next message, which must have the given type.
+func receive(t *testing.T, c *signaling.Client, want signaling.MessageType) signaling.Message {
+	t.Helper()
+
+	select {
+	case m := <-c.Messages():
+		if m.Type != want {
+			t.Fatalf("received %+v, want %s", m, want)
+		}
+
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatalf("didn't receive %s", want)
+	}
+
+	return signaling.Message{}
+}
+
+// TestCallQueuesCandidates connects a Call to a hand written answerer. the answerer's description doesn't contain
+// candidates and it trickles them before the answer, so the call only connects if it queues them until the
+// answer is set. the call's own candidates must not be sent before its offer either.
+//
+//nolint:funlen
+func TestCallQueuesCandidates(t *testing.T) {
+	server := signaling.NewServer()
+	ts := httptest.NewServer(server)
+
+	defer ts.Close()
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	answerer := dial(t, url)
+	defer answerer.Close()
+
+	answererPeer := newPeer(t)
+	defer answererPeer.Close()
+
+	candidates := make(chan *webrtc.ICECandidate, 16)
+	answererPeer.OnICECandidate(func(c *webrtc.ICECandidate) { candidates <- c })
+
+	caller := dial(t, url)
+	defer caller.Close()
+
+	callerPeer := newPeer(t)
+	defer callerPeer.Close()
+
+	c := call.New(caller, callerPeer)
+	connected := make(chan error, 1)
+
+	go func() {
+		connected <- c.Connect(ctx)
+	}()
+
+	receive(t, answerer, signaling.TypePeerJoin)
+
+	offer := receive(t, answerer, signaling.TypeOffer)
+	if offer.From != caller.ID() {
+		t.Fatalf("offer is from %s, want %s", offer.From, caller.ID())
+	}
+
+	if err := answererPeer.SetRemoteDescription(*offer.SDP); err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := answererPeer.CreateAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(answer.SDP, "a=candidate") {
+		t.Fatal("answer has candidates, they wouldn't need to be trickled")
+	}
+
+	// every candidate is gathered and sent before the answer.
+	for candidate := range candidates {
+		if candidate == nil {
+			break
+		}
+
+		init := candidate.ToJSON()
+		if err := answerer.Send(signaling.Message{
+			Type: signaling.TypeCandidate, To: caller.ID(), Candidate: &init,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := answerer.Send(signaling.Message{Type: signaling.TypeAnswer, To: caller.ID(), SDP: &answer}); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for m := range answerer.Messages() {
+			if m.Type == signaling.TypeCandidate {
+				if err := answererPeer.AddICECandidate(*m.Candidate); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}()
+
+	select {
+	case err := <-connected:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-ctx.Done():
+		t.Fatal("call didn't connect")
+	}
+
+	if c.Remote() != answerer.ID() {
+		t.Fatalf("call is with %s, want %s", c.Remote(), answerer.ID())
+	}
+
+	select {
+	case <-answererPeer.Connected():
+	case <-ctx.Done():
+		t.Fatal("answerer didn't connect")
+	}
+
+	// a third participant can't join the call.
+	third := dial(t, url)
+	defer third.Close()
+
+	thirdPeer := newPeer(t)
+	defer thirdPeer.Close()
+
+	if err := call.New(third, thirdPeer).Connect(ctx); !errors.Is(err, call.ErrRoomFull) {
+		t.Fatalf("joining a full room returned %v, want %v", err, call.ErrRoomFull)
+	}
+
+	if err := answerer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-c.Hangup():
+	case <-ctx.Done():
+		t.Fatal("call didn't end after the answerer left")
+	}
+}
@@ -0,0 +1,378 @@
+package call
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/config"
+	"github.com/smf8/kenny/internal/app/kenny/controls"
+	"github.com/smf8/kenny/internal/app/kenny/device"
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/interrupt"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+	"github.com/smf8/kenny/internal/app/kenny/stats"
+	"github.com/smf8/kenny/internal/app/kenny/terminal"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/portaudio"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	room          string
+	server        string
+	sfu           string
+	session       string
+	rtpListen     string
+	rtpRemote     string
+	sdpIn         string
+	sdpOut        string
+	input         string
+	output        string
+	statsJSON     string
+	statsInterval time.Duration
+	fec           bool
+	packetLoss    int
+	vad           bool
+	aec           bool
+	noise         bool
+	gate          bool
+	agc           bool
+	pushToTalk    bool
+}
+
+// monitors are the processors which call statistics are read from. they can be nil.
+type monitors struct {
+	vad    *dsp.VAD
+	agc    *dsp.AGC
+	mute   *dsp.Mute
+	input  *dsp.Meter
+	output *dsp.Meter
+}
+
+// setupFEC enables in-band FEC on encoder. a negative packetLoss follows remote receiver reports until ctx is done.
+func setupFEC(ctx context.Context, opts options, encoder *encoding.Encoder, transportStats func() transport.Stats) error {
+	if !opts.fec {
+		return nil
+	}
+
+	if err := encoder.SetFEC(true); err != nil {
+		return err
+	}
+
+	if opts.packetLoss >= 0 {
+		return encoder.SetPacketLoss(opts.packetLoss)
+	}
+
+	go func() {
+		ticker := time.NewTicker(opts.statsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				loss := int(math.Ceil(transportStats().RemoteFractionLost * 100)) //nolint:gomnd
+				if err := encoder.SetPacketLoss(loss); err != nil {
+					log.Error(err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// report runs a stats reporter for the call until ctx is done. the returned function waits for it to stop.
+func report(ctx context.Context, opts options, transportStats func() transport.Stats, remote *participants,
+	m monitors) (wait func(), err error) {
+	reporter := stats.NewReporter(opts.statsInterval, transportStats, remote.decoderStats)
+	// keyboard controls put the terminal into raw mode, which needs carriage returns.
+	reporter.Line = terminal.NewLineWriter(os.Stderr)
+	reporter.Playout = remote.playoutStats
+	reporter.VAD = m.vad
+	reporter.AGC = m.agc
+	reporter.Mute = m.mute
+	reporter.Input = m.input
+	reporter.Output = m.output
+
+	var file *os.File
+
+	if opts.statsJSON != "" {
+		file, err = os.Create(opts.statsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stats file: %w", err)
+		}
+
+		reporter.JSON = file
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if err := reporter.Run(ctx); err != nil {
+			log.Error(err)
+		}
+
+		if file != nil {
+			if err := file.Close(); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	return func() { <-done }, nil
+}
+
+// start joins the room, SFU session or RTP session, connects to the remote side and runs the call until either side hangs up.
+//
+//nolint:funlen
+func start(cfg config.Config, opts options) error {
+	ctx, cancel := interrupt.Context(context.Background())
+	defer cancel()
+
+	cleanup, err := device.Init(opts.input, opts.output)
+	if err != nil {
+		return err
+	}
+
+	defer cleanup()
+
+	settings := audio.StreamSettings{
+		SampleRate:      cfg.Recorder.SampleRate,
+		Channels:        cfg.Recorder.NumberOfChannels,
+		FramesPerBuffer: cfg.Recorder.FramesPerBuffer,
+	}
+
+	recorder, err := device.NewRecorder(opts.input, settings)
+	if err != nil {
+		return err
+	}
+
+	player, err := device.NewPlayer(opts.output, settings)
+	if err != nil {
+		return err
+	}
+
+	encoder, err := encoding.NewEncoder(int(settings.SampleRate), settings.Channels, cfg.Recorder.OpusFrameSizeMs)
+	if err != nil {
+		return err
+	}
+
+	conn, err := connect(ctx, cfg, opts)
+	if err != nil {
+		return err
+	}
+
+	defer conn.close()
+
+	recordID, err := recorder.OpenStream()
+	if err != nil {
+		return err
+	}
+
+	defer recorder.CloseStream(recordID)
+
+	playID, err := player.OpenStream()
+	if err != nil {
+		return err
+	}
+
+	defer player.CloseStream(playID)
+
+	if err := setupFEC(ctx, opts, encoder, conn.stats); err != nil {
+		return err
+	}
+
+	source := pipeline.NewSource(recorder, recordID)
+	p := pipeline.New(source)
+	input := source.Out()
+
+	// echo is removed before voice activity detection, so far end's voice isn't detected as speech.
+	var aec *dsp.EchoCanceller
+
+	if opts.aec {
+		aec = dsp.NewEchoCanceller(int(settings.SampleRate), settings.Channels, cfg.AEC.FilterLength, cfg.AEC.MaxDelay)
+
+		cancelEcho := pipeline.NewProcess("aec", aec, input)
+		p.Add(cancelEcho)
+		input = cancelEcho.Out()
+	}
+
+	// noise suppression and gate are always in the pipeline, so they can be toggled during the call.
+	suppressor := dsp.NewNoiseSuppressor(int(settings.SampleRate), settings.Channels, cfg.Noise.Suppression)
+	suppressor.SetEnabled(opts.noise)
+
+	gate := dsp.NewNoiseGate(int(settings.SampleRate), settings.Channels)
+	gate.Threshold = cfg.Gate.Threshold
+	gate.Range = cfg.Gate.Range
+	gate.Hold = cfg.Gate.Hold
+	gate.SetEnabled(opts.gate)
+
+	// gain control is after noise suppression, so it doesn't amplify noise, and the meter shows what is sent.
+	agc := dsp.NewAGC(int(settings.SampleRate), settings.Channels)
+	agc.Target = cfg.AGC.Target
+	agc.MaxGain = cfg.AGC.MaxGain
+	agc.SetEnabled(opts.agc)
+
+	// muted audio is silence instead of a closed stream, so it's sent as DTX with vad.
+	mute := dsp.NewMute(int(settings.SampleRate), settings.Channels)
+	mute.SetPushToTalk(opts.pushToTalk)
+
+	m := monitors{
+		agc:    agc,
+		mute:   mute,
+		input:  dsp.NewMeter(int(settings.SampleRate), settings.Channels),
+		output: dsp.NewMeter(int(settings.SampleRate), settings.Channels),
+	}
+
+	suppress := pipeline.NewProcess("noise suppression", suppressor, input)
+	applyGate := pipeline.NewProcess("noise gate", gate, suppress.Out())
+	applyAGC := pipeline.NewProcess("agc", agc, applyGate.Out())
+	// the meter is before mute, so it shows whether the microphone picks up audio while it's muted.
+	meterInput := pipeline.NewProcess("input meter", m.input, applyAGC.Out())
+	applyMute := pipeline.NewProcess("mute", mute, meterInput.Out())
+	p.Add(suppress, applyGate, applyAGC, meterInput, applyMute)
+	input = applyMute.Out()
+
+	keys := controls.New(mute, cancel)
+	keys.Toggle('n', "noise suppression", suppressor.Enabled, suppressor.SetEnabled)
+	keys.Toggle('g', "noise gate", gate.Enabled, gate.SetEnabled)
+	keys.Toggle('a', "agc", agc.Enabled, agc.SetEnabled)
+
+	if opts.vad {
+		if err := encoder.SetDTX(true); err != nil {
+			return err
+		}
+
+		m.vad = dsp.NewVAD(int(settings.SampleRate), settings.Channels)
+		m.vad.Threshold = cfg.VAD.Threshold
+		m.vad.Hangover = cfg.VAD.Hangover
+
+		detect := pipeline.NewProcess("vad", m.vad, input)
+		p.Add(detect)
+		input = detect.Out()
+	}
+
+	encode := pipeline.NewEncode(encoder, input)
+	send := transport.NewSender(conn.writer, encode.Out())
+
+	// every participant is decoded separately and mixed into a single player stream.
+	mixer := pipeline.NewMixer(int(settings.SampleRate), settings.Channels)
+	meterOutput := pipeline.NewProcess("output meter", m.output, mixer.Out())
+	p.Add(meterOutput)
+	output := meterOutput.Out()
+
+	if aec != nil {
+		// played audio is the echo reference.
+		render := pipeline.NewProcess("aec render", pipeline.ProcessorFunc(aec.Render), output)
+		p.Add(render)
+		output = render.Out()
+	}
+
+	sink := pipeline.NewSink(player, playID, settings.BufferSize(), output)
+
+	remote := newParticipants(cfg, mixer)
+	go remote.receive(ctx, conn.tracks)
+
+	go func() {
+		select {
+		case <-conn.hangup:
+			log.Info("call is ended by remote peer")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	wait, err := report(ctx, opts, conn.stats, remote, m)
+	if err != nil {
+		return err
+	}
+
+	controlsDone := make(chan struct{})
+
+	go func() {
+		defer close(controlsDone)
+
+		keys.Run(ctx)
+	}()
+
+	p.Add(encode, send, mixer, sink)
+	err = p.Run(ctx)
+
+	cancel()
+	wait()
+	<-controlsDone
+
+	return err
+}
+
+// Register registers call command to the root kenny command.
+func Register(root *cobra.Command, cfg config.Config) {
+	var opts options
+
+	cmd := &cobra.Command{
+		Use:   "call [room]",
+		Short: "joins a room on a kenny signaling server and starts a voice call with the other participant",
+		Long: "joins a room on a kenny signaling server and starts a voice call with the other participant.\n" +
+			"with --sfu and --session, joins an ion-sfu session instead and talks with every participant.\n" +
+			"with --rtp-listen, sends and receives plain RTP (i.e. with ffmpeg or GStreamer) without signaling.\n" +
+			"the call ends on q, Ctrl-C or when the other participant hangs up.\n" +
+			"during the call, press m to mute, n, g or a to toggle noise suppression, noise gate or agc.\n" +
+			"with --push-to-talk, the microphone is only sent while space is held, or from a tap of space to the next.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				opts.room = args[0]
+			}
+
+			if err := validate(opts); err != nil {
+				return err
+			}
+
+			return start(cfg, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.server, "server", "s", cfg.Signal.Server, "signaling server url")
+	cmd.Flags().StringVar(&opts.sfu, "sfu", "", "ion-sfu json-rpc url, i.e. ws://localhost:7000/ws")
+	cmd.Flags().StringVar(&opts.session, "session", "", "ion-sfu session id to join")
+	cmd.Flags().StringVar(&opts.rtpListen, "rtp-listen", "",
+		"send and receive plain RTP on this address (i.e. :5004) instead of webrtc, RTCP uses the next port")
+	cmd.Flags().StringVar(&opts.rtpRemote, "rtp-remote", "", "remote address of plain RTP, i.e. host:5006")
+	cmd.Flags().StringVar(&opts.sdpIn, "sdp-in", "", "read remote address and payload type of plain RTP from this sdp file")
+	cmd.Flags().StringVar(&opts.sdpOut, "sdp-out", "", "write the local plain RTP session description to this sdp file")
+	cmd.Flags().StringVarP(&opts.input, "input", "i", portaudio.DeviceNameDefault,
+		"input device name, use file:<path> to read from a wav file")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", portaudio.DeviceNameDefault,
+		"output device name, use file:<path> to write to a wav file")
+	cmd.Flags().StringVar(&opts.statsJSON, "stats-json", "",
+		"write call statistics to this file as one JSON object per line")
+	cmd.Flags().DurationVar(&opts.statsInterval, "stats-interval", stats.DefaultInterval,
+		"refresh interval of call statistics")
+	cmd.Flags().BoolVar(&opts.fec, "fec", cfg.FEC.Enabled, "enable opus in-band forward error correction")
+	cmd.Flags().IntVar(&opts.packetLoss, "packet-loss", cfg.FEC.PacketLoss,
+		"expected packet loss percentage for FEC, a negative value follows remote receiver reports")
+	cmd.Flags().BoolVar(&opts.vad, "vad", cfg.VAD.Enabled,
+		"detect voice activity and stop sending packets during silence (opus DTX)")
+	cmd.Flags().BoolVar(&opts.aec, "aec", cfg.AEC.Enabled,
+		"cancel echo of the played audio from the recorded audio, i.e. when using speakers")
+	cmd.Flags().BoolVar(&opts.noise, "noise-suppression", cfg.Noise.Enabled,
+		"suppress stationary background noise of the recorded audio, i.e. fans")
+	cmd.Flags().BoolVar(&opts.gate, "noise-gate", cfg.Gate.Enabled,
+		"attenuate the recorded audio while it's quieter than the gate threshold")
+	cmd.Flags().BoolVar(&opts.agc, "agc", cfg.AGC.Enabled,
+		"normalize loudness of the recorded audio, i.e. for quiet microphones")
+	cmd.Flags().BoolVar(&opts.pushToTalk, "push-to-talk", false,
+		"send the recorded audio only while space is held, or toggle it by tapping space")
+
+	root.AddCommand(cmd)
+}
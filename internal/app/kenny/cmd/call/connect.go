@@ -0,0 +1,239 @@
+package call
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/call"
+	"github.com/smf8/kenny/internal/app/kenny/config"
+	"github.com/smf8/kenny/internal/app/kenny/sfu"
+	"github.com/smf8/kenny/internal/app/kenny/signaling"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+const dialTimeout = 10 * time.Second
+
+var (
+	// ErrNoRoom occurs when neither a room, an SFU session nor an RTP address is given.
+	ErrNoRoom = errors.New("a room is required, or an sfu session with --sfu and --session, or --rtp-listen")
+	// ErrNoSession occurs when an SFU url is given without a session id.
+	ErrNoSession = errors.New("--session is required with --sfu")
+	// ErrConflictingModes occurs when more than one of a room, an SFU url and an RTP address is given.
+	ErrConflictingModes = errors.New("only one of a room, --sfu and --rtp-listen can be used")
+	// ErrNoRTPRemote occurs when an RTP address is given without the remote address.
+	ErrNoRTPRemote = errors.New("--rtp-remote or --sdp-in is required with --rtp-listen")
+)
+
+// trackSource provides remote tracks of a call, i.e. transport.Peer or transport.RTPSession.
+type trackSource interface {
+	Tracks() <-chan *transport.RemoteTrack
+	Done() <-chan struct{}
+}
+
+// connection is a connected call: one to one through a signaling server, through an SFU or over plain RTP.
+type connection struct {
+	// writer sends the local stream and tracks receives remote streams.
+	writer transport.FrameWriter
+	tracks trackSource
+	stats  func() transport.Stats
+	// hangup is closed when the call is ended by the other side.
+	hangup <-chan struct{}
+	close  func()
+}
+
+func validate(opts options) error {
+	modes := 0
+
+	for _, mode := range []string{opts.room, opts.sfu, opts.rtpListen} {
+		if mode != "" {
+			modes++
+		}
+	}
+
+	switch {
+	case modes == 0:
+		return ErrNoRoom
+	case modes > 1:
+		return ErrConflictingModes
+	case opts.sfu != "" && opts.session == "":
+		return ErrNoSession
+	case opts.rtpListen != "" && opts.rtpRemote == "" && opts.sdpIn == "":
+		return ErrNoRTPRemote
+	}
+
+	return nil
+}
+
+func connect(ctx context.Context, cfg config.Config, opts options) (connection, error) {
+	switch {
+	case opts.sfu != "":
+		return joinSession(ctx, cfg, opts)
+	case opts.rtpListen != "":
+		return listenRTP(opts)
+	default:
+		return joinRoom(ctx, cfg, opts)
+	}
+}
+
+// joinRoom joins a room on a kenny signaling server and connects to the other participant.
+func joinRoom(ctx context.Context, cfg config.Config, opts options) (connection, error) {
+	dialCtx, dialCancel := context.WithTimeout(ctx, dialTimeout)
+	client, err := signaling.Dial(dialCtx, opts.server, opts.room)
+
+	dialCancel()
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	log.Infof("joined room %s as %s", client.Room(), client.ID())
+
+	peer, err := transport.NewPeer(cfg.WebRTC.ICEServers)
+	if err != nil {
+		_ = client.Close()
+
+		return connection{}, err
+	}
+
+	closeAll := func() {
+		_ = peer.Close()
+		_ = client.Close()
+	}
+
+	c := call.New(client, peer)
+	if err := c.Connect(ctx); err != nil {
+		closeAll()
+
+		return connection{}, err
+	}
+
+	return connection{
+		writer: peer,
+		tracks: peer,
+		stats:  peer.Stats,
+		hangup: c.Hangup(),
+		close:  closeAll,
+	}, nil
+}
+
+// joinSession joins an ion-sfu session with a publisher and a receive only subscriber peer.
+func joinSession(ctx context.Context, cfg config.Config, opts options) (connection, error) {
+	dialCtx, dialCancel := context.WithTimeout(ctx, dialTimeout)
+	conn, err := sfu.Dial(dialCtx, opts.sfu)
+
+	dialCancel()
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	publisher, err := transport.NewPeer(cfg.WebRTC.ICEServers)
+	if err != nil {
+		_ = conn.Close()
+
+		return connection{}, err
+	}
+
+	subscriber, err := transport.NewReceiveOnlyPeer(cfg.WebRTC.ICEServers)
+	if err != nil {
+		_ = publisher.Close()
+		_ = conn.Close()
+
+		return connection{}, err
+	}
+
+	closeAll := func() {
+		_ = publisher.Close()
+		_ = subscriber.Close()
+		_ = conn.Close()
+	}
+
+	s := sfu.New(conn, opts.session, publisher, subscriber)
+	if err := s.Join(ctx); err != nil {
+		closeAll()
+
+		return connection{}, err
+	}
+
+	log.Infof("joined session %s as %s", opts.session, s.UID())
+
+	return connection{
+		writer: publisher,
+		tracks: subscriber,
+		// send statistics are of publisher and receive statistics are of subscriber.
+		stats: func() transport.Stats {
+			return transport.CombineStats(publisher.Stats(), subscriber.Stats())
+		},
+		hangup: s.Done(),
+		close:  closeAll,
+	}, nil
+}
+
+// listenRTP starts a plain RTP session. remote address is either given or read from a session description file,
+// and the local session description is written to a file if it's requested.
+func listenRTP(opts options) (connection, error) {
+	remote := transport.RTPDescription{PayloadType: transport.OpusPayloadType}
+
+	if opts.sdpIn != "" {
+		data, err := ioutil.ReadFile(opts.sdpIn)
+		if err != nil {
+			return connection{}, fmt.Errorf("failed to read session description: %w", err)
+		}
+
+		if remote, err = transport.UnmarshalRTPDescription(data); err != nil {
+			return connection{}, err
+		}
+	}
+
+	if opts.rtpRemote != "" {
+		addr, err := net.ResolveUDPAddr("udp", opts.rtpRemote)
+		if err != nil {
+			return connection{}, fmt.Errorf("failed to resolve remote rtp address %s: %w", opts.rtpRemote, err)
+		}
+
+		remote.Addr = addr
+	}
+
+	session, err := transport.ListenRTP(opts.rtpListen, remote.Addr, remote.PayloadType)
+	if err != nil {
+		return connection{}, err
+	}
+
+	log.Infof("sending rtp to %s, listening on %s", remote.Addr, session.LocalAddr())
+
+	if opts.sdpOut != "" {
+		if err := writeSDP(opts.sdpOut, session.Description()); err != nil {
+			_ = session.Close()
+
+			return connection{}, err
+		}
+	}
+
+	return connection{
+		writer: session,
+		tracks: session,
+		stats:  session.Stats,
+		hangup: session.Hangup(),
+		close: func() {
+			_ = session.Close()
+		},
+	}, nil
+}
+
+func writeSDP(path string, description transport.RTPDescription) error {
+	data, err := description.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil { //nolint:gomnd
+		return fmt.Errorf("failed to write session description: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package call
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/config"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/jitter"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+// participants receives every remote track with its own jitter buffer and decoder and adds it to the mixer.
+type participants struct {
+	cfg   config.Config
+	mixer *pipeline.Mixer
+
+	mu   sync.Mutex
+	list []*participant
+}
+
+type participant struct {
+	decoder *encoding.Decoder
+	playout *jitter.Buffer
+	ended   bool
+}
+
+func newParticipants(cfg config.Config, mixer *pipeline.Mixer) *participants {
+	return &participants{
+		cfg:   cfg,
+		mixer: mixer,
+	}
+}
+
+// receive adds remote tracks as they start, until ctx is done or source is closed.
+func (p *participants) receive(ctx context.Context, source trackSource) {
+	for {
+		select {
+		case track := <-source.Tracks():
+			if err := p.add(ctx, track); err != nil {
+				log.Errorf("failed to receive track %s: %s", track.ID(), err)
+			}
+		case <-source.Done():
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *participants) add(ctx context.Context, track *transport.RemoteTrack) error {
+	decoder, err := encoding.NewDecoder(int(p.cfg.Recorder.SampleRate), p.cfg.Recorder.NumberOfChannels,
+		p.cfg.Recorder.OpusFrameSizeMs)
+	if err != nil {
+		return err
+	}
+
+	pt := &participant{
+		decoder: decoder,
+		playout: jitter.New(transport.OpusClockRate, p.cfg.Jitter.MinDelay, p.cfg.Jitter.MaxDelay),
+	}
+
+	receive := transport.NewReceiver(track, pt.playout)
+	decode := pipeline.NewFrameDecode(decoder, receive.Out())
+
+	if err := p.mixer.Add(track.StreamID(), decode.Out()); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.list = append(p.list, pt)
+	p.mu.Unlock()
+
+	log.Infof("participant %s has joined", track.StreamID())
+
+	go func() {
+		if err := pipeline.New(receive, decode).Run(ctx); err != nil {
+			log.Errorf("participant %s: %s", track.StreamID(), err)
+		}
+
+		p.mu.Lock()
+		pt.ended = true
+		p.mu.Unlock()
+
+		log.Infof("participant %s has left", track.StreamID())
+	}()
+
+	return nil
+}
+
+// decoderStats returns decoder counters of every participant, including the ones who have left.
+func (p *participants) decoderStats() encoding.DecoderStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var stats encoding.DecoderStats
+
+	for _, pt := range p.list {
+		s := pt.decoder.Stats()
+
+		stats.Frames += s.Frames
+		stats.Samples += s.Samples
+		stats.Concealed += s.Concealed
+		stats.Recovered += s.Recovered
+		stats.Comfort += s.Comfort
+	}
+
+	return stats
+}
+
+// playoutStats returns jitter buffer counters of every participant. queued packets, jitter and delay
+// are of the current participants, jitter and delay are the worst of them.
+func (p *participants) playoutStats() jitter.Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var stats jitter.Stats
+
+	for _, pt := range p.list {
+		s := pt.playout.Stats()
+
+		stats.Played += s.Played
+		stats.Lost += s.Lost
+		stats.Late += s.Late
+		stats.Discarded += s.Discarded
+
+		if pt.ended {
+			continue
+		}
+
+		stats.Queued += s.Queued
+
+		if s.Jitter > stats.Jitter {
+			stats.Jitter = s.Jitter
+		}
+
+		if s.TargetDelay > stats.TargetDelay {
+			stats.TargetDelay = s.TargetDelay
+		}
+	}
+
+	return stats
+}
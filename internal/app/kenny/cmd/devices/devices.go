@@ -3,43 +3,51 @@ package devices
 import (
 	"fmt"
 
-	"github.com/smf8/kenny/internal/app/kenny/audio"
+	"github.com/smf8/kenny/pkg/audio/portaudio"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 func main(deviceArg string) error {
-	if err := audio.InitPortAudio(); err != nil {
+	if err := portaudio.Init(); err != nil {
 		return err
 	}
 
-	defer audio.TerminatePortAudio()
+	defer func() {
+		if err := portaudio.Cleanup(); err != nil {
+			log.Error(err)
+		}
+	}()
 
 	switch deviceArg {
 	case "list":
-		devices, err := audio.DevicesInfo()
+		devices, err := portaudio.Devices()
 		if err != nil {
 			log.Errorf("failed to get audio device list: %s", err.Error())
 		}
 
 		for i := range devices {
-			fmt.Println(devices[i])
+			fmt.Println(portaudio.FormatDeviceInfo(devices[i]))
 		}
 	case "input":
-		device, err := audio.DefaultInputDevice()
+		device, err := portaudio.DefaultDevice(portaudio.RecordDeviceType)
 		if err != nil {
 			log.Errorf("failed to get default input audio device: %s", err.Error())
+
+			return nil
 		}
 
-		fmt.Println(audio.PrintDeviceInfo(device))
+		fmt.Println(portaudio.FormatDeviceInfo(device))
 	case "output":
-		device, err := audio.DefaultOutputDevice()
+		device, err := portaudio.DefaultDevice(portaudio.PlayDeviceType)
 		if err != nil {
 			log.Errorf("failed to get default output audio device: %s", err.Error())
+
+			return nil
 		}
 
-		fmt.Println(audio.PrintDeviceInfo(device))
+		fmt.Println(portaudio.FormatDeviceInfo(device))
 	}
 
 	return nil
@@ -52,6 +60,7 @@ func Register(root *cobra.Command) {
 		&cobra.Command{
 			Use:   "devices {list | input | output}",
 			Short: "this command will list available audio devices",
+			Long:  "device names can be passed to --input and --output flags of other commands.",
 			Args:  cobra.ExactArgs(1),
 			ValidArgs: []string{
 				"list",
@@ -0,0 +1,256 @@
+package play
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+	"github.com/smf8/kenny/pkg/audio/wav"
+)
+
+const (
+	// longest possible opus packet is 120 ms.
+	maxFrameSize = 5760
+	// opus decoder needs 80 ms of audio before a seek position to converge. (RFC 7845 section 4.6)
+	seekPreRoll  = 3840
+	wavChunkSize = 4096
+)
+
+// ErrUnknownFormat occurs when the file is neither an Ogg Opus nor a wav file.
+var ErrUnknownFormat = errors.New("unknown file format, only Ogg Opus and wav files are supported")
+
+// track describes an audio file. it's streamed from disk through a pipeline while it's played.
+type track struct {
+	path       string
+	ogg        bool
+	sampleRate int
+	channels   int
+	// frames is the track's duration in audio frames (samples per channel).
+	frames int
+	// head is the OpusHead of Ogg Opus files.
+	head encoding.OpusHead
+}
+
+// load reads the file's format and duration. it detects file format using its magic bytes.
+func load(path string) (*track, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	magic, err := r.Peek(4)
+	if err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	switch string(magic) {
+	case "OggS":
+		return loadOgg(path, r)
+	case "RIFF":
+		return loadWav(path, r)
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+// loadOgg reads Ogg Opus headers and finds the duration from the last granule position, without decoding.
+func loadOgg(path string, r io.Reader) (*track, error) {
+	ogg, err := encoding.NewOggReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	head := ogg.Stream().Head
+	if head.Channels > 2 {
+		return nil, encoding.ErrTooManyChannels
+	}
+
+	for {
+		_, err := ogg.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the last granule position marks the end of stream, any samples after it are discarded while playing.
+	frames := int(ogg.Granule()) - int(head.PreSkip)
+	if frames < 0 {
+		frames = 0
+	}
+
+	return &track{
+		path:       path,
+		ogg:        true,
+		sampleRate: encoding.OpusGranuleRate,
+		channels:   int(head.Channels),
+		frames:     frames,
+		head:       head,
+	}, nil
+}
+
+func loadWav(path string, r io.Reader) (*track, error) {
+	reader, err := wav.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	h := reader.Header()
+
+	return &track{
+		path:       path,
+		sampleRate: h.SampleRate,
+		channels:   h.Channels,
+		frames:     h.Frames(),
+	}, nil
+}
+
+// open opens the file at the given frame and returns the stages which decode it and their pcm port.
+// call the returned close function after the stages are finished.
+func (t *track) open(frame int) ([]pipeline.Stage, pipeline.PCMPort, func(), error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	var (
+		stages []pipeline.Stage
+		out    pipeline.PCMPort
+	)
+
+	if t.ogg {
+		stages, out, err = t.openOgg(f, frame)
+	} else {
+		stages, out, err = t.openWav(f, frame)
+	}
+
+	if err != nil {
+		f.Close()
+
+		return nil, nil, nil, err
+	}
+
+	return stages, out, func() { f.Close() }, nil
+}
+
+// openOgg skips packets which end before the seek position, leaving enough of them for decoder pre-roll.
+// decoded samples before the position, including pre-skip, are trimmed.
+func (t *track) openOgg(r io.Reader, frame int) ([]pipeline.Stage, pipeline.PCMPort, error) {
+	ogg, err := encoding.NewOggReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target := frame + int(t.head.PreSkip)
+	granule := 0
+
+	// packet durations are only known after reading them, so skipping stops a whole packet early.
+	for granule+maxFrameSize+seekPreRoll < target {
+		packet, err := ogg.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		samples, err := encoding.PacketSamples(packet)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		granule += samples
+	}
+
+	decoder, err := encoding.NewDecoder(encoding.OpusGranuleRate, t.channels, maxFrameSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source := pipeline.NewOggSource(ogg)
+	decode := pipeline.NewDecode(decoder, source.Out())
+	trim := pipeline.NewProcess("trim", &trimmer{
+		channels:  t.channels,
+		skip:      target - granule,
+		remaining: t.frames - frame,
+		gain:      t.head.OutputGain,
+	}, decode.Out())
+
+	return []pipeline.Stage{source, decode, trim}, trim.Out(), nil
+}
+
+func (t *track) openWav(r io.Reader, frame int) ([]pipeline.Stage, pipeline.PCMPort, error) {
+	reader, err := wav.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := reader.Skip(frame); err != nil {
+		return nil, nil, err
+	}
+
+	source := pipeline.NewWavSource(reader, wavChunkSize*t.channels)
+
+	return []pipeline.Stage{source}, source.Out(), nil
+}
+
+// trimmer discards decoded samples before the playback position and after the end of stream,
+// and applies OpusHead output gain.
+type trimmer struct {
+	channels int
+	// skip and remaining are in frames.
+	skip      int
+	remaining int
+	gain      int16
+}
+
+// Process implements pipeline.Processor.
+func (t *trimmer) Process(pcm []int16) ([]int16, error) {
+	frames := len(pcm) / t.channels
+
+	skip := t.skip
+	if skip > frames {
+		skip = frames
+	}
+
+	t.skip -= skip
+	pcm = pcm[skip*t.channels:]
+
+	if frames-skip > t.remaining {
+		pcm = pcm[:t.remaining*t.channels]
+	}
+
+	t.remaining -= len(pcm) / t.channels
+
+	applyGain(pcm, t.gain)
+
+	return pcm, nil
+}
+
+// applyGain applies OpusHead output gain which is in Q7.8 dB.
+func applyGain(pcm []int16, gain int16) {
+	if gain == 0 {
+		return
+	}
+
+	factor := math.Pow(10, float64(gain)/(20*256)) //nolint:gomnd
+
+	for i, s := range pcm {
+		v := float64(s) * factor
+		v = math.Max(math.MinInt16, math.Min(math.MaxInt16, v))
+		pcm[i] = int16(v)
+	}
+}
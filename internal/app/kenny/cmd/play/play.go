@@ -0,0 +1,308 @@
+package play
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/config"
+	"github.com/smf8/kenny/internal/app/kenny/device"
+	"github.com/smf8/kenny/internal/app/kenny/interrupt"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+	"github.com/smf8/kenny/internal/app/kenny/terminal"
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/portaudio"
+	"github.com/spf13/cobra"
+)
+
+const (
+	seekStep        = 5 * time.Second
+	longSeekStep    = 30 * time.Second
+	refreshInterval = 100 * time.Millisecond
+)
+
+// session holds playback state of a track.
+type session struct {
+	track    *track
+	player   *countingPlayer
+	streamID int
+	chunk    int
+	paused   bool
+
+	// from is the frame playback was started from after the last seek or pause.
+	// base is number of samples played before it.
+	from int
+	base int64
+}
+
+// countingPlayer counts samples which are played, so playback position is known while the pipeline runs.
+type countingPlayer struct {
+	audio.Player
+	samples int64
+}
+
+// Play implements audio.Player.
+func (c *countingPlayer) Play(streamID int, data []int16) error {
+	if err := c.Player.Play(streamID, data); err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&c.samples, int64(len(data)))
+
+	return nil
+}
+
+func (c *countingPlayer) played() int64 {
+	return atomic.LoadInt64(&c.samples)
+}
+
+//nolint:funlen
+func play(cfg config.Config, path, output string) error {
+	t, err := load(path)
+	if err != nil {
+		return err
+	}
+
+	cleanup, err := device.Init(output)
+	if err != nil {
+		return err
+	}
+
+	defer cleanup()
+
+	settings := audio.StreamSettings{
+		SampleRate:      float64(t.sampleRate),
+		Channels:        t.channels,
+		FramesPerBuffer: cfg.Recorder.FramesPerBuffer,
+	}
+
+	player, err := device.NewPlayer(output, settings)
+	if err != nil {
+		return err
+	}
+
+	streamID, err := player.OpenStream()
+	if err != nil {
+		return err
+	}
+
+	defer player.CloseStream(streamID)
+
+	ctx, cancel := interrupt.Context(context.Background())
+	defer cancel()
+
+	keys, restore, err := terminal.Keys(ctx)
+	if err != nil {
+		log.Debugf("keyboard controls are disabled: %s", err.Error())
+	} else {
+		defer restore()
+
+		fmt.Print("[space] pause/resume  [←/→] seek 5s  [↓/↑] seek 30s  [q] quit\r\n")
+	}
+
+	s := &session{
+		track:    t,
+		player:   &countingPlayer{Player: player},
+		streamID: streamID,
+		chunk:    settings.BufferSize(),
+	}
+
+	err = s.run(ctx, keys)
+
+	fmt.Print("\r\n")
+
+	return err
+}
+
+// run plays the track until it's finished or ctx is done. the file is streamed through a pipeline,
+// which is stopped on every key press and started again from the new position.
+func (s *session) run(ctx context.Context, keys <-chan terminal.Key) error {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		s.printStatus()
+
+		var (
+			done <-chan error
+			stop = func() {}
+		)
+
+		if !s.paused {
+			var err error
+
+			if done, stop, err = s.start(ctx); err != nil {
+				return err
+			}
+		}
+
+		finished, quit, err := s.wait(ctx, keys, ticker.C, done, stop)
+		if finished || quit || err != nil {
+			return err
+		}
+	}
+}
+
+// start runs a pipeline which plays the track from the current position and reports its result on done.
+// stop cancels the pipeline and waits for it.
+func (s *session) start(ctx context.Context) (done <-chan error, stop func(), err error) {
+	stages, out, closeFile, err := s.track.open(s.from)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sink := pipeline.NewSink(s.player, s.streamID, s.chunk, out)
+	p := pipeline.New(append(stages, sink)...)
+
+	ctx, cancel := context.WithCancel(ctx)
+	result := make(chan error, 1)
+
+	go func() {
+		defer closeFile()
+
+		result <- p.Run(ctx)
+	}()
+
+	return result, func() {
+		cancel()
+		<-result
+	}, nil
+}
+
+// wait blocks until the pipeline is finished, a key is pressed or ctx is done.
+// it reports whether the track is finished or playback should stop.
+func (s *session) wait(ctx context.Context, keys <-chan terminal.Key, refresh <-chan time.Time,
+	done <-chan error, stop func()) (finished, quit bool, err error) {
+	for {
+		select {
+		case <-ctx.Done():
+			stop()
+
+			return false, true, nil
+		case err := <-done:
+			if err != nil {
+				return false, false, err
+			}
+
+			s.printStatus()
+
+			return true, false, s.flush()
+		case key := <-keys:
+			stop()
+
+			// playback continues from where the pipeline was stopped.
+			s.from, s.base = s.position(), s.player.played()
+
+			quit, err := s.handle(key)
+
+			return false, quit, err
+		case <-refresh:
+			s.printStatus()
+		}
+	}
+}
+
+// flush pads the player's last partial buffer with silence, so it's played before the stream is closed.
+func (s *session) flush() error {
+	rest := int(s.player.played() % int64(s.chunk))
+	if rest == 0 {
+		return nil
+	}
+
+	return s.player.Play(s.streamID, make([]int16, s.chunk-rest))
+}
+
+// position returns the frame which is being played.
+func (s *session) position() int {
+	frame := s.from + int((s.player.played()-s.base)/int64(s.track.channels))
+	if frame > s.track.frames {
+		frame = s.track.frames
+	}
+
+	return frame
+}
+
+// handle applies a key press to the session. it reports whether playback should stop.
+func (s *session) handle(key terminal.Key) (bool, error) {
+	switch key {
+	case 'q', 'Q', terminal.KeyInterrupt, terminal.KeyEscape:
+		return true, nil
+	case ' ', 'p', 'P':
+		if s.paused {
+			if err := s.player.ResumePlay(s.streamID); err != nil {
+				return false, err
+			}
+		} else {
+			if err := s.player.PausePlay(s.streamID); err != nil {
+				return false, err
+			}
+		}
+
+		s.paused = !s.paused
+	case terminal.KeyRight:
+		s.seek(seekStep)
+	case terminal.KeyLeft:
+		s.seek(-seekStep)
+	case terminal.KeyUp:
+		s.seek(longSeekStep)
+	case terminal.KeyDown:
+		s.seek(-longSeekStep)
+	}
+
+	return false, nil
+}
+
+// seek moves playback position by d, staying in track boundaries.
+func (s *session) seek(d time.Duration) {
+	frame := s.from + int(d.Seconds()*float64(s.track.sampleRate))
+
+	if frame < 0 {
+		frame = 0
+	}
+
+	if frame > s.track.frames {
+		frame = s.track.frames
+	}
+
+	s.from = frame
+}
+
+func (s *session) printStatus() {
+	state := "playing"
+	if s.paused {
+		state = "paused "
+	}
+
+	fmt.Printf("\r[%s] %s / %s ", state,
+		formatDuration(s.position(), s.track.sampleRate),
+		formatDuration(s.track.frames, s.track.sampleRate))
+}
+
+func formatDuration(frames, sampleRate int) string {
+	seconds := frames / sampleRate
+
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60) //nolint:gomnd
+}
+
+// Register registers play command to the root kenny command.
+func Register(root *cobra.Command, cfg config.Config) {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "play <file>",
+		Short: "this command will play an Ogg Opus (.opus, .ogg) or wav file",
+		Long: "plays an Ogg Opus or wav file on the output device.\n" +
+			"use space to pause/resume, left/right arrows to seek 5 seconds, up/down arrows to seek 30 seconds and q to quit.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return play(cfg, args[0], output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", portaudio.DeviceNameDefault,
+		"output device name, use file:<path> to write into a wav file")
+
+	root.AddCommand(cmd)
+}
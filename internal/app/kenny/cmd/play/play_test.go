@@ -0,0 +1,216 @@
+package play
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/terminal"
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/fake"
+	"github.com/smf8/kenny/pkg/audio/wav"
+)
+
+// ramp returns distinct samples, so misplaced audio is detected.
+func ramp(n int) []int16 {
+	pcm := make([]int16, n)
+	for i := range pcm {
+		pcm[i] = int16(i % 30000)
+	}
+
+	return pcm
+}
+
+func writeWav(t *testing.T, pcm []int16) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "track.wav")
+
+	player, err := wav.NewPlayer(path, audio.StreamSettings{SampleRate: 48000, Channels: 1, FramesPerBuffer: 480})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(pcm); i += 480 {
+		end := i + 480
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+
+		if err := player.Play(id, pcm[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := player.CloseStream(id); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func writeOgg(t *testing.T, pcm []int16) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "track.opus")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	encoder, err := encoding.NewEncoder(48000, 1, 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ogg, err := encoding.NewOggWriter(f, encoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := encoder.Write(pcm); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		packet, ok := encoder.Buffer.TryRead()
+		if !ok {
+			break
+		}
+
+		if err := ogg.WritePacket(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ogg.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// playFrom plays the track from the given frame on a fake player and returns the played samples.
+func playFrom(t *testing.T, path string, frame int) (*track, []int16) {
+	t.Helper()
+
+	tr, err := load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	player := fake.NewPlayer(audio.StreamSettings{
+		SampleRate:      float64(tr.sampleRate),
+		Channels:        tr.channels,
+		FramesPerBuffer: 480,
+	}, fake.TimingInstant)
+
+	id, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &session{
+		track:    tr,
+		player:   &countingPlayer{Player: player},
+		streamID: id,
+		chunk:    480 * tr.channels,
+		from:     frame,
+	}
+
+	if err := s.run(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.position() != tr.frames {
+		t.Fatalf("playback stopped at frame %d of %d", s.position(), tr.frames)
+	}
+
+	// the last partial buffer is padded, so everything is played without closing the stream.
+	played, err := player.Samples(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tr, played
+}
+
+func TestPlayWav(t *testing.T) {
+	pcm := ramp(10000)
+
+	tr, played := playFrom(t, writeWav(t, pcm), 2500)
+
+	if tr.frames != len(pcm) {
+		t.Fatalf("track has %d frames, want %d", tr.frames, len(pcm))
+	}
+
+	if len(played) != 7680 {
+		t.Fatalf("played %d samples, want 7500 padded to 16 buffers", len(played))
+	}
+
+	if !reflect.DeepEqual(played[:7500], pcm[2500:]) {
+		t.Fatal("played audio doesn't match the file after the seek position")
+	}
+}
+
+func TestPlayOgg(t *testing.T) {
+	pcm := ramp(48000)
+
+	// the encoder's lookahead is skipped and the stream ends at its last granule position.
+	tr, played := playFrom(t, writeOgg(t, pcm), 0)
+
+	if tr.frames != 48000-312 {
+		t.Fatalf("track has %d frames, want %d", tr.frames, 48000-312)
+	}
+
+	if len(played) != 48000 {
+		t.Fatalf("played %d samples, want %d frames padded to 100 buffers", len(played), tr.frames)
+	}
+
+	// opus is lossy, so only the timing is compared: ramps are distinct every 30000 samples.
+	if played[1000] == 0 || played[tr.frames] != 0 {
+		t.Fatalf("played audio isn't trimmed at the end of stream")
+	}
+
+	_, seeked := playFrom(t, writeOgg(t, pcm), 24000)
+
+	if len(seeked) != 24000 {
+		t.Fatalf("played %d samples after seeking, want %d frames padded to 50 buffers", len(seeked), tr.frames-24000)
+	}
+}
+
+func TestSeekKeys(t *testing.T) {
+	s := &session{track: &track{sampleRate: 48000, channels: 1, frames: 48000 * 60}}
+
+	for _, c := range []struct {
+		key  terminal.Key
+		want int
+	}{
+		{terminal.KeyUp, 30 * 48000},
+		{terminal.KeyRight, 35 * 48000},
+		{terminal.KeyLeft, 30 * 48000},
+		{terminal.KeyUp, 60 * 48000},
+		{terminal.KeyDown, 30 * 48000},
+		{terminal.KeyDown, 0},
+		{terminal.KeyLeft, 0},
+	} {
+		if _, err := s.handle(c.key); err != nil {
+			t.Fatal(err)
+		}
+
+		if s.from != c.want {
+			t.Fatalf("seeked to frame %d, want %d", s.from, c.want)
+		}
+	}
+}
@@ -1,109 +1,84 @@
 package record
 
 import (
-	"bytes"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-	"github.com/smf8/kenny/internal/app/kenny/audio"
 	"github.com/smf8/kenny/internal/app/kenny/config"
+	"github.com/smf8/kenny/internal/app/kenny/controls"
+	"github.com/smf8/kenny/internal/app/kenny/device"
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
 	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
 )
 
-//nolint:funlen,gomnd
-func echo(cfg config.Config) error {
-	if err := audio.InitPortAudio(); err != nil {
-		return err
-	}
+const loopbackSize = 1000
 
-	defer audio.TerminatePortAudio()
-
-	input, err := audio.DefaultInputDevice()
+// echo records audio from input, passes it through opus encoder and decoder and plays it on output.
+// keyboard controls mute the input and stop recording.
+//nolint:funlen
+func echo(cfg config.Config, input, output string, duration time.Duration, pushToTalk bool) error {
+	cleanup, err := device.Init(input, output)
 	if err != nil {
 		return err
 	}
 
-	output, err := audio.DefaultOutputDevice()
-	if err != nil {
-		return err
-	}
+	defer cleanup()
 
-	storage := new(bytes.Buffer)
-	settings := audio.StreamSettings{
-		FramesPerBuffer:  cfg.Recorder.FramesPerBuffer,
-		SampleRate:       cfg.Recorder.SampleRate,
-		NumberOfChannels: cfg.Recorder.NumberOfChannels,
-		Buffer:           storage,
-	}
+	settings := streamSettings(cfg)
 
-	inputStreamParam, err := audio.NewStreamParam(&settings, input, audio.RecordDeviceType)
+	recorder, err := device.NewRecorder(input, settings)
 	if err != nil {
 		return err
 	}
 
-	outputSteamParam, err := audio.NewStreamParam(&settings, output, audio.PlayDeviceType)
+	player, err := device.NewPlayer(output, settings)
 	if err != nil {
 		return err
 	}
 
-	api := audio.API{
-		Settings: &settings,
-	}
-
-	encoder, err := encoding.NewEncoder(int(inputStreamParam.SampleRate),
-		inputStreamParam.Input.Channels,
-		cfg.Recorder.OpusFrameSizeMs)
+	encoder, err := encoding.NewEncoder(int(settings.SampleRate), settings.Channels, cfg.Recorder.OpusFrameSizeMs)
 	if err != nil {
 		return err
 	}
 
-	decoder, err := encoding.NewDecoder(int(inputStreamParam.SampleRate),
-		outputSteamParam.Output.Channels,
-		cfg.Recorder.OpusFrameSizeMs)
+	decoder, err := encoding.NewDecoder(int(settings.SampleRate), settings.Channels, cfg.Recorder.OpusFrameSizeMs)
 	if err != nil {
 		return err
 	}
 
-	err = api.OpenStream(inputStreamParam, audio.RecordDeviceType, encoder.CallbackRecord)
+	recordID, err := recorder.OpenStream()
 	if err != nil {
 		return err
 	}
 
-	err = api.OpenStream(outputSteamParam, audio.PlayDeviceType, decoder.CallbackPlay)
+	defer recorder.CloseStream(recordID)
+
+	playID, err := player.OpenStream()
 	if err != nil {
 		return err
 	}
 
-	go func() {
-		if err := api.Record(); err != nil {
-			log.Error(err)
-		}
-	}()
+	defer player.CloseStream(playID)
 
-	<-time.After(100 * time.Millisecond)
+	source := pipeline.NewSource(recorder, recordID)
+	source.Limit = limit(settings, duration)
 
-	go func() {
-		for {
-			data, _ := encoder.Buffer.Read()
+	mute := dsp.NewMute(int(settings.SampleRate), settings.Channels)
+	mute.SetPushToTalk(pushToTalk)
 
-			_ = decoder.DecodeFrame(data)
-		}
-	}()
-	go func() {
-		if err := api.Play(); err != nil {
-			log.Error(err)
-		}
-	}()
+	applyMute := pipeline.NewProcess("mute", mute, source.Out())
+	encode := pipeline.NewEncode(encoder, applyMute.Out())
 
-	<-time.After(8 * time.Second)
-
-	if err := api.PauseRecord(); err != nil {
+	loopback, err := pipeline.NewLoopback(loopbackSize, encoding.DropOldest, encode.Out())
+	if err != nil {
 		return err
 	}
 
-	if err := api.PausePlay(); err != nil {
-		return err
-	}
+	decode := pipeline.NewDecode(decoder, loopback.Out())
+	sink := pipeline.NewSink(player, playID, settings.BufferSize(), decode.Out())
+
+	// quitting stops recording gracefully, like the first interrupt.
+	keys := controls.New(mute, source.Stop)
 
-	return nil
+	return run(pipeline.New(source, applyMute, encode, loopback, decode, sink), source, keys)
 }
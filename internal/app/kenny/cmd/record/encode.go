@@ -0,0 +1,74 @@
+package record
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/config"
+	"github.com/smf8/kenny/internal/app/kenny/device"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+)
+
+// encode records audio from input device and saves it as an Ogg Opus file in output.
+// recording stops after duration (zero means no limit) or when user interrupts it.
+func encode(cfg config.Config, input, output string, duration time.Duration) error {
+	cleanup, err := device.Init(input)
+	if err != nil {
+		return err
+	}
+
+	defer cleanup()
+
+	settings := streamSettings(cfg)
+
+	recorder, err := device.NewRecorder(input, settings)
+	if err != nil {
+		return err
+	}
+
+	encoder, err := encoding.NewEncoder(int(settings.SampleRate), settings.Channels, cfg.Recorder.OpusFrameSizeMs)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	defer f.Close()
+
+	ogg, err := encoding.NewOggWriter(f, encoder)
+	if err != nil {
+		return err
+	}
+
+	streamID, err := recorder.OpenStream()
+	if err != nil {
+		return err
+	}
+
+	defer recorder.CloseStream(streamID)
+
+	// the limit is checked against recorded samples so file inputs are cut at the same point as devices.
+	source := pipeline.NewSource(recorder, streamID)
+	source.Limit = limit(settings, duration)
+
+	encode := pipeline.NewEncode(encoder, source.Out())
+	sink := pipeline.NewOggSink(ogg, encode.Out())
+
+	log.Infof("recording into %s, press Ctrl-C to stop", output)
+
+	if err := run(pipeline.New(source, encode, sink), source, nil); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close output file: %w", err)
+	}
+
+	return nil
+}
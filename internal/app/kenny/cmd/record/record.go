@@ -1,7 +1,15 @@
 package record
 
 import (
+	"context"
+	"time"
+
 	"github.com/smf8/kenny/internal/app/kenny/config"
+	"github.com/smf8/kenny/internal/app/kenny/controls"
+	"github.com/smf8/kenny/internal/app/kenny/interrupt"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/portaudio"
 
 	"github.com/spf13/cobra"
 )
@@ -9,27 +17,108 @@ import (
 const (
 	commandEcho   = "echo"
 	encodeCommand = "encode"
+
+	defaultEchoDuration = 8 * time.Second
 )
 
+func streamSettings(cfg config.Config) audio.StreamSettings {
+	return audio.StreamSettings{
+		SampleRate:      cfg.Recorder.SampleRate,
+		Channels:        cfg.Recorder.NumberOfChannels,
+		FramesPerBuffer: cfg.Recorder.FramesPerBuffer,
+	}
+}
+
+// limit returns number of samples (all channels) in duration. zero duration means no limit.
+func limit(settings audio.StreamSettings, duration time.Duration) int {
+	return int(duration.Seconds()*settings.SampleRate) * settings.Channels
+}
+
+// run runs the pipeline until source is finished. the first interrupt stops source gracefully,
+// so the rest of the pipeline can finish its work. keys handles keyboard controls meanwhile, it can be nil.
+func run(p *pipeline.Pipeline, source *pipeline.Source, keys *controls.Controls) error {
+	ctx, cancel := interrupt.Context(context.Background())
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		source.Stop()
+	}()
+
+	if keys == nil {
+		return p.Run(context.Background())
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		keys.Run(ctx)
+	}()
+
+	err := p.Run(context.Background())
+
+	// controls are stopped before returning, so the terminal is restored.
+	cancel()
+	<-done
+
+	return err
+}
+
 // Register registers record command to the root kenny command
 //nolint:gomnd
 func Register(root *cobra.Command, cfg config.Config) {
-	root.AddCommand(
-		&cobra.Command{
-			Use:   "record {echo | encode}",
-			Short: "this command will record something, encode it with opus, then decodes it and plays it back",
-			Args:  cobra.ExactArgs(1),
-			ValidArgs: []string{
-				"echo",
-			},
-			RunE: func(cmd *cobra.Command, args []string) error {
-				switch args[0] {
-				case commandEcho:
-					return echo(cfg)
+	var (
+		input      string
+		output     string
+		duration   time.Duration
+		pushToTalk bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "record {echo | encode}",
+		Short: "this command will record something, encode it with opus, then decodes it and plays it back",
+		Long: "echo: records audio, encodes it with opus, then decodes it and plays it back on output device.\n" +
+			"      press m to mute and q to quit, with --push-to-talk only audio is sent while space is held or tapped.\n" +
+			"encode: records audio from input device and saves it as an Ogg Opus file in output.",
+		Args: cobra.ExactArgs(1),
+		ValidArgs: []string{
+			commandEcho,
+			encodeCommand,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch args[0] {
+			case commandEcho:
+				if output == "" {
+					output = portaudio.DeviceNameDefault
+				}
+
+				if duration == 0 {
+					duration = defaultEchoDuration
 				}
 
-				return nil
-			},
+				return echo(cfg, input, output, duration, pushToTalk)
+			case encodeCommand:
+				if output == "" {
+					output = "kenny.opus"
+				}
+
+				return encode(cfg, input, output, duration)
+			}
+
+			return nil
 		},
-	)
+	}
+
+	cmd.Flags().StringVarP(&input, "input", "i", portaudio.DeviceNameDefault,
+		"input device name, use file:<path> to read from a wav file")
+	cmd.Flags().StringVarP(&output, "output", "o", "",
+		"echo: output device name or file:<path> (default \"default\"), encode: output file (default \"kenny.opus\")")
+	cmd.Flags().DurationVarP(&duration, "duration", "d", 0,
+		"maximum recording duration, zero means until interrupted (echo defaults to 8s)")
+	cmd.Flags().BoolVar(&pushToTalk, "push-to-talk", false,
+		"echo: record only while space is held, or toggle it by tapping space")
+
+	root.AddCommand(cmd)
 }
@@ -3,10 +3,13 @@ package cmd
 import (
 	"os"
 
+	"github.com/smf8/kenny/internal/app/kenny/cmd/call"
 	"github.com/smf8/kenny/internal/app/kenny/cmd/record"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/smf8/kenny/internal/app/kenny/cmd/devices"
+	"github.com/smf8/kenny/internal/app/kenny/cmd/play"
+	"github.com/smf8/kenny/internal/app/kenny/cmd/signal"
 	"github.com/smf8/kenny/internal/app/kenny/config"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +27,9 @@ func NewRootCommand() *cobra.Command {
 
 	devices.Register(root)
 	record.Register(root, cfg)
+	play.Register(root, cfg)
+	signal.Register(root, cfg)
+	call.Register(root, cfg)
 
 	return root
 }
@@ -0,0 +1,88 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/config"
+	"github.com/smf8/kenny/internal/app/kenny/interrupt"
+	"github.com/smf8/kenny/internal/app/kenny/signaling"
+	"github.com/spf13/cobra"
+)
+
+const (
+	serveCommand = "serve"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+func serve(cfg config.Config, listen string) error {
+	ctx, cancel := interrupt.Context(context.Background())
+	defer cancel()
+
+	server := signaling.NewServer()
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Signal.Path, server)
+
+	srv := &http.Server{
+		Addr:    listen,
+		Handler: mux,
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	log.Infof("signaling server is listening on ws://%s%s", listen, cfg.Signal.Path)
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("signaling server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Info("shutting down signaling server")
+
+	server.Close()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shutdown signaling server: %w", err)
+	}
+
+	return nil
+}
+
+// Register registers signal command to the root kenny command.
+func Register(root *cobra.Command, cfg config.Config) {
+	var listen string
+
+	cmd := &cobra.Command{
+		Use:   "signal serve",
+		Short: "runs a WebSocket signaling server which kenny clients use to find each other",
+		Long: "serve: runs a signaling server. clients join a room by name and the server relays " +
+			"SDP offers/answers and ICE candidates between clients in the same room.",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{serveCommand},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] != serveCommand {
+				return fmt.Errorf("unknown signal command %q", args[0])
+			}
+
+			return serve(cfg, listen)
+		},
+	}
+
+	cmd.Flags().StringVarP(&listen, "listen", "l", cfg.Signal.Listen, "address to listen on")
+
+	root.AddCommand(cmd)
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/file"
@@ -14,6 +16,15 @@ type (
 		Debug    bool     `koanf:"debug"`
 		Logger   Logger   `koanf:"logger"`
 		Recorder Recorder `koanf:"recorder"`
+		WebRTC   WebRTC   `koanf:"webrtc"`
+		Signal   Signal   `koanf:"signal"`
+		Jitter   Jitter   `koanf:"jitter"`
+		FEC      FEC      `koanf:"fec"`
+		VAD      VAD      `koanf:"vad"`
+		AEC      AEC      `koanf:"aec"`
+		Noise    Noise    `koanf:"noise"`
+		Gate     Gate     `koanf:"gate"`
+		AGC      AGC      `koanf:"agc"`
 	}
 
 	//Logger represents logger(logrus) config information.
@@ -29,6 +40,77 @@ type (
 		FramesPerBuffer  int
 		OpusFrameSizeMs  int
 	}
+
+	// WebRTC represents peer connection settings.
+	WebRTC struct {
+		// ICEServers is a list of STUN/TURN urls. it can be empty on local networks.
+		ICEServers []string `koanf:"ice_servers"`
+	}
+
+	// Signal represents signaling server settings.
+	Signal struct {
+		// Listen is the address which signaling server listens on.
+		Listen string `koanf:"listen"`
+		// Path is the WebSocket endpoint's path.
+		Path string `koanf:"path"`
+		// Server is the signaling server's url which call command connects to.
+		Server string `koanf:"server"`
+	}
+
+	// Jitter represents jitter buffer settings. target delay adapts to network jitter between these bounds.
+	Jitter struct {
+		MinDelay time.Duration `koanf:"min_delay"`
+		MaxDelay time.Duration `koanf:"max_delay"`
+	}
+
+	// FEC represents opus in-band forward error correction settings.
+	FEC struct {
+		Enabled bool `koanf:"enabled"`
+		// PacketLoss is the expected packet loss percentage. a negative value follows remote receiver reports.
+		PacketLoss int `koanf:"packet_loss"`
+	}
+
+	// VAD represents voice activity detection settings. when it's enabled, opus DTX stops sending silence.
+	VAD struct {
+		Enabled bool `koanf:"enabled"`
+		// Threshold is the level above background noise which is considered speech, in dB.
+		Threshold float64       `koanf:"threshold"`
+		Hangover  time.Duration `koanf:"hangover"`
+	}
+
+	// AEC represents acoustic echo cancellation settings. echo delay is estimated automatically up to MaxDelay.
+	AEC struct {
+		Enabled bool `koanf:"enabled"`
+		// FilterLength is the echo tail which is modeled after the estimated delay.
+		FilterLength time.Duration `koanf:"filter_length"`
+		MaxDelay     time.Duration `koanf:"max_delay"`
+	}
+
+	// Noise represents noise suppression settings of recorded audio.
+	Noise struct {
+		Enabled bool `koanf:"enabled"`
+		// Suppression is the maximum attenuation of noise, in dB.
+		Suppression float64 `koanf:"suppression"`
+	}
+
+	// Gate represents noise gate settings of recorded audio.
+	Gate struct {
+		Enabled bool `koanf:"enabled"`
+		// Threshold is the level which opens the gate, in dBFS.
+		Threshold float64 `koanf:"threshold"`
+		// Range is the attenuation of the closed gate, in dB.
+		Range float64       `koanf:"range"`
+		Hold  time.Duration `koanf:"hold"`
+	}
+
+	// AGC represents automatic gain control settings of recorded audio.
+	AGC struct {
+		Enabled bool `koanf:"enabled"`
+		// Target is the loudness which speech is normalized to, in dBFS.
+		Target float64 `koanf:"target"`
+		// MaxGain is the maximum amplification and attenuation, in dB.
+		MaxGain float64 `koanf:"max_gain"`
+	}
 )
 
 //New creates a new config instance with this order : default -> config.yml.
@@ -37,7 +119,7 @@ func New() Config {
 
 	k := koanf.New(".")
 
-	if err := k.Load(structs.Provider(def, "konaf"), nil); err != nil {
+	if err := k.Load(structs.Provider(def, "koanf"), nil); err != nil {
 		logrus.Fatalf("error loading default: %s", err)
 	}
 
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewLoadsDefaults(t *testing.T) {
+	cfg := New()
+
+	if !reflect.DeepEqual(cfg, def) {
+		t.Fatalf("defaults are changed by loading:\n got: %+v\nwant: %+v", cfg, def)
+	}
+}
+
+func TestFECFollowsReceiverReportsByDefault(t *testing.T) {
+	cfg := New()
+
+	if !cfg.FEC.Enabled {
+		t.Error("FEC is disabled by default")
+	}
+
+	// a negative packet loss makes calls follow RTCP receiver reports instead of a fixed percentage.
+	if cfg.FEC.PacketLoss != -1 {
+		t.Errorf("default packet loss is %d, want -1", cfg.FEC.PacketLoss)
+	}
+}
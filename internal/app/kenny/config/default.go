@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 //Namespace is the name for application instance
 const Namespace = "Kenny"
 
@@ -18,4 +20,54 @@ var def = Config{
 		FramesPerBuffer:  64,
 		OpusFrameSizeMs:  2880,
 	},
+
+	WebRTC: WebRTC{
+		ICEServers: []string{},
+	},
+
+	Signal: Signal{
+		Listen: ":7000",
+		Path:   "/ws",
+		Server: "ws://localhost:7000/ws",
+	},
+
+	Jitter: Jitter{
+		MinDelay: 20 * time.Millisecond,
+		MaxDelay: 400 * time.Millisecond,
+	},
+
+	FEC: FEC{
+		Enabled:    true,
+		PacketLoss: -1,
+	},
+
+	VAD: VAD{
+		Enabled:   true,
+		Threshold: 9,
+		Hangover:  300 * time.Millisecond,
+	},
+
+	AEC: AEC{
+		Enabled:      true,
+		FilterLength: 32 * time.Millisecond,
+		MaxDelay:     500 * time.Millisecond,
+	},
+
+	Noise: Noise{
+		Enabled:     true,
+		Suppression: 20,
+	},
+
+	Gate: Gate{
+		Enabled:   false,
+		Threshold: -50,
+		Range:     40,
+		Hold:      200 * time.Millisecond,
+	},
+
+	AGC: AGC{
+		Enabled: true,
+		Target:  -18,
+		MaxGain: 30,
+	},
 }
@@ -0,0 +1,234 @@
+// Package controls handles keyboard controls of live audio commands, i.e. calls.
+// m toggles mute, space is push to talk and q quits. features can add their own toggle keys.
+package controls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+	"github.com/smf8/kenny/internal/app/kenny/terminal"
+)
+
+const (
+	// holdDelay is how long the first key repeat is waited for, it's longer than the usual key repeat delay.
+	// space is held when it repeats, otherwise it's tapped and push to talk stays on until the next tap.
+	holdDelay = 700 * time.Millisecond
+	// releaseDelay is how long after the last key repeat a held key is considered released.
+	releaseDelay = 150 * time.Millisecond
+)
+
+// talkState is the push to talk state of space key.
+type talkState int
+
+const (
+	idle talkState = iota
+	// pressed is right after a press, until it repeats or holdDelay passes.
+	pressed
+	held
+	// latched is after a tap, talking continues until the next one.
+	latched
+)
+
+// timer waits for space repeats. Run uses a time.Timer, tests fire timeouts by calling Controls.timeout.
+type timer interface {
+	// Reset stops the timer and starts waiting for d again.
+	Reset(d time.Duration)
+	Stop()
+}
+
+// clockTimer is a timer which fires on the time.Timer's channel.
+type clockTimer struct {
+	t *time.Timer
+}
+
+func (t clockTimer) Reset(d time.Duration) {
+	t.Stop()
+	t.t.Reset(d)
+}
+
+func (t clockTimer) Stop() {
+	if !t.t.Stop() {
+		// the timer has fired, its value is dropped unless it's already received.
+		select {
+		case <-t.t.C:
+		default:
+		}
+	}
+}
+
+// toggle is a feature which can be turned on and off by a key.
+type toggle struct {
+	key     rune
+	name    string
+	enabled func() bool
+	set     func(enabled bool)
+}
+
+// Controls reads key presses from the terminal and applies them to the capture's Mute.
+type Controls struct {
+	mute    *dsp.Mute
+	quit    func()
+	toggles []toggle
+	talk    talkState
+	// timer detects whether space is held or tapped, it's stopped while nothing is waited for.
+	timer timer
+}
+
+// New creates Controls which mutes audio by mute and calls quit when q or Ctrl-C is pressed.
+func New(mute *dsp.Mute, quit func()) *Controls {
+	return &Controls{
+		mute: mute,
+		quit: quit,
+	}
+}
+
+// Toggle adds a key which turns a feature on and off, i.e. noise suppression.
+func (c *Controls) Toggle(key rune, name string, enabled func() bool, set func(enabled bool)) {
+	c.toggles = append(c.toggles, toggle{key: key, name: name, enabled: enabled, set: set})
+}
+
+// Help returns the key bindings as a single line.
+func (c *Controls) Help() string {
+	help := []string{"[m] mute"}
+
+	if c.mute.PushToTalk() {
+		help = append(help, "[space] hold or tap to talk")
+	}
+
+	toggles := append([]toggle(nil), c.toggles...)
+	sort.Slice(toggles, func(i, j int) bool {
+		return toggles[i].key < toggles[j].key
+	})
+
+	for _, t := range toggles {
+		help = append(help, fmt.Sprintf("[%c] %s", t.key, t.name))
+	}
+
+	return strings.Join(append(help, "[q] quit"), "  ")
+}
+
+// Run switches the terminal into raw mode and handles key presses until ctx is done. logs are written
+// with carriage returns meanwhile. when stdin isn't a terminal, it returns without controls.
+func (c *Controls) Run(ctx context.Context) {
+	keys, restore, err := terminal.Keys(ctx)
+	if errors.Is(err, terminal.ErrNotTerminal) {
+		log.Debugf("keyboard controls are disabled: %s", err)
+
+		return
+	}
+
+	if err != nil {
+		log.Errorf("keyboard controls are disabled: %s", err)
+
+		return
+	}
+
+	defer restore()
+
+	logger := log.StandardLogger()
+	out := logger.Out
+	logger.SetOutput(terminal.NewLineWriter(out))
+
+	defer logger.SetOutput(out)
+
+	fmt.Print(c.Help() + "\r\n")
+
+	t := time.NewTimer(holdDelay)
+	t.Stop()
+
+	c.timer = clockTimer{t: t}
+
+	for {
+		select {
+		case key := <-keys:
+			c.handle(key)
+		case <-t.C:
+			c.timeout()
+		case <-ctx.Done():
+			c.timer.Stop()
+
+			return
+		}
+	}
+}
+
+func (c *Controls) handle(key terminal.Key) {
+	switch key {
+	case 'q', 'Q', terminal.KeyInterrupt:
+		c.quit()
+	case 'm', 'M':
+		muted := !c.mute.Muted()
+		c.mute.SetMuted(muted)
+
+		if muted {
+			log.Info("microphone is muted")
+		} else {
+			log.Info("microphone is unmuted")
+		}
+	case ' ':
+		if c.mute.PushToTalk() {
+			c.press()
+		}
+	default:
+		for _, t := range c.toggles {
+			if key != terminal.Key(t.key) {
+				continue
+			}
+
+			enabled := !t.enabled()
+			t.set(enabled)
+
+			state := "disabled"
+			if enabled {
+				state = "enabled"
+			}
+
+			log.Infof("%s is %s", t.name, state)
+		}
+	}
+}
+
+// press handles a space press or repeat. terminals don't report key releases, so a held key is
+// detected by its repeats and released when they stop.
+func (c *Controls) press() {
+	switch c.talk {
+	case idle:
+		c.talk = pressed
+		c.setTalking(true)
+		c.timer.Reset(holdDelay)
+	case pressed, held:
+		c.talk = held
+		c.timer.Reset(releaseDelay)
+	case latched:
+		c.talk = idle
+		c.setTalking(false)
+	}
+}
+
+// timeout handles the end of waiting for a space repeat.
+func (c *Controls) timeout() {
+	switch c.talk {
+	case pressed:
+		c.talk = latched
+
+		log.Info("talking, tap space to stop")
+	case held:
+		c.talk = idle
+		c.setTalking(false)
+	case idle, latched:
+	}
+}
+
+func (c *Controls) setTalking(talking bool) {
+	c.mute.SetTalking(talking)
+
+	if c.mute.Muted() {
+		log.Info("microphone is muted, press m to unmute")
+	}
+}
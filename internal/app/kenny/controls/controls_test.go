@@ -0,0 +1,135 @@
+package controls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+)
+
+// fakeTimer records what push to talk waits for, tests fire it by calling Controls.timeout.
+type fakeTimer struct {
+	waiting time.Duration
+}
+
+func (t *fakeTimer) Reset(d time.Duration) {
+	t.waiting = d
+}
+
+func (t *fakeTimer) Stop() {
+	t.waiting = 0
+}
+
+func newControls(pushToTalk bool) (*Controls, *dsp.Mute, *fakeTimer) {
+	mute := dsp.NewMute(48000, 1)
+	mute.SetPushToTalk(pushToTalk)
+
+	timer := &fakeTimer{}
+	c := New(mute, func() {})
+	c.timer = timer
+
+	return c, mute, timer
+}
+
+// fire lets the timer run out.
+func fire(c *Controls, timer *fakeTimer) {
+	timer.waiting = 0
+	c.timeout()
+}
+
+func TestPushToTalkTap(t *testing.T) {
+	c, mute, timer := newControls(true)
+
+	if mute.Open() {
+		t.Fatal("push to talk starts talking")
+	}
+
+	c.handle(' ')
+
+	if !mute.Open() || timer.waiting != holdDelay {
+		t.Fatalf("pressing space: open %t, waiting %s for a repeat", mute.Open(), timer.waiting)
+	}
+
+	// space doesn't repeat, so it was tapped and talking continues.
+	fire(c, timer)
+
+	if !mute.Open() {
+		t.Fatal("tapping space didn't latch talking")
+	}
+
+	c.handle(' ')
+
+	if mute.Open() {
+		t.Fatal("the second tap didn't stop talking")
+	}
+}
+
+func TestPushToTalkHold(t *testing.T) {
+	c, mute, timer := newControls(true)
+
+	c.handle(' ')
+
+	// key repeats while space is held.
+	for i := 0; i < 10; i++ {
+		c.handle(' ')
+
+		if !mute.Open() || timer.waiting != releaseDelay {
+			t.Fatalf("repeat %d: open %t, waiting %s for the next repeat", i, mute.Open(), timer.waiting)
+		}
+	}
+
+	// repeats stop when space is released.
+	fire(c, timer)
+
+	if mute.Open() {
+		t.Fatal("releasing space didn't stop talking")
+	}
+
+	// the next press starts over.
+	c.handle(' ')
+
+	if !mute.Open() || timer.waiting != holdDelay {
+		t.Fatalf("pressing space again: open %t, waiting %s for a repeat", mute.Open(), timer.waiting)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	mute := dsp.NewMute(48000, 1)
+
+	var quit, enabled bool
+
+	c := New(mute, func() { quit = true })
+	c.timer = &fakeTimer{}
+	c.Toggle('n', "noise suppression", func() bool { return enabled }, func(e bool) { enabled = e })
+
+	// space does nothing without push to talk.
+	c.handle(' ')
+
+	if !mute.Open() || c.talk != idle {
+		t.Fatal("space changed talking without push to talk")
+	}
+
+	c.handle('m')
+
+	if !mute.Muted() || mute.Open() {
+		t.Fatal("m didn't mute")
+	}
+
+	c.handle('M')
+
+	if mute.Muted() {
+		t.Fatal("m didn't unmute")
+	}
+
+	c.handle('n')
+
+	if !enabled {
+		t.Fatal("n didn't toggle its feature")
+	}
+
+	c.handle('q')
+
+	if !quit {
+		t.Fatal("q didn't quit")
+	}
+}
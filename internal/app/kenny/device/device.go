@@ -0,0 +1,62 @@
+package device
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/portaudio"
+	"github.com/smf8/kenny/pkg/audio/wav"
+)
+
+// FilePrefix marks a device name as a wav file path instead of a portaudio device, i.e. `file:greeting.wav`.
+const FilePrefix = "file:"
+
+// IsFile reports whether name refers to a wav file.
+func IsFile(name string) bool {
+	return strings.HasPrefix(name, FilePrefix)
+}
+
+// Init initializes portaudio if any of the device names refers to a portaudio device.
+// call the returned cleanup function after you are done using the devices.
+func Init(names ...string) (cleanup func(), err error) {
+	for _, name := range names {
+		if IsFile(name) {
+			continue
+		}
+
+		if err := portaudio.Init(); err != nil {
+			return nil, err
+		}
+
+		return func() {
+			if err := portaudio.Cleanup(); err != nil {
+				log.Error(err)
+			}
+		}, nil
+	}
+
+	return func() {}, nil
+}
+
+// NewRecorder creates an audio.Recorder for the given device name.
+// name is either a portaudio device name, portaudio.DeviceNameDefault or a wav file path prefixed by FilePrefix.
+// portaudio must be initialized before recording from a portaudio device.
+func NewRecorder(name string, settings audio.StreamSettings) (audio.Recorder, error) {
+	if IsFile(name) {
+		return wav.NewRecorder(strings.TrimPrefix(name, FilePrefix), settings)
+	}
+
+	return portaudio.NewRecorder(name, settings)
+}
+
+// NewPlayer creates an audio.Player for the given device name.
+// name is either a portaudio device name, portaudio.DeviceNameDefault or a wav file path prefixed by FilePrefix.
+// portaudio must be initialized before playing on a portaudio device.
+func NewPlayer(name string, settings audio.StreamSettings) (audio.Player, error) {
+	if IsFile(name) {
+		return wav.NewPlayer(strings.TrimPrefix(name, FilePrefix), settings)
+	}
+
+	return portaudio.NewPlayer(name, settings)
+}
@@ -0,0 +1,337 @@
+package dsp
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultEchoFilterLength is the default echo tail which the adaptive filter models.
+	DefaultEchoFilterLength = 32 * time.Millisecond
+	// DefaultEchoMaxDelay is the default maximum delay between playing audio and capturing its echo.
+	DefaultEchoMaxDelay = 500 * time.Millisecond
+
+	// echoDecimation is the downsampling factor of delay estimation.
+	echoDecimation = 8
+	// echoEstimateWindow is the duration of captured audio which delay is estimated on.
+	echoEstimateWindow = 500 * time.Millisecond
+	// echoEstimateInterval is how often delay is estimated.
+	echoEstimateInterval = time.Second
+	// echoMinCorrelation is the minimum normalized correlation of a delay estimate.
+	echoMinCorrelation = 0.3
+	// echoMargin is how far the filter starts before the estimated delay, it covers estimation errors.
+	echoMargin = 4 * time.Millisecond
+	// echoStep is the NLMS adaptation step size.
+	echoStep = 0.5
+	// echoMinLevel is the reference level below which the filter doesn't adapt, in dBFS.
+	echoMinLevel = -60
+	// doubleTalkRatio detects near end speech when captured audio is louder than this ratio of the
+	// reference peak, then adaptation stops for doubleTalkHold. (Geigel detector)
+	doubleTalkRatio = 0.5
+	doubleTalkHold  = 30 * time.Millisecond
+)
+
+// EchoCanceller removes echo of played audio from captured audio, i.e. the far end's voice which
+// laptop speakers play into the microphone. played audio is given to Render as reference and
+// captured audio goes through Process before encoding.
+//
+// the delay between reference and its echo is estimated by cross correlation of both signals,
+// then a normalized least mean squares (NLMS) filter models the echo path around that delay
+// and its estimate of the echo is subtracted from captured audio.
+type EchoCanceller struct {
+	sampleRate int
+	channels   int
+	taps       int
+	margin     int
+	maxDelay   int
+	window     int
+	interval   int
+	hold       int
+	minPower   float64
+
+	mu sync.Mutex
+	// reference is mono far end audio, its first sample has index refStart.
+	reference []float64
+	refStart  int64
+	keep      int
+
+	// capture state is only used by Process.
+	started bool
+	// offset is the reference index which is played at the same time as capture index 0, without the echo delay.
+	offset   int64
+	captured int64
+	history  []float64
+	elapsed  int
+	// delay is the estimated echo delay in samples, it's negative until the first estimate.
+	delay       int
+	weights     []float64
+	doubleTalk  int
+	delayMicros int64
+}
+
+// NewEchoCanceller creates an EchoCanceller for audio with the given sample rate and channel count.
+// filterLength is the echo tail which is modeled after the estimated delay, maxDelay bounds the estimate.
+func NewEchoCanceller(sampleRate, channels int, filterLength, maxDelay time.Duration) *EchoCanceller {
+	samples := func(d time.Duration) int {
+		return int(d * time.Duration(sampleRate) / time.Second)
+	}
+
+	e := &EchoCanceller{
+		sampleRate: sampleRate,
+		channels:   channels,
+		taps:       samples(filterLength),
+		margin:     samples(echoMargin),
+		maxDelay:   samples(maxDelay) / echoDecimation * echoDecimation,
+		window:     samples(echoEstimateWindow) / echoDecimation * echoDecimation,
+		interval:   samples(echoEstimateInterval),
+		hold:       samples(doubleTalkHold),
+		delay:      -1,
+	}
+
+	if e.taps < e.margin+1 {
+		e.taps = e.margin + 1
+	}
+
+	e.weights = make([]float64, e.taps)
+	e.keep = e.maxDelay + e.window + e.taps + sampleRate
+
+	minLevel := fullScale * math.Pow(10, echoMinLevel/20) //nolint:gomnd
+	e.minPower = minLevel * minLevel
+
+	return e
+}
+
+// Delay returns the estimated delay between played audio and its echo, zero until it's estimated.
+// it's safe to call from other goroutines.
+func (e *EchoCanceller) Delay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&e.delayMicros)) * time.Microsecond
+}
+
+// Render adds played audio as the echo reference. it passes pcm through unchanged, so it can be a processor
+// right before the player. it's safe to call concurrently with Process.
+func (e *EchoCanceller) Render(pcm []int16) ([]int16, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.reference = append(e.reference, mono(pcm, e.channels)...)
+
+	if extra := len(e.reference) - e.keep; extra > 0 {
+		e.reference = append(e.reference[:0], e.reference[extra:]...)
+		e.refStart += int64(extra)
+	}
+
+	return pcm, nil
+}
+
+// Process implements pipeline.Processor. it removes echo of the reference from captured pcm.
+func (e *EchoCanceller) Process(pcm []int16) ([]int16, error) {
+	near := mono(pcm, e.channels)
+	frames := len(near)
+
+	if !e.started {
+		e.started = true
+
+		// the first captured chunk is aligned with the latest reference, so echo delay is never negative.
+		e.mu.Lock()
+		e.offset = e.refStart + int64(len(e.reference)) - int64(frames)
+		e.mu.Unlock()
+	}
+
+	e.history = append(e.history, near...)
+	if extra := len(e.history) - e.window; extra > 0 {
+		e.history = append(e.history[:0], e.history[extra:]...)
+	}
+
+	e.elapsed += frames
+	if e.elapsed >= e.interval && len(e.history) == e.window {
+		e.elapsed = 0
+		e.estimate(e.captured + int64(frames))
+	}
+
+	if e.delay >= 0 {
+		e.cancel(pcm, near)
+	}
+
+	e.captured += int64(frames)
+
+	return pcm, nil
+}
+
+// cancel subtracts the filter's echo estimate from pcm and adapts the filter.
+func (e *EchoCanceller) cancel(pcm []int16, near []float64) {
+	// x[k] of sample j is ref[j+taps-1-k], k = 0 is the newest reference sample of the filter.
+	newest := e.captured + e.offset - int64(e.delay) + int64(e.margin)
+	ref := e.referenceRange(newest-int64(e.taps)+1, len(near)+e.taps-1)
+
+	var power, peak float64
+
+	for _, x := range ref[:e.taps] {
+		power += x * x
+	}
+
+	for _, x := range ref {
+		peak = math.Max(peak, math.Abs(x))
+	}
+
+	for j, d := range near {
+		if j > 0 {
+			newX, oldX := ref[j+e.taps-1], ref[j-1]
+			power += newX*newX - oldX*oldX
+		}
+
+		x := ref[j : j+e.taps]
+
+		var y float64
+		for k, w := range e.weights {
+			y += w * x[e.taps-1-k]
+		}
+
+		if math.Abs(d) > doubleTalkRatio*peak {
+			e.doubleTalk = e.hold
+		}
+
+		if e.doubleTalk > 0 {
+			e.doubleTalk--
+		} else if power > e.minPower*float64(e.taps) {
+			g := echoStep * (d - y) / power
+			for k := range e.weights {
+				e.weights[k] += g * x[e.taps-1-k]
+			}
+		}
+
+		for c := 0; c < e.channels; c++ {
+			i := j*e.channels + c
+			pcm[i] = clip(float64(pcm[i]) - y)
+		}
+	}
+}
+
+// estimate estimates echo delay by cross correlation of captured history, which ends before capture index end,
+// with the reference. both are decimated to make it cheap.
+func (e *EchoCanceller) estimate(end int64) {
+	near := decimate(e.history)
+	// ref starts maxDelay before history, so with a delay of lag decimated samples near[i] is the echo of ref[lags-lag+i].
+	ref := decimate(e.referenceRange(end-int64(e.window)+e.offset-int64(e.maxDelay), e.window+e.maxDelay))
+
+	var nearPower float64
+	for _, s := range near {
+		nearPower += s * s
+	}
+
+	if nearPower < e.minPower*float64(len(near)) {
+		return
+	}
+
+	// prefix holds cumulative reference power, so power of every window is found in constant time.
+	prefix := make([]float64, len(ref)+1)
+	for i, s := range ref {
+		prefix[i+1] = prefix[i] + s*s
+	}
+
+	lags := len(ref) - len(near)
+	best, bestLag := 0.0, -1
+
+	for lag := 0; lag <= lags; lag++ {
+		start := lags - lag
+
+		refPower := prefix[start+len(near)] - prefix[start]
+		if refPower < e.minPower*float64(len(near)) {
+			continue
+		}
+
+		var corr float64
+		for i, s := range near {
+			corr += s * ref[start+i]
+		}
+
+		if c := math.Abs(corr) / math.Sqrt(nearPower*refPower); c > best {
+			best, bestLag = c, lag
+		}
+	}
+
+	if bestLag < 0 || best < echoMinCorrelation {
+		return
+	}
+
+	delay := bestLag * echoDecimation
+	if e.delay >= 0 && abs(delay-e.delay) <= e.margin/2 {
+		return
+	}
+
+	// a new echo path starts over, the old weights model another delay.
+	for k := range e.weights {
+		e.weights[k] = 0
+	}
+
+	e.delay = delay
+
+	d := time.Duration(delay) * time.Second / time.Duration(e.sampleRate)
+	atomic.StoreInt64(&e.delayMicros, d.Microseconds())
+
+	log.Debugf("echo delay is estimated %s, correlation %.2f", d, best)
+}
+
+// referenceRange copies n reference samples from index start. missing samples are zero.
+func (e *EchoCanceller) referenceRange(start int64, n int) []float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]float64, n)
+
+	for i := range out {
+		j := start + int64(i) - e.refStart
+		if j >= 0 && j < int64(len(e.reference)) {
+			out[i] = e.reference[j]
+		}
+	}
+
+	return out
+}
+
+// mono averages interleaved channels.
+func mono(pcm []int16, channels int) []float64 {
+	out := make([]float64, len(pcm)/channels)
+
+	for i := range out {
+		var sum float64
+		for c := 0; c < channels; c++ {
+			sum += float64(pcm[i*channels+c])
+		}
+
+		out[i] = sum / float64(channels)
+	}
+
+	return out
+}
+
+// decimate averages every echoDecimation samples, which also filters frequencies that would alias.
+func decimate(samples []float64) []float64 {
+	out := make([]float64, len(samples)/echoDecimation)
+
+	for i := range out {
+		var sum float64
+		for _, s := range samples[i*echoDecimation : (i+1)*echoDecimation] {
+			sum += s
+		}
+
+		out[i] = sum / echoDecimation
+	}
+
+	return out
+}
+
+func clip(s float64) int16 {
+	return int16(math.Max(math.Min(math.Round(s), math.MaxInt16), math.MinInt16))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
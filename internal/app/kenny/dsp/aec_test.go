@@ -0,0 +1,93 @@
+package dsp_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/fake"
+)
+
+// TestEchoCanceller plays noise on the fake backend, whose recorder picks it up 100 ms later at half level,
+// and checks that echo is estimated at that delay and removed from captured audio.
+func TestEchoCanceller(t *testing.T) {
+	settings := audio.StreamSettings{SampleRate: 48000, Channels: 1, FramesPerBuffer: 480}
+	delay := 100 * time.Millisecond
+
+	player := fake.NewPlayer(settings, fake.TimingInstant)
+
+	playID, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := fake.NewRecorder(settings,
+		fake.EchoSource(fake.SilenceSource(), player, playID, int(delay.Seconds()*settings.SampleRate), 0.5),
+		fake.TimingInstant)
+
+	recordID, err := recorder.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aec := dsp.NewEchoCanceller(int(settings.SampleRate), settings.Channels,
+		dsp.DefaultEchoFilterLength, dsp.DefaultEchoMaxDelay)
+
+	// far end speech is white noise, its autocorrelation has a single peak.
+	random := rand.New(rand.NewSource(1))
+
+	var echo, residual float64
+
+	// 4 seconds, the last one is measured.
+	for i := 0; i < 400; i++ {
+		far := make([]int16, settings.BufferSize())
+		for j := range far {
+			far[j] = int16(random.NormFloat64() * 4000)
+		}
+
+		if _, err := aec.Render(far); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := player.Play(playID, far); err != nil {
+			t.Fatal(err)
+		}
+
+		captured, err := recorder.Record(recordID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		before := power(captured)
+
+		cancelled, err := aec.Process(captured)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i >= 300 {
+			echo += before
+			residual += power(cancelled)
+		}
+	}
+
+	if d := aec.Delay(); d < delay-time.Millisecond || d > delay+time.Millisecond {
+		t.Fatalf("echo delay is estimated %s, want %s", d, delay)
+	}
+
+	if attenuation := 10 * math.Log10(echo/residual); attenuation < 20 {
+		t.Fatalf("echo is attenuated %.1f dB, want at least 20 dB", attenuation)
+	}
+}
+
+func power(pcm []int16) float64 {
+	var sum float64
+	for _, s := range pcm {
+		sum += float64(s) * float64(s)
+	}
+
+	return sum
+}
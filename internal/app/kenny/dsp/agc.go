@@ -0,0 +1,155 @@
+package dsp
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultAGCTarget is the default loudness which AGC normalizes speech to, in dBFS.
+	DefaultAGCTarget = -18
+	// DefaultAGCMaxGain is the default maximum amplification and attenuation of AGC, in dB.
+	DefaultAGCMaxGain = 30
+
+	// agcMinLevel is the level below which loudness isn't measured, so noise in pauses isn't amplified.
+	agcMinLevel = -50
+	// agcLoudnessTime is the time constant of loudness measurement.
+	agcLoudnessTime = 400 * time.Millisecond
+	// agcRise and agcFall are how fast gain changes, in dB per second. gain rises slowly, so it doesn't pump.
+	agcRise = 6
+	agcFall = 30
+	// agcLimit is the maximum peak level after amplification, louder peaks are reduced right away and the
+	// reduction is released at agcLimitRelease dB per second, without changing the slow gain.
+	agcLimit        = -1
+	agcLimitRelease = 60
+)
+
+// AGC is an automatic gain control which normalizes speech loudness towards Target, i.e. for quiet laptop mics.
+// loudness is measured slowly and only while there is sound, and the gain changes at a limited rate, so the
+// level doesn't pump between words. peaks never exceed -1 dBFS.
+type AGC struct {
+	sampleRate int
+	channels   int
+	window     int
+
+	// Target is the loudness which speech is normalized to, in dBFS.
+	Target float64
+	// MaxGain is the maximum amplification and attenuation, in dB.
+	MaxGain float64
+
+	enabled  atomicBool
+	loudness float64
+	// gain is the current gain in dB without the limiter's reduction, gainBits holds it for other goroutines.
+	gain      float64
+	gainBits  uint64
+	reduction float64
+}
+
+// NewAGC creates an enabled AGC for audio with the given sample rate and channel count.
+func NewAGC(sampleRate, channels int) *AGC {
+	a := &AGC{
+		sampleRate: sampleRate,
+		channels:   channels,
+		window:     sampleRate / windowsPerSecond * channels,
+		Target:     DefaultAGCTarget,
+		MaxGain:    DefaultAGCMaxGain,
+	}
+
+	a.enabled.Set(true)
+
+	return a
+}
+
+// SetEnabled enables or disables AGC. when it's disabled, gain returns to 0 dB at the same limited rate.
+func (a *AGC) SetEnabled(enabled bool) {
+	a.enabled.Set(enabled)
+}
+
+// Enabled reports whether AGC is enabled.
+func (a *AGC) Enabled() bool {
+	return a.enabled.Get()
+}
+
+// Gain returns the current gain in dB. it's safe to call from other goroutines.
+func (a *AGC) Gain() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&a.gainBits))
+}
+
+// Process implements pipeline.Processor.
+func (a *AGC) Process(pcm []int16) ([]int16, error) {
+	enabled := a.Enabled()
+
+	for start := 0; start < len(pcm); start += a.window {
+		end := start + a.window
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+
+		window := pcm[start:end]
+		frames := len(window) / a.channels
+		duration := float64(frames) / float64(a.sampleRate)
+
+		if l := level(window); l > agcMinLevel {
+			power := math.Pow(10, l/10) //nolint:gomnd
+			if a.loudness == 0 {
+				a.loudness = power
+			}
+
+			a.loudness += (power - a.loudness) * (1 - math.Exp(-duration/agcLoudnessTime.Seconds()))
+		}
+
+		desired := 0.0
+		if enabled && a.loudness > 0 {
+			desired = a.Target - 10*math.Log10(a.loudness) //nolint:gomnd
+			desired = math.Max(math.Min(desired, a.MaxGain), -a.MaxGain)
+		}
+
+		previous := a.gain - a.reduction
+
+		if desired > a.gain {
+			a.gain = math.Min(desired, a.gain+agcRise*duration)
+		} else {
+			a.gain = math.Max(desired, a.gain-agcFall*duration)
+		}
+
+		// the limiter reduces gain of a loud peak instead of clipping it.
+		a.reduction = math.Max(a.reduction-agcLimitRelease*duration, 0)
+		current := a.gain - a.reduction
+
+		if p := peak(window); p > 0 {
+			if limit := agcLimit - 20*math.Log10(p/fullScale); current > limit { //nolint:gomnd
+				a.reduction = a.gain - limit
+				current = limit
+				previous = math.Min(previous, limit)
+			}
+		}
+
+		// gain changes linearly over the window, so it doesn't click.
+		from, to := math.Pow(10, previous/20), math.Pow(10, current/20) //nolint:gomnd
+
+		for i := 0; i < frames; i++ {
+			g := from + (to-from)*float64(i+1)/float64(frames)
+
+			for c := 0; c < a.channels; c++ {
+				j := i*a.channels + c
+				window[j] = clip(float64(window[j]) * g)
+			}
+		}
+	}
+
+	atomic.StoreUint64(&a.gainBits, math.Float64bits(a.gain))
+
+	return pcm, nil
+}
+
+// peak returns the largest absolute sample of pcm.
+func peak(pcm []int16) float64 {
+	var p float64
+
+	for _, s := range pcm {
+		p = math.Max(p, math.Abs(float64(s)))
+	}
+
+	return p
+}
@@ -0,0 +1,58 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+	"github.com/smf8/kenny/pkg/audio/fake"
+)
+
+// TestAGCConvergence amplifies a tone 18 dB below the target, i.e. a quiet laptop mic. gain rises at a limited
+// rate, so it takes a few seconds to reach the target.
+func TestAGCConvergence(t *testing.T) {
+	const sampleRate = 48000
+
+	agc := dsp.NewAGC(sampleRate, 1)
+
+	// a sine's RMS level is 3 dB below its peak, so it's -36 dBFS.
+	amplitude := 32768 * math.Pow(10, (dsp.DefaultAGCTarget-18+3)/20.0)
+	tone := fake.SineSource(440, sampleRate, 1, int16(amplitude))
+
+	var last float64
+
+	// 6 seconds in 10 ms buffers.
+	for i := 0; i < 600; i++ {
+		pcm := make([]int16, sampleRate/100)
+		if _, err := tone.Read(pcm); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := agc.Process(pcm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case i == 99:
+			// 6 dB per second.
+			if gain := agc.Gain(); gain < 4 || gain > 7 {
+				t.Fatalf("gain is %.1f dB after a second", gain)
+			}
+		case i >= 500:
+			last = rms(out)
+		}
+	}
+
+	if gain := agc.Gain(); math.Abs(gain-18) > 1 {
+		t.Fatalf("gain is %.1f dB, want 18 dB", gain)
+	}
+
+	if l := 20 * math.Log10(last/32768); math.Abs(l-dsp.DefaultAGCTarget) > 1 {
+		t.Fatalf("output level is %.1f dBFS, want %d dBFS", l, dsp.DefaultAGCTarget)
+	}
+}
+
+func rms(pcm []int16) float64 {
+	return math.Sqrt(power(pcm) / float64(len(pcm)))
+}
@@ -0,0 +1,22 @@
+package dsp
+
+import "sync/atomic"
+
+// atomicBool is a flag which is set from other goroutines, i.e. a UI toggling a processor
+// while the pipeline runs it. its zero value is false.
+type atomicBool int32
+
+// Set stores v.
+func (b *atomicBool) Set(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+
+	atomic.StoreInt32((*int32)(b), i)
+}
+
+// Get loads the flag.
+func (b *atomicBool) Get() bool {
+	return atomic.LoadInt32((*int32)(b)) == 1
+}
@@ -0,0 +1,118 @@
+package dsp
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultGateThreshold is the default level which opens the gate, in dBFS.
+	DefaultGateThreshold = -50
+	// DefaultGateRange is the default attenuation of a closed gate, in dB.
+	DefaultGateRange = 40
+	// DefaultGateHold is the default time the gate is kept open after the level falls below threshold.
+	DefaultGateHold = 200 * time.Millisecond
+
+	// gateAttack and gateRelease are time constants of opening and closing the gate.
+	gateAttack  = 5 * time.Millisecond
+	gateRelease = 100 * time.Millisecond
+)
+
+// NoiseGate attenuates audio while its level is below a threshold, so background noise between words
+// isn't heard. the gate opens quickly and closes slowly after Hold, so word onsets and endings aren't cut.
+type NoiseGate struct {
+	channels int
+	window   int
+	attack   float64
+	release  float64
+
+	// Threshold is the level which opens the gate, in dBFS.
+	Threshold float64
+	// Range is the attenuation of the closed gate, in dB.
+	Range float64
+	// Hold is the time the gate is kept open after the level falls below Threshold.
+	Hold time.Duration
+
+	sampleRate int
+	enabled    atomicBool
+	gain       float64
+	// held is number of frames which the gate is kept open for.
+	held int
+}
+
+// NewNoiseGate creates an enabled NoiseGate for audio with the given sample rate and channel count.
+func NewNoiseGate(sampleRate, channels int) *NoiseGate {
+	coefficient := func(d time.Duration) float64 {
+		return 1 - math.Exp(-1/(d.Seconds()*float64(sampleRate)))
+	}
+
+	g := &NoiseGate{
+		channels:   channels,
+		window:     sampleRate / windowsPerSecond * channels,
+		attack:     coefficient(gateAttack),
+		release:    coefficient(gateRelease),
+		Threshold:  DefaultGateThreshold,
+		Range:      DefaultGateRange,
+		Hold:       DefaultGateHold,
+		sampleRate: sampleRate,
+		gain:       1,
+	}
+
+	g.enabled.Set(true)
+
+	return g
+}
+
+// SetEnabled enables or disables the gate.
+func (g *NoiseGate) SetEnabled(enabled bool) {
+	g.enabled.Set(enabled)
+}
+
+// Enabled reports whether the gate is enabled.
+func (g *NoiseGate) Enabled() bool {
+	return g.enabled.Get()
+}
+
+// Process implements pipeline.Processor.
+func (g *NoiseGate) Process(pcm []int16) ([]int16, error) {
+	enabled := g.Enabled()
+	closed := math.Pow(10, -g.Range/20) //nolint:gomnd
+	hold := int(g.Hold.Seconds() * float64(g.sampleRate))
+
+	for start := 0; start < len(pcm); start += g.window {
+		end := start + g.window
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+
+		window := pcm[start:end]
+		frames := len(window) / g.channels
+
+		target := 1.0
+
+		if level(window) > g.Threshold {
+			g.held = hold
+		} else if g.held > 0 {
+			g.held -= frames
+		} else if enabled {
+			target = closed
+		}
+
+		// gain moves towards target smoothly for every frame, so opening and closing doesn't click.
+		coefficient := g.release
+		if target > g.gain {
+			coefficient = g.attack
+		}
+
+		for i := 0; i < frames; i++ {
+			g.gain += (target - g.gain) * coefficient
+
+			for c := 0; c < g.channels; c++ {
+				j := i*g.channels + c
+				window[j] = int16(float64(window[j]) * g.gain)
+			}
+		}
+	}
+
+	return pcm, nil
+}
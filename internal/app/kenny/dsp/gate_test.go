@@ -0,0 +1,92 @@
+package dsp_test
+
+import (
+	"testing"
+
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+	"github.com/smf8/kenny/pkg/audio/fake"
+)
+
+// gains passes ms milliseconds of source through processor in 10 ms buffers and returns the RMS gain of each buffer.
+func gains(t *testing.T, processor pipeline.Processor, source fake.Source, ms int) []float64 {
+	t.Helper()
+
+	g := make([]float64, ms/10)
+
+	for i := range g {
+		pcm := make([]int16, testRate/100)
+		if _, err := source.Read(pcm); err != nil {
+			t.Fatal(err)
+		}
+
+		in := rms(pcm)
+
+		out, err := processor.Process(pcm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g[i] = rms(out) / in
+	}
+
+	return g
+}
+
+func TestNoiseGate(t *testing.T) {
+	g := dsp.NewNoiseGate(testRate, 1)
+	quiet, speech := noiseSource(-55), toneSource(-20)
+
+	// the closed gate attenuates by its range, 40 dB.
+	if closed := gains(t, g, quiet, 1000); closed[len(closed)-1] > 0.011 {
+		t.Fatalf("closed gate has %.3f gain", closed[len(closed)-1])
+	}
+
+	// attack opens the gate within the first 20 ms of speech. gains are a bit less than 1 since samples are truncated.
+	open := gains(t, g, speech, 500)
+	if open[0] < 0.5 || open[2] < 0.95 {
+		t.Fatalf("gate didn't open quickly, gains are %.3f and %.3f", open[0], open[2])
+	}
+
+	released := gains(t, g, quiet, 1000)
+
+	// hold keeps the gate open for 200 ms after speech.
+	for i, gain := range released[:20] {
+		if gain < 0.95 {
+			t.Fatalf("gate closed %d ms after speech, during hold", (i+1)*10)
+		}
+	}
+
+	// release closes it slowly, so word endings fade out.
+	if gain := released[24]; gain < 0.5 || gain > 0.9 {
+		t.Fatalf("gate has %.3f gain 50 ms into release", gain)
+	}
+
+	if gain := released[len(released)-1]; gain > 0.011 {
+		t.Fatalf("gate has %.3f gain after release", gain)
+	}
+}
+
+func TestNoiseGateToggle(t *testing.T) {
+	g := dsp.NewNoiseGate(testRate, 1)
+	quiet := noiseSource(-55)
+
+	gains(t, g, quiet, 1000)
+
+	g.SetEnabled(false)
+
+	if g.Enabled() {
+		t.Fatal("gate is enabled after disabling it")
+	}
+
+	// a disabled gate opens like it does for speech, and lets noise through.
+	if disabled := gains(t, g, quiet, 100); disabled[len(disabled)-1] < 0.95 {
+		t.Fatalf("disabled gate has %.3f gain", disabled[len(disabled)-1])
+	}
+
+	g.SetEnabled(true)
+
+	if enabled := gains(t, g, quiet, 1000); enabled[len(enabled)-1] > 0.011 {
+		t.Fatalf("enabled gate has %.3f gain", enabled[len(enabled)-1])
+	}
+}
@@ -0,0 +1,70 @@
+package dsp
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// meterRMSTime is the time constant of RMS level.
+	meterRMSTime = 300 * time.Millisecond
+	// meterPeakFall is how fast the held peak level falls, in dB per second.
+	meterPeakFall = 20
+)
+
+// Meter measures peak and RMS levels of audio which passes through it, i.e. for a level bar graph.
+// it doesn't change the audio.
+type Meter struct {
+	sampleRate int
+	channels   int
+
+	mu    sync.Mutex
+	power float64
+	peak  float64
+}
+
+// NewMeter creates a Meter for audio with the given sample rate and channel count.
+func NewMeter(sampleRate, channels int) *Meter {
+	return &Meter{
+		sampleRate: sampleRate,
+		channels:   channels,
+		peak:       silenceLevel,
+	}
+}
+
+// Process implements pipeline.Processor.
+func (m *Meter) Process(pcm []int16) ([]int16, error) {
+	if len(pcm) == 0 {
+		return pcm, nil
+	}
+
+	duration := time.Duration(len(pcm)/m.channels) * time.Second / time.Duration(m.sampleRate)
+	power := math.Pow(10, level(pcm)/10) //nolint:gomnd
+
+	p := float64(silenceLevel)
+	if s := peak(pcm); s >= 1 {
+		p = 20 * math.Log10(s/fullScale) //nolint:gomnd
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.power += (power - m.power) * (1 - math.Exp(-duration.Seconds()/meterRMSTime.Seconds()))
+	m.peak = math.Max(math.Max(m.peak-meterPeakFall*duration.Seconds(), p), silenceLevel)
+
+	return pcm, nil
+}
+
+// Levels returns peak and RMS levels in dBFS. peak is held and falls slowly as audio passes. it's safe to call from other goroutines.
+func (m *Meter) Levels() (peak, rms float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rms = float64(silenceLevel)
+	if m.power > 0 {
+		rms = math.Max(10*math.Log10(m.power), silenceLevel) //nolint:gomnd
+	}
+
+	return m.peak, rms
+}
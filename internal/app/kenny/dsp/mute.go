@@ -0,0 +1,98 @@
+package dsp
+
+import (
+	"math"
+	"time"
+)
+
+// muteFade is the duration of fading in and out, so muting doesn't click.
+const muteFade = 5 * time.Millisecond
+
+// Mute replaces captured audio with digital silence while the microphone is muted, or in push to talk mode
+// while the user isn't talking. the recording stream stays open, so unmuting is instant, and silence is
+// sent as opus DTX when it's enabled.
+type Mute struct {
+	channels int
+	step     float64
+
+	muted      atomicBool
+	pushToTalk atomicBool
+	talking    atomicBool
+	gain       float64
+}
+
+// NewMute creates an unmuted Mute for audio with the given sample rate and channel count.
+func NewMute(sampleRate, channels int) *Mute {
+	return &Mute{
+		channels: channels,
+		step:     1 / (muteFade.Seconds() * float64(sampleRate)),
+		gain:     1,
+	}
+}
+
+// SetMuted mutes or unmutes the microphone.
+func (m *Mute) SetMuted(muted bool) {
+	m.muted.Set(muted)
+}
+
+// Muted reports whether the microphone is muted.
+func (m *Mute) Muted() bool {
+	return m.muted.Get()
+}
+
+// SetPushToTalk enables or disables push to talk mode, in which audio is only sent while talking is set.
+func (m *Mute) SetPushToTalk(enabled bool) {
+	m.pushToTalk.Set(enabled)
+}
+
+// PushToTalk reports whether push to talk mode is enabled.
+func (m *Mute) PushToTalk() bool {
+	return m.pushToTalk.Get()
+}
+
+// SetTalking sets whether the user is talking in push to talk mode, i.e. while a key is held.
+func (m *Mute) SetTalking(talking bool) {
+	m.talking.Set(talking)
+}
+
+// Talking reports whether the user is talking in push to talk mode.
+func (m *Mute) Talking() bool {
+	return m.talking.Get()
+}
+
+// Open reports whether captured audio is sent, it's neither muted nor waiting for push to talk.
+func (m *Mute) Open() bool {
+	return !m.Muted() && (!m.PushToTalk() || m.Talking())
+}
+
+// Process implements pipeline.Processor.
+func (m *Mute) Process(pcm []int16) ([]int16, error) {
+	target := 0.0
+	if m.Open() {
+		target = 1
+	}
+
+	if m.gain == target {
+		if target == 0 {
+			for i := range pcm {
+				pcm[i] = 0
+			}
+		}
+
+		return pcm, nil
+	}
+
+	for i := 0; i < len(pcm); i += m.channels {
+		if m.gain < target {
+			m.gain = math.Min(m.gain+m.step, target)
+		} else if m.gain > target {
+			m.gain = math.Max(m.gain-m.step, target)
+		}
+
+		for c := i; c < i+m.channels && c < len(pcm); c++ {
+			pcm[c] = clip(float64(pcm[c]) * m.gain)
+		}
+	}
+
+	return pcm, nil
+}
@@ -0,0 +1,67 @@
+package dsp_test
+
+import (
+	"testing"
+
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+)
+
+// TestMuteAfterMeter mutes a tone the way a call does: the meter is before mute, so it still shows
+// the microphone's level while only silence is sent.
+func TestMuteAfterMeter(t *testing.T) {
+	meter := dsp.NewMeter(testRate, 1)
+	mute := dsp.NewMute(testRate, 1)
+	tone := toneSource(-20)
+
+	mute.SetMuted(true)
+
+	// 500 ms in 10 ms buffers.
+	for i := 0; i < 50; i++ {
+		pcm := make([]int16, testRate/100)
+		if _, err := tone.Read(pcm); err != nil {
+			t.Fatal(err)
+		}
+
+		metered, err := meter.Process(pcm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := mute.Process(metered)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the first buffer fades out, so muting doesn't click.
+		if i == 0 {
+			continue
+		}
+
+		for j, s := range out {
+			if s != 0 {
+				t.Fatalf("sample %d of buffer %d is %d while muted", j, i, s)
+			}
+		}
+	}
+
+	if _, rms := meter.Levels(); rms < -22 || rms > -18 {
+		t.Fatalf("meter shows %.1f dBFS while muted, want the tone's -20 dBFS", rms)
+	}
+
+	mute.SetMuted(false)
+
+	pcm := make([]int16, testRate/100)
+	if _, err := tone.Read(pcm); err != nil {
+		t.Fatal(err)
+	}
+
+	// unmuting fades in within 5 ms.
+	out, err := mute.Process(pcm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rms(out[testRate/200:]) < 1000 {
+		t.Fatal("unmuted audio is still silent")
+	}
+}
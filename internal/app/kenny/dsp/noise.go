@@ -0,0 +1,199 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// DefaultNoiseSuppression is the default maximum attenuation of noise, in dB.
+	DefaultNoiseSuppression = 20
+
+	// suppressorFramesPerSecond sets the analysis frame to about 20 ms, it's rounded up to a power of two.
+	suppressorFramesPerSecond = 50
+	// noiseInitFrames is number of frames which the initial noise estimate is averaged over.
+	noiseInitFrames = 10
+	// noiseSpeechRatio is the power ratio above noise estimate which a bin is considered to have speech.
+	noiseSpeechRatio = 4
+	// noiseAdapt is how fast the noise estimate follows bins without speech.
+	noiseAdapt = 0.05
+	// noiseRise is how fast the noise estimate rises in bins with speech in each frame, as a power ratio.
+	// it lets the estimate follow noise which has become louder.
+	noiseRise = 1.007
+	// powerSmoothing is the weight of the previous frame in smoothed power which noise is tracked on.
+	powerSmoothing = 0.7
+	// overSubtraction subtracts more than the estimated noise, so noise fluctuations are suppressed too.
+	overSubtraction = 2
+	// gainSmoothing is the weight of the previous gain of each bin, it reduces musical noise.
+	gainSmoothing = 0.5
+)
+
+// NoiseSuppressor reduces stationary background noise, i.e. fans and air conditioners, by spectral subtraction.
+// audio is analyzed in overlapping frames, noise spectrum is estimated by averaging every frequency while
+// it doesn't contain speech, and each frequency is attenuated by how much of it is noise.
+//
+// it delays audio by half a frame. when it's disabled, audio passes through with the same delay,
+// so toggling it during a call doesn't click.
+type NoiseSuppressor struct {
+	size     int
+	hop      int
+	window   []float64
+	floor    float64
+	enabled  atomicBool
+	channels []*suppressorChannel
+}
+
+type suppressorChannel struct {
+	// input holds the last frame of samples, pending is number of new samples in its last hop.
+	input    []float64
+	pending  int
+	overlap  []float64
+	output   []float64
+	spectrum []complex128
+	power    []float64
+	noise    []float64
+	gain     []float64
+	frames   int
+}
+
+// NewNoiseSuppressor creates an enabled NoiseSuppressor for audio with the given sample rate and channel count.
+// suppression is the maximum attenuation of noise in dB.
+func NewNoiseSuppressor(sampleRate, channels int, suppression float64) *NoiseSuppressor {
+	size := nextPowerOfTwo(sampleRate / suppressorFramesPerSecond)
+
+	n := &NoiseSuppressor{
+		size:   size,
+		hop:    size / 2, //nolint:gomnd
+		window: make([]float64, size),
+		floor:  math.Pow(10, -suppression/20), //nolint:gomnd
+	}
+
+	n.enabled.Set(true)
+
+	// square root of periodic hann window is used for both analysis and synthesis,
+	// so overlapping frames add up to the original signal when nothing is attenuated.
+	for i := range n.window {
+		n.window[i] = math.Sqrt(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size))) //nolint:gomnd
+	}
+
+	bins := size/2 + 1 //nolint:gomnd
+
+	for c := 0; c < channels; c++ {
+		ch := &suppressorChannel{
+			input:    make([]float64, size),
+			overlap:  make([]float64, size),
+			spectrum: make([]complex128, size),
+			power:    make([]float64, bins),
+			noise:    make([]float64, bins),
+			gain:     make([]float64, bins),
+		}
+
+		for k := range ch.gain {
+			ch.gain[k] = 1
+		}
+
+		n.channels = append(n.channels, ch)
+	}
+
+	return n
+}
+
+// SetEnabled enables or disables suppression.
+func (n *NoiseSuppressor) SetEnabled(enabled bool) {
+	n.enabled.Set(enabled)
+}
+
+// Enabled reports whether suppression is enabled.
+func (n *NoiseSuppressor) Enabled() bool {
+	return n.enabled.Get()
+}
+
+// Process implements pipeline.Processor. output is delayed, so it may be shorter or longer than pcm.
+func (n *NoiseSuppressor) Process(pcm []int16) ([]int16, error) {
+	channels := len(n.channels)
+	enabled := n.Enabled()
+
+	for c, ch := range n.channels {
+		for i := c; i < len(pcm); i += channels {
+			// new samples fill the last hop of the frame, then the frame slides by a hop.
+			ch.input[n.size-n.hop+ch.pending] = float64(pcm[i])
+			ch.pending++
+
+			if ch.pending == n.hop {
+				ch.pending = 0
+				n.frame(ch, enabled)
+				copy(ch.input, ch.input[n.hop:])
+			}
+		}
+	}
+
+	frames := len(n.channels[0].output)
+	out := make([]int16, frames*channels)
+
+	for c, ch := range n.channels {
+		for i, s := range ch.output {
+			out[i*channels+c] = clip(s)
+		}
+
+		ch.output = ch.output[:0]
+	}
+
+	return out, nil
+}
+
+// frame suppresses noise of the last frame and adds it to the output with overlap-add.
+func (n *NoiseSuppressor) frame(ch *suppressorChannel, enabled bool) {
+	for i, s := range ch.input {
+		ch.spectrum[i] = complex(s*n.window[i], 0)
+	}
+
+	fft(ch.spectrum, false)
+
+	ch.frames++
+
+	for k := range ch.power {
+		p := real(ch.spectrum[k])*real(ch.spectrum[k]) + imag(ch.spectrum[k])*imag(ch.spectrum[k])
+		ch.power[k] = powerSmoothing*ch.power[k] + (1-powerSmoothing)*p
+
+		switch {
+		case ch.frames <= noiseInitFrames:
+			ch.noise[k] += (p - ch.noise[k]) / float64(ch.frames)
+		case ch.power[k] < noiseSpeechRatio*ch.noise[k]:
+			ch.noise[k] += noiseAdapt * (ch.power[k] - ch.noise[k])
+		default:
+			ch.noise[k] *= noiseRise
+		}
+
+		gain := 1.0
+
+		if enabled && p > 0 {
+			gain = math.Sqrt(math.Max(1-overSubtraction*ch.noise[k]/p, 0))
+		}
+
+		gain = math.Max(gainSmoothing*ch.gain[k]+(1-gainSmoothing)*gain, n.floor)
+		if !enabled {
+			gain = 1
+		}
+
+		ch.gain[k] = gain
+
+		ch.spectrum[k] *= complex(gain, 0)
+		if k > 0 && k < n.size/2 {
+			ch.spectrum[n.size-k] = cmplx.Conj(ch.spectrum[k])
+		}
+	}
+
+	fft(ch.spectrum, true)
+
+	for i := range ch.overlap {
+		ch.overlap[i] += real(ch.spectrum[i]) / float64(n.size) * n.window[i]
+	}
+
+	ch.output = append(ch.output, ch.overlap[:n.hop]...)
+
+	copy(ch.overlap, ch.overlap[n.hop:])
+
+	for i := n.size - n.hop; i < n.size; i++ {
+		ch.overlap[i] = 0
+	}
+}
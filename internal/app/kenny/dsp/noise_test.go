@@ -0,0 +1,125 @@
+package dsp_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+	"github.com/smf8/kenny/pkg/audio/fake"
+)
+
+// suppress passes ms milliseconds of source through n in 10 ms buffers and returns the input and the output.
+func suppress(t *testing.T, n *dsp.NoiseSuppressor, source fake.Source, ms int) (in, out []int16) {
+	t.Helper()
+
+	for i := 0; i < ms/10; i++ {
+		pcm := make([]int16, testRate/100)
+		if _, err := source.Read(pcm); err != nil {
+			t.Fatal(err)
+		}
+
+		in = append(in, pcm...)
+
+		processed, err := n.Process(pcm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out = append(out, processed...)
+	}
+
+	return in, out
+}
+
+// mix returns a source which sums sources.
+func mix(sources ...fake.Source) fake.Source {
+	return fake.SourceFunc(func(pcm []int16) (int, error) {
+		buffer := make([]int16, len(pcm))
+
+		for i := range pcm {
+			pcm[i] = 0
+		}
+
+		for _, s := range sources {
+			if _, err := s.Read(buffer); err != nil {
+				return 0, err
+			}
+
+			for i := range pcm {
+				pcm[i] += buffer[i]
+			}
+		}
+
+		return len(pcm), nil
+	})
+}
+
+// toneAmplitude returns amplitude of the given frequency in pcm, which must have whole periods of it.
+func toneAmplitude(pcm []int16, frequency float64) float64 {
+	var sum complex128
+
+	for i, s := range pcm {
+		sum += complex(float64(s), 0) * cmplx.Exp(complex(0, -2*math.Pi*frequency*float64(i)/testRate))
+	}
+
+	return 2 * cmplx.Abs(sum) / float64(len(pcm))
+}
+
+func TestNoiseSuppressor(t *testing.T) {
+	n := dsp.NewNoiseSuppressor(testRate, 1, dsp.DefaultNoiseSuppression)
+	noise := noiseSource(-40)
+
+	in, out := suppress(t, n, noise, 2000)
+
+	// the estimate is learned in the first second, the stationary noise is attenuated after it.
+	second := testRate
+	if reduction := 20 * math.Log10(rms(in[second:])/rms(out[second:])); reduction < 10 {
+		t.Fatalf("noise is reduced by %.1f dB", reduction)
+	}
+
+	// a tone 20 dB louder than the noise, i.e. speech, is kept.
+	tone := fake.SineSource(1000, testRate, 1, int16(32768*math.Pow(10, -17.0/20)))
+
+	in, out = suppress(t, n, mix(noise, tone), 1000)
+
+	half := testRate / 2
+	if gain := toneAmplitude(out[half:], 1000) / toneAmplitude(in[half:], 1000); gain < 0.8 || gain > 1.1 {
+		t.Fatalf("tone has %.2f gain", gain)
+	}
+}
+
+func TestNoiseSuppressorToggle(t *testing.T) {
+	// half of a 1024 samples frame at 48 kHz.
+	const delay = 512
+
+	n := dsp.NewNoiseSuppressor(testRate, 1, dsp.DefaultNoiseSuppression)
+	noise := noiseSource(-40)
+
+	in, out := suppress(t, n, noise, 1000)
+
+	n.SetEnabled(false)
+
+	if n.Enabled() {
+		t.Fatal("suppressor is enabled after disabling it")
+	}
+
+	// a disabled suppressor passes audio through with the same delay, so it doesn't click.
+	start := len(out) + testRate/10
+
+	disabledIn, disabledOut := suppress(t, n, noise, 1000)
+	in, out = append(in, disabledIn...), append(out, disabledOut...)
+
+	for i := start; i < len(out); i++ {
+		if s, want := out[i], in[i-delay]; math.Abs(float64(s)-float64(want)) > 1 {
+			t.Fatalf("sample %d is %d, want %d", i, s, want)
+		}
+	}
+
+	n.SetEnabled(true)
+
+	in, out = suppress(t, n, noise, 1000)
+	if reduction := 20 * math.Log10(rms(in)/rms(out)); reduction < 10 {
+		t.Fatalf("noise is reduced by %.1f dB after enabling the suppressor again", reduction)
+	}
+}
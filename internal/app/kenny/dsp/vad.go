@@ -0,0 +1,138 @@
+// Package dsp contains pcm processors for the audio pipeline.
+// every processor implements pipeline.Processor and works on interleaved 16 bit pcm data.
+package dsp
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultVADThreshold is the default level above noise floor which is considered speech, in dB.
+	DefaultVADThreshold = 9
+	// DefaultVADHangover is the default time the speaking state is kept after speech ends.
+	DefaultVADHangover = 300 * time.Millisecond
+
+	// windowsPerSecond splits audio into 10 ms analysis windows.
+	windowsPerSecond = 100
+	// minSpeechLevel is the level below which nothing is considered speech, in dBFS.
+	minSpeechLevel = -55
+	// noiseFloorRise is how fast noise floor rises towards louder levels in each window, in dB.
+	noiseFloorRise = 0.05
+	// silenceLevel is the level of digital silence, in dBFS.
+	silenceLevel = -96
+	fullScale    = 32768
+)
+
+// VAD is an energy based voice activity detector. it tracks the noise floor and detects speech
+// when a window is louder than the floor by Threshold. speaking state is kept for Hangover
+// after the last speech window, so quiet word endings aren't cut.
+type VAD struct {
+	window int
+
+	// Threshold is the level above noise floor which is considered speech, in dB.
+	Threshold float64
+	// Hangover is the time speaking state is kept after speech ends.
+	Hangover time.Duration
+	// Gate replaces non-speech audio with digital silence, so opus DTX stops sending packets.
+	Gate bool
+
+	noiseFloor float64
+	started    bool
+	// quiet is number of windows since the last speech window.
+	quiet    int
+	speaking int32
+}
+
+// NewVAD creates a VAD for audio with the given sample rate and channel count.
+func NewVAD(sampleRate, channels int) *VAD {
+	return &VAD{
+		window:    sampleRate / windowsPerSecond * channels,
+		Threshold: DefaultVADThreshold,
+		Hangover:  DefaultVADHangover,
+		Gate:      true,
+	}
+}
+
+// Speaking reports whether speech is detected. it's safe to call from other goroutines.
+func (v *VAD) Speaking() bool {
+	return atomic.LoadInt32(&v.speaking) == 1
+}
+
+// Process implements pipeline.Processor.
+func (v *VAD) Process(pcm []int16) ([]int16, error) {
+	hangover := int(v.Hangover.Seconds() * windowsPerSecond)
+
+	for start := 0; start < len(pcm); start += v.window {
+		end := start + v.window
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+
+		window := pcm[start:end]
+
+		if v.detect(level(window)) {
+			v.quiet = 0
+		} else if v.quiet <= hangover {
+			v.quiet++
+		}
+
+		speaking := v.quiet <= hangover
+		if speaking {
+			atomic.StoreInt32(&v.speaking, 1)
+		} else {
+			atomic.StoreInt32(&v.speaking, 0)
+
+			if v.Gate {
+				for i := range window {
+					window[i] = 0
+				}
+			}
+		}
+	}
+
+	return pcm, nil
+}
+
+// detect updates noise floor with a window's level and reports whether it's speech.
+func (v *VAD) detect(l float64) bool {
+	if !v.started {
+		v.started = true
+		v.noiseFloor = l
+		// the first window starts in hangover, so a call doesn't start with cut audio.
+		return true
+	}
+
+	speech := l > minSpeechLevel && l > v.noiseFloor+v.Threshold
+
+	// noise floor follows quieter levels immediately and louder levels slowly, even slower during speech.
+	rise := noiseFloorRise
+	if speech {
+		rise /= 10
+	}
+
+	v.noiseFloor = math.Min(v.noiseFloor+rise, l)
+
+	return speech
+}
+
+// level returns RMS level of interleaved pcm data in dBFS.
+func level(pcm []int16) float64 {
+	if len(pcm) == 0 {
+		return silenceLevel
+	}
+
+	var sum float64
+
+	for _, s := range pcm {
+		sum += float64(s) * float64(s)
+	}
+
+	rms := math.Sqrt(sum / float64(len(pcm)))
+	if rms < 1 {
+		return silenceLevel
+	}
+
+	return 20 * math.Log10(rms/fullScale) //nolint:gomnd
+}
@@ -0,0 +1,145 @@
+package dsp_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+	"github.com/smf8/kenny/pkg/audio/fake"
+)
+
+const testRate = 48000
+
+// noiseSource returns steady white noise with the given RMS level in dBFS.
+func noiseSource(level float64) fake.Source {
+	r := rand.New(rand.NewSource(1)) //nolint:gosec
+	// uniform noise's RMS level is 4.8 dB below its peak.
+	amplitude := 32768 * math.Pow(10, level/20) * math.Sqrt(3)
+
+	return fake.SourceFunc(func(pcm []int16) (int, error) {
+		for i := range pcm {
+			pcm[i] = int16(amplitude * (2*r.Float64() - 1))
+		}
+
+		return len(pcm), nil
+	})
+}
+
+// toneSource returns a tone with the given RMS level in dBFS.
+func toneSource(level float64) fake.Source {
+	return fake.SineSource(440, testRate, 1, int16(32768*math.Pow(10, (level+3)/20)))
+}
+
+// feed passes ms milliseconds of source through v in 10 ms buffers and returns speaking state after each buffer
+// and the processed audio.
+func feed(t *testing.T, v *dsp.VAD, source fake.Source, ms int) ([]bool, []int16) {
+	t.Helper()
+
+	var (
+		speaking []bool
+		out      []int16
+	)
+
+	for i := 0; i < ms/10; i++ {
+		pcm := make([]int16, testRate/100)
+		if _, err := source.Read(pcm); err != nil {
+			t.Fatal(err)
+		}
+
+		processed, err := v.Process(pcm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		speaking = append(speaking, v.Speaking())
+		out = append(out, processed...)
+	}
+
+	return speaking, out
+}
+
+func TestVADHangover(t *testing.T) {
+	v := dsp.NewVAD(testRate, 1)
+	background := noiseSource(-60)
+
+	if speaking, _ := feed(t, v, background, 1000); speaking[len(speaking)-1] {
+		t.Fatal("background noise is detected as speech")
+	}
+
+	speaking, _ := feed(t, v, toneSource(-20), 500)
+	for i, s := range speaking {
+		if !s {
+			t.Fatalf("speech isn't detected after %d ms", (i+1)*10)
+		}
+	}
+
+	speaking, out := feed(t, v, background, 1000)
+
+	// 300 ms of hangover.
+	for i, s := range speaking {
+		switch ms := (i + 1) * 10; {
+		case ms <= 300 && !s:
+			t.Fatalf("speaking state ended %d ms after speech, during hangover", ms)
+		case ms > 310 && s:
+			t.Fatalf("still speaking %d ms after speech", ms)
+		}
+	}
+
+	// the gate silences audio after hangover, so opus DTX stops sending packets.
+	silent := out[320*testRate/1000:]
+	for i, s := range silent {
+		if s != 0 {
+			t.Fatalf("sample %d after hangover isn't gated: %d", i, s)
+		}
+	}
+
+	e, err := encoding.NewEncoder(testRate, 1, testRate/50)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.SetDTX(true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Write(silent); err != nil {
+		t.Fatal(err)
+	}
+
+	var frame []byte
+
+	for {
+		packet, ok := e.Buffer.TryRead()
+		if !ok {
+			break
+		}
+
+		frame = packet
+	}
+
+	if packet, err := transport.NewPacketizer().Packetize(frame); err != nil || packet != nil {
+		t.Fatalf("gated silence is sent: %v, %v", packet, err)
+	}
+}
+
+func TestVADNoiseFloorAdapts(t *testing.T) {
+	v := dsp.NewVAD(testRate, 1)
+
+	feed(t, v, noiseSource(-50), 1000)
+
+	// a steady noise 10 dB louder, i.e. a fan turning on, is speech at first.
+	speaking, _ := feed(t, v, noiseSource(-40), 10000)
+	if !speaking[10] {
+		t.Fatal("louder noise isn't detected as speech")
+	}
+
+	// noise floor rises slowly towards the new noise, then it's not speech anymore.
+	for i, s := range speaking[len(speaking)-200:] {
+		if s {
+			t.Fatalf("steady noise is speech %d ms before the end", (200-i)*10)
+		}
+	}
+}
@@ -1,26 +1,44 @@
 package encoding
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
-	"io"
+	"sync/atomic"
 
-	log "github.com/sirupsen/logrus"
 	"gopkg.in/hraban/opus.v2"
 )
 
 // Decoder is used to decode opus encoded data into PCM.
 type Decoder struct {
-	Buffer     *bytes.Buffer
+	// decoded frames and samples (per channel) counters, they are accessed atomically.
+	// they come first to be 64-bit aligned on 32-bit platforms.
+	frames    uint64
+	samples   uint64
+	concealed uint64
+	recovered uint64
+	comfort   uint64
+
 	D          *opus.Decoder
 	SampleRate int
 	Channels   int
 	FrameSize  int
 }
 
+// DecoderStats contains decoder counters.
+type DecoderStats struct {
+	// Frames is number of decoded frames.
+	Frames uint64
+	// Samples is number of decoded samples per channel, including concealed ones.
+	Samples uint64
+	// Concealed is number of lost frames synthesized by opus packet loss concealment.
+	Concealed uint64
+	// Recovered is number of lost frames recovered from in-band FEC data of the next packet.
+	Recovered uint64
+	// Comfort is number of comfort noise frames generated during transmission gaps.
+	Comfort uint64
+}
+
 // NewDecoder creates a Decoder instance.
-// buffer is the encoded data buffer. call Decode to start decoding data Decoder.Buffer.
+// frameSize is the maximum number of samples per channel in a frame.
 func NewDecoder(sampleRate, channels, frameSize int) (*Decoder, error) {
 	d, err := opus.NewDecoder(sampleRate, channels)
 	if err != nil {
@@ -28,7 +46,6 @@ func NewDecoder(sampleRate, channels, frameSize int) (*Decoder, error) {
 	}
 
 	return &Decoder{
-		Buffer:     new(bytes.Buffer),
 		D:          d,
 		SampleRate: sampleRate,
 		Channels:   channels,
@@ -36,33 +53,99 @@ func NewDecoder(sampleRate, channels, frameSize int) (*Decoder, error) {
 	}, nil
 }
 
-// DecodeFrame decodes given frame to pcm data, the frame must be a valid opus frame data
-// encoded with Decoder.FrameSize. decoded pcm data is saved in Decoder.Buffer
-func (d *Decoder) DecodeFrame(frame []byte) error {
-	pcmData := make([]int16, d.FrameSize)
+// DecodeFrame decodes given frame and returns interleaved pcm data.
+// the frame must be a valid opus frame not longer than Decoder.FrameSize.
+func (d *Decoder) DecodeFrame(frame []byte) ([]int16, error) {
+	pcmData := make([]int16, d.FrameSize*d.Channels)
 
 	n, err := d.D.Decode(frame, pcmData)
 	if err != nil {
-		return fmt.Errorf("failed to decode opus data to pcm: %w", err)
+		return nil, fmt.Errorf("failed to decode opus data to pcm: %w", err)
 	}
 
-	err = binary.Write(d.Buffer, binary.BigEndian, pcmData[:n])
+	atomic.AddUint64(&d.frames, 1)
+	atomic.AddUint64(&d.samples, uint64(n))
+
+	return pcmData[:n*d.Channels], nil
+}
+
+// ConcealFrame synthesizes a lost frame. if the packet after the lost one is given, the frame is recovered
+// from its in-band FEC data, otherwise opus packet loss concealment is used.
+// the lost frame is assumed to be as long as the next packet, or the last decoded one if next is nil.
+func (d *Decoder) ConcealFrame(next []byte) ([]int16, error) {
+	samples, err := d.lostSamples(next)
 	if err != nil {
-		return fmt.Errorf("failed to save pcm data to decoder buffer: %w", err)
+		return nil, err
 	}
 
-	return nil
-}
+	// opus uses capacity of the buffer as frame size, so it's allocated with the exact size.
+	pcmData := make([]int16, samples*d.Channels)
 
-//CallbackPlay is a portaudio callback function to decode and write Data to output device
-func (d *Decoder) CallbackPlay(output []int16) {
-	if err := binary.Read(d.Buffer, binary.BigEndian, output); err != nil {
-		for i := range output {
-			output[i] = 0
+	if next != nil {
+		if err := d.D.DecodeFEC(next, pcmData); err != nil {
+			return nil, fmt.Errorf("failed to decode opus fec data: %w", err)
 		}
 
-		if err != io.EOF {
-			log.Errorf("failed to play during callback: %s", err.Error())
+		atomic.AddUint64(&d.recovered, 1)
+	} else {
+		if err := d.D.DecodePLC(pcmData); err != nil {
+			return nil, fmt.Errorf("failed to conceal lost opus frame: %w", err)
+		}
+
+		atomic.AddUint64(&d.concealed, 1)
+	}
+
+	atomic.AddUint64(&d.samples, uint64(samples))
+
+	return pcmData, nil
+}
+
+// ComfortNoise generates a frame of comfort noise for a gap in transmission, i.e. when the remote peer uses DTX.
+// opus concealment fades into comfort noise which matches the background noise of the last decoded frames.
+func (d *Decoder) ComfortNoise() ([]int16, error) {
+	samples, err := d.lostSamples(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pcmData := make([]int16, samples*d.Channels)
+
+	if err := d.D.DecodePLC(pcmData); err != nil {
+		return nil, fmt.Errorf("failed to generate comfort noise: %w", err)
+	}
+
+	atomic.AddUint64(&d.comfort, 1)
+	atomic.AddUint64(&d.samples, uint64(samples))
+
+	return pcmData, nil
+}
+
+func (d *Decoder) lostSamples(next []byte) (int, error) {
+	if next != nil {
+		samples, err := PacketSamples(next)
+		if err != nil {
+			return 0, err
 		}
+
+		return samples * d.SampleRate / OpusGranuleRate, nil
+	}
+
+	samples, err := d.D.LastPacketDuration()
+	if err != nil || samples == 0 {
+		// nothing is decoded yet, conceal a single 20 ms frame.
+		return d.SampleRate / 50, nil //nolint:gomnd
+	}
+
+	return samples, nil
+}
+
+// Stats returns decoder counters. it's safe to call while decoding.
+func (d *Decoder) Stats() DecoderStats {
+	return DecoderStats{
+		Frames:    atomic.LoadUint64(&d.frames),
+		Samples:   atomic.LoadUint64(&d.samples),
+		Concealed: atomic.LoadUint64(&d.concealed),
+		Recovered: atomic.LoadUint64(&d.recovered),
+		Comfort:   atomic.LoadUint64(&d.comfort),
 	}
 }
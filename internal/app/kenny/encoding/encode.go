@@ -1,15 +1,34 @@
 package encoding
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
 
-	log "github.com/sirupsen/logrus"
 	"gopkg.in/hraban/opus.v2"
 )
 
 const (
-	bufferSize      = 1024
+	// maxPacketSize is the recommended opus packet buffer size by libopus.
+	maxPacketSize   = 4000
 	frameBufferSize = 1000
+
+	// encoder lookahead durations as fractions of a second, 2.5 ms and 4 ms.
+	celtOverlapRate       = 400
+	delayCompensationRate = 250
+)
+
+var (
+	// ErrInvalidFrameSize occurs when frame size is not 2.5, 5, 10, 20, 40 or 60 ms of audio.
+	ErrInvalidFrameSize = errors.New("invalid opus frame size")
+	// ErrInvalidSampleRate occurs when sample rate is not supported by opus.
+	ErrInvalidSampleRate = errors.New("opus only supports 8, 12, 16, 24 and 48 kHz sample rates")
+	// ErrInvalidChannels occurs when channel count is not supported by opus encoder.
+	ErrInvalidChannels = errors.New("opus only supports mono and stereo audio")
+	// ErrPartialSample occurs when pcm data doesn't contain a whole number of interleaved samples.
+	ErrPartialSample = errors.New("pcm data length must be a multiple of channel count")
+	// ErrInvalidPacketLoss occurs when packet loss percentage is not between 0 and 100.
+	ErrInvalidPacketLoss = errors.New("packet loss percentage must be between 0 and 100")
 )
 
 // Encoder represents an opus audio encoder.
@@ -18,52 +37,179 @@ type Encoder struct {
 	E          *opus.Encoder
 	SampleRate int
 	Channels   int
+	// FrameSize is the number of samples per channel in each frame.
+	FrameSize int
 
 	// opus encoder only accepts PCM audio data of size 2.5, 5, 10, 20, 40, 60 ms
-	// but portaudio callback function is called whenever it has captured audio data
-	// so we use a temporary buffer to store one frame.
+	// but audio devices deliver data in their own chunk size,
+	// so we use a temporary buffer to store one frame and carry leftover samples into the next frame.
 	pcmBuffer []int16
 	pcmCursor int
-	FrameSize int
+	packet    []byte
+
+	// packetLoss is the expected packet loss percentage requested by SetPacketLoss, it's accessed atomically.
+	// it's applied before encoding the next frame, because opus encoder can't be configured while encoding.
+	packetLoss        int32
+	appliedPacketLoss int32
+}
+
+// ValidateFrameSize checks whether frameSize (samples per channel) is a legal opus frame duration
+// for the given sample rate and channel count.
+//
+//nolint:gomnd
+func ValidateFrameSize(sampleRate, channels, frameSize int) error {
+	switch sampleRate {
+	case 8000, 12000, 16000, 24000, 48000:
+	default:
+		return fmt.Errorf("%w: %d", ErrInvalidSampleRate, sampleRate)
+	}
+
+	if channels != 1 && channels != 2 {
+		return fmt.Errorf("%w: %d channels", ErrInvalidChannels, channels)
+	}
+
+	// legal durations in units of 2.5 ms.
+	for _, d := range []int{1, 2, 4, 8, 16, 24} {
+		if frameSize*400 == sampleRate*d {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %d samples at %d Hz, frames must be 2.5, 5, 10, 20, 40 or 60 ms",
+		ErrInvalidFrameSize, frameSize, sampleRate)
 }
 
 // NewEncoder creates a new encoder instance with a new buffer. You can access encoded data from buffer.
+// frameSize is the number of samples per channel in each opus frame.
 func NewEncoder(sampleRate, channels, frameSize int) (*Encoder, error) {
+	if err := ValidateFrameSize(sampleRate, channels, frameSize); err != nil {
+		return nil, err
+	}
+
 	e, err := opus.NewEncoder(sampleRate, channels, opus.AppVoIP)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create opus encoder: %w", err)
 	}
 
+	buffer, err := NewFrameBuffer(frameBufferSize, DropOldest)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Encoder{
-		Buffer:     NewFrameBuffer(frameBufferSize),
+		Buffer:     buffer,
 		E:          e,
 		SampleRate: sampleRate,
 		Channels:   channels,
-		pcmBuffer:  make([]int16, frameSize),
+		FrameSize:  frameSize,
+		pcmBuffer:  make([]int16, frameSize*channels),
+		packet:     make([]byte, maxPacketSize),
 	}, nil
 }
 
-// CallbackRecord handles recording and encoding procedures.
-// input pcm data is recorded and when it reaches Encoder.FrameSize
-// it will be written into the buffer as an opus frame
-func (e *Encoder) CallbackRecord(input []int16) {
-	if e.pcmCursor+len(input) >= len(e.pcmBuffer) {
-		// pcmBuffer is full, encode a frame
-		overflow := len(e.pcmBuffer) - e.pcmCursor
-		copy(e.pcmBuffer[e.pcmCursor:], input[:overflow])
-		e.pcmCursor = 0
-
-		// encode e.pcmBuffer
-		b := make([]byte, bufferSize)
-		n, err := e.E.Encode(e.pcmBuffer, b)
-
-		if err != nil {
-			log.Errorf("failed to encode pcm data: %s", err.Error())
+// Lookahead returns number of samples per channel the encoder delays audio by, the value libopus reports with
+// OPUS_GET_LOOKAHEAD. opus bindings don't expose it, so it's computed the same way: 2.5 ms of
+// CELT overlap and 4 ms of delay compensation, which voip and audio applications add.
+func (e *Encoder) Lookahead() int {
+	return e.SampleRate/celtOverlapRate + e.SampleRate/delayCompensationRate
+}
+
+// PreSkip returns the encoder's lookahead at 48 kHz, the number of samples a decoder must discard from
+// the beginning of an Ogg Opus stream.
+func (e *Encoder) PreSkip() uint16 {
+	return uint16(e.Lookahead() * OpusGranuleRate / e.SampleRate)
+}
+
+// SetFEC enables or disables opus in-band forward error correction.
+// with FEC each packet carries a low bitrate copy of the previous frame, so the receiver can recover a lost frame
+// from the next packet. opus only adds FEC data when expected packet loss is more than zero.
+// it must not be called while encoding.
+func (e *Encoder) SetFEC(enabled bool) error {
+	if err := e.E.SetInBandFEC(enabled); err != nil {
+		return fmt.Errorf("failed to set opus in-band fec: %w", err)
+	}
+
+	return nil
+}
+
+// SetDTX enables or disables opus discontinuous transmission. with DTX, silent frames are encoded
+// as 1 or 2 byte packets which don't need to be sent. it must not be called while encoding.
+func (e *Encoder) SetDTX(enabled bool) error {
+	if err := e.E.SetDTX(enabled); err != nil {
+		return fmt.Errorf("failed to set opus dtx: %w", err)
+	}
+
+	return nil
+}
+
+// SetPacketLoss sets the expected packet loss percentage, i.e. from receiver reports.
+// opus uses it to tune FEC. it's safe to call while encoding, the value is applied before the next frame.
+func (e *Encoder) SetPacketLoss(percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("%w: %d", ErrInvalidPacketLoss, percent)
+	}
+
+	atomic.StoreInt32(&e.packetLoss, int32(percent))
+
+	return nil
+}
+
+// Write adds interleaved pcm data to the current frame. each time a frame is completed
+// it's encoded and written into Encoder.Buffer. samples which don't fill a frame are kept for the next call.
+func (e *Encoder) Write(pcm []int16) error {
+	if len(pcm)%e.Channels != 0 {
+		return ErrPartialSample
+	}
+
+	for len(pcm) > 0 {
+		n := copy(e.pcmBuffer[e.pcmCursor:], pcm)
+		e.pcmCursor += n
+		pcm = pcm[n:]
+
+		if e.pcmCursor == len(e.pcmBuffer) {
+			e.pcmCursor = 0
+
+			if err := e.encode(); err != nil {
+				return err
+			}
 		}
+	}
 
-		e.Buffer.Write(b[:n])
-	} else {
-		copy(e.pcmBuffer[e.pcmCursor:], input)
-		e.pcmCursor += len(input)
+	return nil
+}
+
+// Flush pads the current partial frame with silence and encodes it. use it after the last Write.
+func (e *Encoder) Flush() error {
+	if e.pcmCursor == 0 {
+		return nil
 	}
+
+	for i := e.pcmCursor; i < len(e.pcmBuffer); i++ {
+		e.pcmBuffer[i] = 0
+	}
+
+	e.pcmCursor = 0
+
+	return e.encode()
+}
+
+func (e *Encoder) encode() error {
+	if loss := atomic.LoadInt32(&e.packetLoss); loss != e.appliedPacketLoss {
+		if err := e.E.SetPacketLossPerc(int(loss)); err != nil {
+			return fmt.Errorf("failed to set opus packet loss percentage: %w", err)
+		}
+
+		e.appliedPacketLoss = loss
+	}
+
+	n, err := e.E.Encode(e.pcmBuffer, e.packet)
+	if err != nil {
+		return fmt.Errorf("failed to encode pcm data: %w", err)
+	}
+
+	if err := e.Buffer.Write(e.packet[:n]); err != nil {
+		return fmt.Errorf("failed to buffer encoded frame: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,110 @@
+package encoding_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+)
+
+// TestEncoderCarriesLeftoverSamples writes a stereo tone in chunks which don't line up with 20 ms frames,
+// and checks that every frame is encoded once it's filled and decodes to the continuous tone.
+func TestEncoderCarriesLeftoverSamples(t *testing.T) {
+	const (
+		channels  = 2
+		frameSize = 960
+	)
+
+	e, err := encoding.NewEncoder(48000, channels, frameSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := encoding.NewDecoder(48000, channels, frameSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 frames and 100 samples per channel.
+	tone := make([]int16, (5*frameSize+100)*channels)
+	for i := range tone {
+		tone[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i/channels)/48000))
+	}
+
+	var decoded []int16
+
+	decode := func() {
+		for {
+			packet, ok := e.Buffer.TryRead()
+			if !ok {
+				return
+			}
+
+			pcm, err := d.DecodeFrame(packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded = append(decoded, pcm...)
+		}
+	}
+
+	written := 0
+
+	for _, frames := range []int{1, 479, 1000, 333, 2000, 1, 1086} {
+		chunk := tone[written : written+frames*channels]
+		written += len(chunk)
+
+		if err := e.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+
+		decode()
+
+		// only whole frames are encoded, the rest waits for the next chunk.
+		if want := written / channels / frameSize; e.Buffer.Stats().Written != uint64(want) {
+			t.Fatalf("encoded %d frames after %d samples, want %d", e.Buffer.Stats().Written, written, want)
+		}
+	}
+
+	if written != len(tone) {
+		t.Fatalf("wrote %d samples of %d", written, len(tone))
+	}
+
+	if err := e.Write(tone[:1]); !errors.Is(err, encoding.ErrPartialSample) {
+		t.Fatalf("writing half a stereo frame returned %v, want %v", err, encoding.ErrPartialSample)
+	}
+
+	// the last 100 samples are padded into a frame.
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	decode()
+
+	if len(decoded) != 6*frameSize*channels {
+		t.Fatalf("decoded %d samples, want %d", len(decoded), 6*frameSize*channels)
+	}
+
+	// a lost or repeated chunk breaks the tone. opus is lossy and delays it by its lookahead, so it's compared
+	// with the best aligned tone.
+	best := 0.0
+
+	for delay := 0; delay <= 2*e.Lookahead(); delay++ {
+		var dot, dd, tt float64
+
+		for i := delay * channels; i < len(tone); i++ {
+			a, b := float64(decoded[i]), float64(tone[i-delay*channels])
+			dot += a * b
+			dd += a * a
+			tt += b * b
+		}
+
+		best = math.Max(best, dot/math.Sqrt(dd*tt))
+	}
+
+	if best < 0.9 {
+		t.Fatalf("decoded audio correlates %.2f with the written tone", best)
+	}
+}
@@ -1,43 +1,201 @@
 package encoding
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"sync"
 )
 
-// FrameBuffer represents opus data frame. each frame contains a variable sized []byte data,
+// OverflowPolicy decides what FrameBuffer.Write does when the buffer is full.
+type OverflowPolicy int
+
+const (
+	// DropOldest discards the oldest unread frame to make room for the new one.
+	DropOldest OverflowPolicy = iota
+	// DropNewest discards the frame being written.
+	DropNewest
+	// Block waits until a reader makes room for the new frame.
+	Block
+)
+
+var (
+	// ErrBufferClosed occurs when writing to a closed FrameBuffer.
+	ErrBufferClosed = errors.New("frame buffer is closed")
+	// ErrInvalidBufferSize occurs when a FrameBuffer can't hold a single frame.
+	ErrInvalidBufferSize = errors.New("frame buffer size must be at least 1")
+)
+
+// FrameBufferStats represents FrameBuffer counters.
+type FrameBufferStats struct {
+	// Queued is the number of unread frames.
+	Queued int
+	// Written is the number of frames accepted by Write.
+	Written uint64
+	// Read is the number of frames returned by Read.
+	Read uint64
+	// Dropped is the number of frames discarded by the overflow policy.
+	Dropped uint64
+}
+
+// FrameBuffer represents a queue of opus data frames. each frame contains a variable sized []byte data,
 // and providing slices of data with different size leads to decoding error.
 // so a circular array of []byte are used to save opus audio frames.
+//
+// FrameBuffer is safe for concurrent use. Read blocks until a frame is available.
 type FrameBuffer struct {
-	buffer                [][]byte
-	readIndex, writeIndex int
-	size                  int
+	mu     sync.Mutex
+	buffer [][]byte
+	head   int
+	count  int
+	policy OverflowPolicy
+	closed bool
+	stats  FrameBufferStats
+
+	// changed is closed and replaced whenever a frame is written, read or the buffer is closed.
+	changed chan struct{}
 }
 
-//NewFrameBuffer creates a new FrameBuffer.
-func NewFrameBuffer(size int) *FrameBuffer {
-	b := make([][]byte, size)
+// NewFrameBuffer creates a new FrameBuffer which holds at most size frames.
+func NewFrameBuffer(size int, policy OverflowPolicy) (*FrameBuffer, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidBufferSize, size)
+	}
 
 	return &FrameBuffer{
-		buffer: b,
-		size:   size,
+		buffer:  make([][]byte, size),
+		policy:  policy,
+		changed: make(chan struct{}),
+	}, nil
+}
+
+// Write adds a copy of data to the buffer. when the buffer is full, the overflow policy is applied.
+// with Block policy it waits as long as needed, use WriteContext to limit the wait.
+func (f *FrameBuffer) Write(data []byte) error {
+	return f.WriteContext(context.Background(), data)
+}
+
+// WriteContext is like Write, but it stops waiting for room when ctx is done.
+func (f *FrameBuffer) WriteContext(ctx context.Context, data []byte) error {
+	frame := make([]byte, len(data))
+	copy(frame, data)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for !f.closed && f.count == len(f.buffer) {
+		switch f.policy {
+		case DropOldest:
+			f.buffer[f.head] = nil
+			f.head = (f.head + 1) % len(f.buffer)
+			f.count--
+			f.stats.Dropped++
+		case DropNewest:
+			f.stats.Dropped++
+
+			return nil
+		case Block:
+			if err := f.wait(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if f.closed {
+		return ErrBufferClosed
+	}
+
+	f.buffer[(f.head+f.count)%len(f.buffer)] = frame
+	f.count++
+	f.stats.Written++
+	f.notify()
+
+	return nil
+}
+
+// Read returns the oldest unread frame. it blocks until a frame is available or ctx is done.
+// after the buffer is closed, remaining frames are returned and then io.EOF.
+func (f *FrameBuffer) Read(ctx context.Context) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for f.count == 0 {
+		if f.closed {
+			return nil, io.EOF
+		}
+
+		if err := f.wait(ctx); err != nil {
+			return nil, err
+		}
 	}
+
+	return f.pop(), nil
 }
 
-// Write writes data to buffer at writeIndex.
-func (f *FrameBuffer) Write(data []byte) {
-	f.buffer[f.writeIndex] = make([]byte, len(data))
-	copy(f.buffer[f.writeIndex], data)
-	f.writeIndex = (f.writeIndex + 1) % f.size
+// TryRead returns the oldest unread frame without blocking. it reports false if the buffer is empty.
+func (f *FrameBuffer) TryRead() ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.count == 0 {
+		return nil, false
+	}
+
+	return f.pop(), true
 }
 
-// Read returns data currently located in readIndex cursor.
-func (f *FrameBuffer) Read() ([]byte, error) {
-	data := f.buffer[f.readIndex]
-	if data == nil {
-		return nil, io.EOF
+// Close closes the buffer. next writes fail and readers receive io.EOF once remaining frames are read.
+func (f *FrameBuffer) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.closed {
+		f.closed = true
+		f.notify()
 	}
+}
+
+// Stats returns a snapshot of buffer counters.
+func (f *FrameBuffer) Stats() FrameBufferStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-	f.readIndex = (f.readIndex + 1) % f.size
+	stats := f.stats
+	stats.Queued = f.count
 
-	return data, nil
+	return stats
+}
+
+// pop removes the oldest frame. f.mu must be held and buffer must not be empty.
+func (f *FrameBuffer) pop() []byte {
+	data := f.buffer[f.head]
+	f.buffer[f.head] = nil
+	f.head = (f.head + 1) % len(f.buffer)
+	f.count--
+	f.stats.Read++
+	f.notify()
+
+	return data
+}
+
+// notify wakes up every waiting reader and writer. f.mu must be held.
+func (f *FrameBuffer) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// wait releases f.mu until buffer changes or ctx is done. f.mu must be held.
+func (f *FrameBuffer) wait(ctx context.Context) error {
+	changed := f.changed
+
+	f.mu.Unlock()
+	defer f.mu.Lock()
+
+	select {
+	case <-changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
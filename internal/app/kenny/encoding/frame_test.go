@@ -0,0 +1,221 @@
+package encoding_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+)
+
+func TestNewFrameBufferSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		if _, err := encoding.NewFrameBuffer(size, encoding.DropOldest); !errors.Is(err, encoding.ErrInvalidBufferSize) {
+			t.Fatalf("size %d returned %v, want %v", size, err, encoding.ErrInvalidBufferSize)
+		}
+	}
+
+	if _, err := encoding.NewFrameBuffer(1, encoding.DropOldest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readAll reads every queued frame without blocking.
+func readAll(f *encoding.FrameBuffer) [][]byte {
+	var frames [][]byte
+
+	for {
+		frame, ok := f.TryRead()
+		if !ok {
+			return frames
+		}
+
+		frames = append(frames, frame)
+	}
+}
+
+func TestFrameBufferOverflow(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy encoding.OverflowPolicy
+		// write is the error of writing the third frame to a buffer of two.
+		write error
+		read  [][]byte
+		stats encoding.FrameBufferStats
+	}{
+		{
+			name:   "drop oldest",
+			policy: encoding.DropOldest,
+			read:   [][]byte{{2}, {3}},
+			stats:  encoding.FrameBufferStats{Queued: 2, Written: 3, Dropped: 1},
+		},
+		{
+			name:   "drop newest",
+			policy: encoding.DropNewest,
+			read:   [][]byte{{1}, {2}},
+			stats:  encoding.FrameBufferStats{Queued: 2, Written: 2, Dropped: 1},
+		},
+		{
+			name:   "block",
+			policy: encoding.Block,
+			write:  context.DeadlineExceeded,
+			read:   [][]byte{{1}, {2}},
+			stats:  encoding.FrameBufferStats{Queued: 2, Written: 2},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := encoding.NewFrameBuffer(2, c.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, frame := range [][]byte{{1}, {2}} {
+				if err := f.Write(frame); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			if err := f.WriteContext(ctx, []byte{3}); !errors.Is(err, c.write) {
+				t.Fatalf("writing to a full buffer returned %v, want %v", err, c.write)
+			}
+
+			if stats := f.Stats(); stats != c.stats {
+				t.Fatalf("stats are %+v, want %+v", stats, c.stats)
+			}
+
+			if frames := readAll(f); !reflect.DeepEqual(frames, c.read) {
+				t.Fatalf("read %v, want %v", frames, c.read)
+			}
+
+			want := c.stats
+			want.Queued, want.Read = 0, uint64(len(c.read))
+
+			if stats := f.Stats(); stats != want {
+				t.Fatalf("stats after reading are %+v, want %+v", stats, want)
+			}
+		})
+	}
+}
+
+func TestFrameBufferBlockWaitsForReader(t *testing.T) {
+	f, err := encoding.NewFrameBuffer(1, encoding.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Write([]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	written := make(chan error)
+
+	go func() {
+		written <- f.Write([]byte{2})
+	}()
+
+	select {
+	case err := <-written:
+		t.Fatalf("writing to a full buffer returned %v before reading", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	for _, want := range [][]byte{{1}, {2}} {
+		frame, err := f.Read(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(frame, want) {
+			t.Fatalf("read %v, want %v", frame, want)
+		}
+	}
+
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFrameBufferReadUnblocks(t *testing.T) {
+	cases := []struct {
+		name string
+		// unblock is called while Read is waiting on an empty buffer.
+		unblock func(f *encoding.FrameBuffer, cancel context.CancelFunc)
+		err     error
+	}{
+		{
+			name:    "cancel",
+			unblock: func(_ *encoding.FrameBuffer, cancel context.CancelFunc) { cancel() },
+			err:     context.Canceled,
+		},
+		{
+			name:    "close",
+			unblock: func(f *encoding.FrameBuffer, _ context.CancelFunc) { f.Close() },
+			err:     io.EOF,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := encoding.NewFrameBuffer(4, encoding.DropOldest)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			read := make(chan error)
+
+			go func() {
+				_, err := f.Read(ctx)
+				read <- err
+			}()
+
+			time.Sleep(10 * time.Millisecond)
+			c.unblock(f, cancel)
+
+			select {
+			case err := <-read:
+				if !errors.Is(err, c.err) {
+					t.Fatalf("read returned %v, want %v", err, c.err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("read is still blocked")
+			}
+		})
+	}
+}
+
+func TestFrameBufferClose(t *testing.T) {
+	f, err := encoding.NewFrameBuffer(4, encoding.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Write([]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	if err := f.Write([]byte{2}); !errors.Is(err, encoding.ErrBufferClosed) {
+		t.Fatalf("writing to a closed buffer returned %v, want %v", err, encoding.ErrBufferClosed)
+	}
+
+	// remaining frames are read before io.EOF.
+	if frame, err := f.Read(context.Background()); err != nil || !reflect.DeepEqual(frame, []byte{1}) {
+		t.Fatalf("read %v, %v from a closed buffer, want its remaining frame", frame, err)
+	}
+
+	if _, err := f.Read(context.Background()); !errors.Is(err, io.EOF) {
+		t.Fatalf("reading a drained closed buffer returned %v, want io.EOF", err)
+	}
+}
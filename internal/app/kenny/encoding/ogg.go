@@ -0,0 +1,287 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Ogg Opus container constants. refer to RFC 3533 and RFC 7845.
+const (
+	// OpusGranuleRate is the rate of Ogg Opus granule positions. it's always 48 kHz regardless of the input rate.
+	OpusGranuleRate = 48000
+
+	oggPageHeaderSize = 27
+	oggMaxSegments    = 255
+	oggMaxSegmentSize = 255
+
+	oggHeaderContinued = 0x01
+	oggHeaderBOS       = 0x02
+	oggHeaderEOS       = 0x04
+
+	opusHeadMagic = "OpusHead"
+	opusTagsMagic = "OpusTags"
+	opusVersion   = 1
+	opusHeadSize  = 19
+
+	// granule position of pages which don't finish any packet.
+	oggNoGranule = ^uint64(0)
+)
+
+var (
+	// ErrTooManyChannels occurs when creating an Ogg Opus stream with more than 2 channels.
+	// only channel mapping family 0 is supported.
+	ErrTooManyChannels = errors.New("ogg opus only supports mono and stereo streams")
+	// ErrPacketTooLarge occurs when a packet doesn't fit in a single ogg page.
+	ErrPacketTooLarge = errors.New("packet is too large for an ogg page")
+	// ErrInvalidOgg occurs when reading a malformed ogg stream.
+	ErrInvalidOgg = errors.New("invalid ogg stream")
+	// ErrInvalidChecksum occurs when an ogg page's CRC doesn't match its content.
+	ErrInvalidChecksum = errors.New("ogg page checksum mismatch")
+	// ErrNotOpus occurs when the ogg stream doesn't start with an OpusHead header.
+	ErrNotOpus = errors.New("ogg stream is not an opus stream")
+)
+
+// OggStream describes an Ogg Opus logical stream.
+type OggStream struct {
+	Serial uint32
+	Head   OpusHead
+	Tags   OpusTags
+}
+
+// OpusHead is the Ogg Opus identification header. (RFC 7845 section 5.1)
+// StreamCount, CoupledCount and ChannelMapping are only present when MappingFamily is not 0.
+type OpusHead struct {
+	Version         uint8
+	Channels        uint8
+	PreSkip         uint16
+	InputSampleRate uint32
+	OutputGain      int16
+	MappingFamily   uint8
+	StreamCount     uint8
+	CoupledCount    uint8
+	ChannelMapping  []byte
+}
+
+func (h OpusHead) marshal() []byte {
+	b := make([]byte, opusHeadSize, opusHeadSize+2+len(h.ChannelMapping))
+	copy(b, opusHeadMagic)
+	b[8] = h.Version
+	b[9] = h.Channels
+	binary.LittleEndian.PutUint16(b[10:], h.PreSkip)
+	binary.LittleEndian.PutUint32(b[12:], h.InputSampleRate)
+	binary.LittleEndian.PutUint16(b[16:], uint16(h.OutputGain))
+	b[18] = h.MappingFamily
+
+	if h.MappingFamily != 0 {
+		b = append(b, h.StreamCount, h.CoupledCount)
+		b = append(b, h.ChannelMapping...)
+	}
+
+	return b
+}
+
+func (h *OpusHead) unmarshal(b []byte) error {
+	if len(b) < opusHeadSize || string(b[:8]) != opusHeadMagic {
+		return ErrNotOpus
+	}
+
+	// only major version 0 is defined, minor versions are backward compatible.
+	if b[8]>>4 != 0 || b[9] == 0 {
+		return fmt.Errorf("%w: unsupported OpusHead", ErrInvalidOgg)
+	}
+
+	h.Version = b[8]
+	h.Channels = b[9]
+	h.PreSkip = binary.LittleEndian.Uint16(b[10:])
+	h.InputSampleRate = binary.LittleEndian.Uint32(b[12:])
+	h.OutputGain = int16(binary.LittleEndian.Uint16(b[16:]))
+	h.MappingFamily = b[18]
+
+	if h.MappingFamily != 0 {
+		if len(b) < opusHeadSize+2+int(h.Channels) {
+			return fmt.Errorf("%w: truncated channel mapping table", ErrInvalidOgg)
+		}
+
+		h.StreamCount = b[19]
+		h.CoupledCount = b[20]
+		h.ChannelMapping = append([]byte(nil), b[21:21+int(h.Channels)]...)
+	}
+
+	return nil
+}
+
+// OpusTags is the Ogg Opus comment header. (RFC 7845 section 5.2)
+// each comment is a "KEY=value" string. Extra holds any data after the comments which is preserved as is.
+type OpusTags struct {
+	Vendor   string
+	Comments []string
+	Extra    []byte
+}
+
+func (t OpusTags) marshal() []byte {
+	size := len(opusTagsMagic) + 4 + len(t.Vendor) + 4
+
+	for _, c := range t.Comments {
+		size += 4 + len(c)
+	}
+
+	b := make([]byte, 0, size)
+	b = append(b, opusTagsMagic...)
+	b = appendString(b, t.Vendor)
+	b = appendUint32(b, uint32(len(t.Comments)))
+
+	for _, c := range t.Comments {
+		b = appendString(b, c)
+	}
+
+	return append(b, t.Extra...)
+}
+
+func (t *OpusTags) unmarshal(b []byte) error {
+	if len(b) < len(opusTagsMagic) || string(b[:len(opusTagsMagic)]) != opusTagsMagic {
+		return fmt.Errorf("%w: missing OpusTags header", ErrInvalidOgg)
+	}
+
+	b = b[len(opusTagsMagic):]
+
+	vendor, b, err := readString(b)
+	if err != nil {
+		return err
+	}
+
+	if len(b) < 4 {
+		return fmt.Errorf("%w: truncated OpusTags", ErrInvalidOgg)
+	}
+
+	count := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+
+	// each comment needs at least 4 bytes for its length.
+	if uint64(count)*4 > uint64(len(b)) {
+		return fmt.Errorf("%w: invalid comment count", ErrInvalidOgg)
+	}
+
+	t.Vendor = vendor
+	t.Comments = make([]string, 0, count)
+
+	for i := uint32(0); i < count; i++ {
+		var c string
+
+		c, b, err = readString(b)
+		if err != nil {
+			return err
+		}
+
+		t.Comments = append(t.Comments, c)
+	}
+
+	if len(b) > 0 {
+		t.Extra = append([]byte(nil), b...)
+	}
+
+	return nil
+}
+
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 4 {
+		return "", nil, fmt.Errorf("%w: truncated OpusTags", ErrInvalidOgg)
+	}
+
+	n := binary.LittleEndian.Uint32(b)
+	if uint64(n) > uint64(len(b)-4) {
+		return "", nil, fmt.Errorf("%w: truncated OpusTags", ErrInvalidOgg)
+	}
+
+	return string(b[4 : 4+n]), b[4+n:], nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+
+	binary.LittleEndian.PutUint32(tmp[:], v)
+
+	return append(b, tmp[:]...)
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendUint32(b, uint32(len(s))), s...)
+}
+
+// oggPage represents a single ogg page. segments is the lacing table of payload.
+type oggPage struct {
+	headerType byte
+	granule    uint64
+	serial     uint32
+	sequence   uint32
+	segments   []byte
+	payload    []byte
+}
+
+// checksum computes CRC of the page as it would be marshaled.
+func (p *oggPage) checksum() uint32 {
+	return binary.LittleEndian.Uint32(p.marshal()[22:])
+}
+
+func (p *oggPage) marshal() []byte {
+	b := make([]byte, oggPageHeaderSize+len(p.segments)+len(p.payload))
+	copy(b, "OggS")
+	b[4] = 0 // stream structure version
+	b[5] = p.headerType
+	binary.LittleEndian.PutUint64(b[6:], p.granule)
+	binary.LittleEndian.PutUint32(b[14:], p.serial)
+	binary.LittleEndian.PutUint32(b[18:], p.sequence)
+	b[26] = byte(len(p.segments))
+	copy(b[oggPageHeaderSize:], p.segments)
+	copy(b[oggPageHeaderSize+len(p.segments):], p.payload)
+
+	binary.LittleEndian.PutUint32(b[22:], oggChecksum(b))
+
+	return b
+}
+
+// lacing returns the ogg lacing values for a packet of the given size.
+func lacing(size int) []byte {
+	segments := make([]byte, size/oggMaxSegmentSize+1)
+	for i := 0; i < len(segments)-1; i++ {
+		segments[i] = oggMaxSegmentSize
+	}
+
+	segments[len(segments)-1] = byte(size % oggMaxSegmentSize)
+
+	return segments
+}
+
+//nolint:gochecknoglobals
+var oggCRCTable = func() [256]uint32 {
+	const poly = 0x04c11db7
+
+	var table [256]uint32
+
+	for i := range table {
+		r := uint32(i) << 24
+
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = (r << 1) ^ poly
+			} else {
+				r <<= 1
+			}
+		}
+
+		table[i] = r
+	}
+
+	return table
+}()
+
+// oggChecksum computes the page CRC. the checksum field of page must be zero.
+func oggChecksum(page []byte) uint32 {
+	var crc uint32
+
+	for _, b := range page {
+		crc = (crc << 8) ^ oggCRCTable[byte(crc>>24)^b]
+	}
+
+	return crc
+}
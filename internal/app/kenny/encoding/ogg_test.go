@@ -0,0 +1,143 @@
+package encoding_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+)
+
+// encodeTone encodes the given number of frames of a tone and returns the packets.
+func encodeTone(t *testing.T, e *encoding.Encoder, frames int) [][]byte {
+	t.Helper()
+
+	pcm := make([]int16, e.FrameSize*e.Channels)
+	packets := make([][]byte, 0, frames)
+
+	for i := 0; i < frames; i++ {
+		for j := range pcm {
+			frame := i*e.FrameSize + j/e.Channels
+			pcm[j] = int16(8000 * math.Sin(2*math.Pi*440*float64(frame)/float64(e.SampleRate)))
+		}
+
+		if err := e.Write(pcm); err != nil {
+			t.Fatal(err)
+		}
+
+		packet, ok := e.Buffer.TryRead()
+		if !ok {
+			t.Fatalf("frame %d wasn't encoded", i)
+		}
+
+		packets = append(packets, packet)
+	}
+
+	return packets
+}
+
+func TestOggWriterPreSkip(t *testing.T) {
+	for _, rate := range []int{8000, 12000, 16000, 24000, 48000} {
+		e, err := encoding.NewEncoder(rate, 2, rate/50)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// libopus reports 6.5 ms of lookahead for voip encoders at every rate.
+		if want := rate * 13 / 2000; e.Lookahead() != want {
+			t.Fatalf("%d Hz encoder has %d samples of lookahead, want %d", rate, e.Lookahead(), want)
+		}
+
+		var b bytes.Buffer
+
+		if _, err := encoding.NewOggWriter(&b, e); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := encoding.NewOggReader(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		head := r.Stream().Head
+		if head.PreSkip != 312 || head.InputSampleRate != uint32(rate) || head.Channels != 2 {
+			t.Fatalf("%d Hz encoder wrote OpusHead %+v, want 312 samples of pre-skip", rate, head)
+		}
+	}
+}
+
+func TestOggRoundTrip(t *testing.T) {
+	e, err := encoding.NewEncoder(48000, 1, 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets := encodeTone(t, e, 300)
+
+	var written bytes.Buffer
+
+	w, err := encoding.NewOggWriter(&written, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, packet := range packets {
+		if err := w.WritePacket(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := encoding.NewOggReader(bytes.NewReader(written.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// remuxing a file kenny wrote reproduces it byte for byte.
+	var remuxed bytes.Buffer
+
+	remux, err := encoding.NewOggStreamWriter(&remuxed, r.Stream())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; ; i++ {
+		packet, err := r.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			if i != len(packets) {
+				t.Fatalf("read %d packets, want %d", i, len(packets))
+			}
+
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i >= len(packets) || !bytes.Equal(packet, packets[i]) {
+			t.Fatalf("packet %d doesn't match the written packet", i)
+		}
+
+		if err := remux.WritePacket(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if r.Granule() != 300*960 {
+		t.Fatalf("stream ends at granule %d, want %d", r.Granule(), 300*960)
+	}
+
+	if err := remux.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(remuxed.Bytes(), written.Bytes()) {
+		t.Fatal("remuxed stream isn't the same as the written stream")
+	}
+}
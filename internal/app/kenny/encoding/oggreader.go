@@ -0,0 +1,197 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// OggReader demuxes opus packets from an Ogg Opus stream. (RFC 7845)
+// only the first logical stream is read, pages of other streams are skipped.
+type OggReader struct {
+	r      *bufio.Reader
+	stream OggStream
+
+	// granule position of the last page which finished a packet.
+	granule uint64
+	eos     bool
+
+	packets [][]byte
+	partial []byte
+}
+
+// NewOggReader creates an OggReader and reads the Ogg Opus headers from r.
+func NewOggReader(r io.Reader) (*OggReader, error) {
+	o := &OggReader{
+		r: bufio.NewReader(r),
+	}
+
+	page, err := o.readPage()
+	if err != nil {
+		return nil, err
+	}
+
+	if page.headerType&oggHeaderBOS == 0 {
+		return nil, fmt.Errorf("%w: first page is not a beginning of stream", ErrInvalidOgg)
+	}
+
+	o.stream.Serial = page.serial
+	o.addPage(page)
+
+	head, err := o.nextPacket()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing OpusHead", ErrNotOpus)
+	}
+
+	if err := o.stream.Head.unmarshal(head); err != nil {
+		return nil, err
+	}
+
+	tags, err := o.nextPacket()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing OpusTags", ErrInvalidOgg)
+	}
+
+	if err := o.stream.Tags.unmarshal(tags); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// Stream returns the stream's serial number and headers.
+func (o *OggReader) Stream() OggStream {
+	return o.stream
+}
+
+// Granule returns granule position of the last read page which finished a packet.
+// subtract OpusHead.PreSkip to get the playback position in 48 kHz samples.
+func (o *OggReader) Granule() uint64 {
+	return o.granule
+}
+
+// ReadPacket returns next opus packet of the stream. it returns io.EOF at the end of stream.
+// the returned packet can be passed to Decoder.DecodeFrame.
+func (o *OggReader) ReadPacket() ([]byte, error) {
+	return o.nextPacket()
+}
+
+func (o *OggReader) nextPacket() ([]byte, error) {
+	for len(o.packets) == 0 {
+		if o.eos {
+			return nil, io.EOF
+		}
+
+		page, err := o.readPage()
+		if errors.Is(err, io.EOF) {
+			// the stream is truncated or it doesn't set the EOS flag.
+			o.eos = true
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if page.serial != o.stream.Serial {
+			continue
+		}
+
+		o.addPage(page)
+	}
+
+	packet := o.packets[0]
+	o.packets = o.packets[1:]
+
+	return packet, nil
+}
+
+// addPage splits page into packets using its lacing table.
+func (o *OggReader) addPage(page *oggPage) {
+	if page.headerType&oggHeaderContinued == 0 {
+		// a packet which was never finished is dropped.
+		o.partial = nil
+	}
+
+	skip := page.headerType&oggHeaderContinued != 0 && o.partial == nil
+	offset := 0
+
+	for _, segment := range page.segments {
+		data := page.payload[offset : offset+int(segment)]
+		offset += int(segment)
+
+		if skip {
+			// continued data of a packet whose beginning we don't have.
+			if segment < oggMaxSegmentSize {
+				skip = false
+			}
+
+			continue
+		}
+
+		o.partial = append(o.partial, data...)
+
+		if segment < oggMaxSegmentSize {
+			o.packets = append(o.packets, o.partial)
+			o.partial = nil
+		}
+	}
+
+	if page.granule != oggNoGranule {
+		o.granule = page.granule
+	}
+
+	if page.headerType&oggHeaderEOS != 0 {
+		o.eos = true
+	}
+}
+
+func (o *OggReader) readPage() (*oggPage, error) {
+	header := make([]byte, oggPageHeaderSize)
+
+	if _, err := io.ReadFull(o.r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: truncated page header", ErrInvalidOgg)
+		}
+
+		return nil, err
+	}
+
+	if !bytes.Equal(header[:4], []byte("OggS")) || header[4] != 0 {
+		return nil, fmt.Errorf("%w: missing capture pattern", ErrInvalidOgg)
+	}
+
+	page := &oggPage{
+		headerType: header[5],
+		granule:    binary.LittleEndian.Uint64(header[6:]),
+		serial:     binary.LittleEndian.Uint32(header[14:]),
+		sequence:   binary.LittleEndian.Uint32(header[18:]),
+		segments:   make([]byte, header[26]),
+	}
+
+	if _, err := io.ReadFull(o.r, page.segments); err != nil {
+		return nil, fmt.Errorf("%w: truncated page", ErrInvalidOgg)
+	}
+
+	size := 0
+	for _, s := range page.segments {
+		size += int(s)
+	}
+
+	page.payload = make([]byte, size)
+
+	if _, err := io.ReadFull(o.r, page.payload); err != nil {
+		return nil, fmt.Errorf("%w: truncated page", ErrInvalidOgg)
+	}
+
+	checksum := binary.LittleEndian.Uint32(header[22:])
+	if page.checksum() != checksum {
+		return nil, ErrInvalidChecksum
+	}
+
+	return page, nil
+}
@@ -0,0 +1,143 @@
+package encoding
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+const (
+	// pages are flushed once their payload reaches this size.
+	oggPagePayloadSize = 4096
+	vendor             = "kenny"
+)
+
+// OggWriter muxes opus packets into an Ogg Opus stream. (RFC 7845)
+// call Close after writing the last packet to mark the end of stream.
+type OggWriter struct {
+	w        io.Writer
+	serial   uint32
+	sequence uint32
+	granule  uint64
+
+	// packets of the page being filled.
+	segments []byte
+	payload  []byte
+}
+
+// NewOggWriter creates an OggWriter with a random serial number and writes the Ogg Opus headers to w.
+// the headers describe packets of e, its sample rate, channel count and lookahead as pre-skip.
+func NewOggWriter(w io.Writer, e *Encoder) (*OggWriter, error) {
+	if e.Channels > 2 {
+		return nil, ErrTooManyChannels
+	}
+
+	return NewOggStreamWriter(w, OggStream{
+		Serial: rand.New(rand.NewSource(time.Now().UnixNano())).Uint32(), //nolint:gosec
+		Head: OpusHead{
+			Version:         opusVersion,
+			Channels:        uint8(e.Channels),
+			PreSkip:         e.PreSkip(),
+			InputSampleRate: uint32(e.SampleRate),
+		},
+		Tags: OpusTags{Vendor: vendor},
+	})
+}
+
+// NewOggStreamWriter creates an OggWriter for the given stream and writes its headers to w.
+// use it with OggReader.Stream to remux an existing file. a file kenny wrote is reproduced byte for byte,
+// other muxers paginate differently and may trim samples at the end, so only their headers and packets are kept.
+func NewOggStreamWriter(w io.Writer, stream OggStream) (*OggWriter, error) {
+	o := &OggWriter{
+		w:      w,
+		serial: stream.Serial,
+	}
+
+	head := stream.Head.marshal()
+	if err := o.writePage(oggHeaderBOS, 0, lacing(len(head)), head); err != nil {
+		return nil, err
+	}
+
+	tags := stream.Tags.marshal()
+	if len(lacing(len(tags))) > oggMaxSegments {
+		return nil, ErrPacketTooLarge
+	}
+
+	if err := o.writePage(0, 0, lacing(len(tags)), tags); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// WritePacket adds an opus packet to the stream. packet's duration is read from its TOC byte
+// to keep track of granule position.
+func (o *OggWriter) WritePacket(packet []byte) error {
+	samples, err := PacketSamples(packet)
+	if err != nil {
+		return err
+	}
+
+	segments := lacing(len(packet))
+	if len(segments) > oggMaxSegments {
+		return ErrPacketTooLarge
+	}
+
+	if len(o.segments)+len(segments) > oggMaxSegments {
+		if err := o.Flush(); err != nil {
+			return err
+		}
+	}
+
+	o.segments = append(o.segments, segments...)
+	o.payload = append(o.payload, packet...)
+	o.granule += uint64(samples)
+
+	if len(o.payload) >= oggPagePayloadSize {
+		return o.Flush()
+	}
+
+	return nil
+}
+
+// Flush writes buffered packets as a page.
+func (o *OggWriter) Flush() error {
+	if len(o.segments) == 0 {
+		return nil
+	}
+
+	return o.flush(0)
+}
+
+// Close writes remaining packets and marks the end of stream. it doesn't close the underlying writer.
+func (o *OggWriter) Close() error {
+	return o.flush(oggHeaderEOS)
+}
+
+func (o *OggWriter) flush(headerType byte) error {
+	err := o.writePage(headerType, o.granule, o.segments, o.payload)
+	o.segments = o.segments[:0]
+	o.payload = o.payload[:0]
+
+	return err
+}
+
+func (o *OggWriter) writePage(headerType byte, granule uint64, segments, payload []byte) error {
+	p := oggPage{
+		headerType: headerType,
+		granule:    granule,
+		serial:     o.serial,
+		sequence:   o.sequence,
+		segments:   segments,
+		payload:    payload,
+	}
+
+	if _, err := o.w.Write(p.marshal()); err != nil {
+		return fmt.Errorf("failed to write ogg page: %w", err)
+	}
+
+	o.sequence++
+
+	return nil
+}
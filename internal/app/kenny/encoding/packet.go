@@ -0,0 +1,57 @@
+package encoding
+
+import "errors"
+
+// ErrInvalidPacket occurs when an opus packet is empty or malformed.
+var ErrInvalidPacket = errors.New("invalid opus packet")
+
+// PacketSamples returns the duration of an opus packet in samples per channel at 48 kHz,
+// using packet's TOC byte. (RFC 6716 section 3.1)
+//
+//nolint:gomnd
+func PacketSamples(packet []byte) (int, error) {
+	if len(packet) == 0 {
+		return 0, ErrInvalidPacket
+	}
+
+	toc := packet[0]
+	config := toc >> 3
+
+	var frameSize int
+
+	switch {
+	case config < 12:
+		// SILK only: 10, 20, 40, 60 ms.
+		frameSize = []int{480, 960, 1920, 2880}[config%4]
+	case config < 16:
+		// Hybrid: 10, 20 ms.
+		frameSize = []int{480, 960}[config%2]
+	default:
+		// CELT only: 2.5, 5, 10, 20 ms.
+		frameSize = []int{120, 240, 480, 960}[config%4]
+	}
+
+	var frames int
+
+	switch toc & 0x3 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, ErrInvalidPacket
+		}
+
+		frames = int(packet[1] & 0x3F)
+	}
+
+	samples := frames * frameSize
+
+	// a packet can't be longer than 120 ms.
+	if samples == 0 || samples > 5760 {
+		return 0, ErrInvalidPacket
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,313 @@
+// Package jitter implements an adaptive jitter buffer for received RTP packets.
+//
+// packets are reordered by sequence number and each packet is released at its playout time, which is
+// its RTP timestamp mapped to local time plus a target delay. the target delay follows measured jitter,
+// so the buffer holds packets longer on unstable networks and plays sooner on stable ones.
+//
+// the buffer doesn't read the clock itself, every call takes the current time. so it can be driven by
+// synthetic arrival schedules as well as real ones.
+package jitter
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// jitterMultiplier is how many times of measured jitter the target delay is.
+	jitterMultiplier = 4
+	// jitterGain is the jitter estimator's gain. (RFC 3550 A.8)
+	jitterGain = 16
+
+	// seqMaxJump is the sequence number distance which is considered a stream restart.
+	seqMaxJump = 3000
+)
+
+// Stats contains jitter buffer counters.
+type Stats struct {
+	// Queued is number of packets waiting for their playout time.
+	Queued int
+	// Played is number of released packets.
+	Played uint64
+	// Lost is number of packets which didn't arrive before their playout time.
+	Lost uint64
+	// Late is number of packets which arrived after their playout time, they are discarded.
+	Late uint64
+	// Discarded is number of duplicate or overflowing packets, including late ones.
+	Discarded uint64
+	// Jitter is the measured interarrival jitter.
+	Jitter time.Duration
+	// TargetDelay is the current playout delay.
+	TargetDelay time.Duration
+}
+
+// Buffer is an adaptive jitter buffer. it's safe for concurrent use.
+type Buffer struct {
+	clockRate int
+	minDelay  time.Duration
+	maxDelay  time.Duration
+
+	mu sync.Mutex
+	// packets are sorted by extended sequence number.
+	packets []entry
+	stats   Stats
+
+	started bool
+	// next is the extended sequence number of the next packet to release.
+	next int64
+	// lastTimestamp is the extended timestamp of the last released or lost packet.
+	lastTimestamp int64
+	// highest is the highest extended sequence number seen, it's used to extend new sequence numbers.
+	highest int64
+	// highestTimestamp is the highest extended timestamp seen, it's used to extend new timestamps.
+	highestTimestamp int64
+	// baseTimestamp and baseTime map extended RTP timestamps to local time.
+	// baseTime moves earlier whenever a packet arrives faster than all the previous ones.
+	baseTimestamp int64
+	baseTime      time.Time
+	// lastTransit and jitter are in clock rate units.
+	lastTransit int64
+	jitter      float64
+}
+
+type entry struct {
+	seq       int64
+	timestamp int64
+	packet    *rtp.Packet
+}
+
+// New creates a jitter buffer for a stream with the given RTP clock rate.
+// target delay is kept between minDelay and maxDelay.
+func New(clockRate int, minDelay, maxDelay time.Duration) *Buffer {
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+
+	return &Buffer{
+		clockRate: clockRate,
+		minDelay:  minDelay,
+		maxDelay:  maxDelay,
+		stats: Stats{
+			TargetDelay: minDelay,
+		},
+	}
+}
+
+// Push adds a packet which has arrived at the given time.
+// packets which are older than already released ones are counted as late and discarded.
+func (b *Buffer) Push(packet *rtp.Packet, arrival time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.started || b.restarted(packet.SequenceNumber) {
+		b.reset(packet, arrival)
+	}
+
+	seq := b.extend(packet.SequenceNumber)
+	if seq > b.highest {
+		b.highest = seq
+	}
+
+	timestamp := b.extendTimestamp(packet.Timestamp)
+	if timestamp > b.highestTimestamp {
+		b.highestTimestamp = timestamp
+	}
+
+	if seq < b.next {
+		b.stats.Late++
+		b.stats.Discarded++
+
+		return
+	}
+
+	b.measure(timestamp, arrival)
+
+	i := sort.Search(len(b.packets), func(i int) bool {
+		return b.packets[i].seq >= seq
+	})
+
+	if i < len(b.packets) && b.packets[i].seq == seq {
+		b.stats.Discarded++
+
+		return
+	}
+
+	b.packets = append(b.packets, entry{})
+	copy(b.packets[i+1:], b.packets[i:])
+	b.packets[i] = entry{seq: seq, timestamp: timestamp, packet: packet}
+
+	b.trim()
+
+	b.stats.Queued = len(b.packets)
+}
+
+// Pop returns the next packet whose playout time has come.
+// if the next packet in sequence is missing while a later one is due, lost is true and packet is nil,
+// so the caller can conceal it. ok is false when nothing is due yet.
+func (b *Buffer) Pop(now time.Time) (packet *rtp.Packet, lost, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.packets) == 0 {
+		return nil, false, false
+	}
+
+	head := b.packets[0]
+
+	if head.seq > b.next {
+		// missing packets are spread evenly between the last released packet and head.
+		step := (head.timestamp - b.lastTimestamp) / (head.seq - b.next + 1)
+		expected := b.lastTimestamp + step
+
+		if now.Before(b.playoutTime(expected)) {
+			return nil, false, false
+		}
+
+		b.next++
+		b.lastTimestamp = expected
+		b.stats.Lost++
+
+		return nil, true, true
+	}
+
+	if now.Before(b.playoutTime(head.timestamp)) {
+		return nil, false, false
+	}
+
+	b.packets = b.packets[1:]
+	b.next = head.seq + 1
+	b.lastTimestamp = head.timestamp
+	b.stats.Played++
+	b.stats.Queued = len(b.packets)
+
+	return head.packet, false, true
+}
+
+// Next returns the next packet in sequence without removing it, or nil if it hasn't arrived.
+// after a lost packet it's the packet which carries the lost one's FEC data.
+func (b *Buffer) Next() *rtp.Packet {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.packets) == 0 || b.packets[0].seq != b.next {
+		return nil
+	}
+
+	return b.packets[0].packet
+}
+
+// Stats returns jitter buffer counters.
+func (b *Buffer) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stats
+}
+
+// restarted reports whether seq is too far from the previous packets to belong to the same stream.
+func (b *Buffer) restarted(seq uint16) bool {
+	delta := int16(seq - uint16(b.highest))
+
+	return delta >= seqMaxJump || delta <= -seqMaxJump
+}
+
+// reset starts over from packet, i.e. for the first packet or when the remote stream has restarted.
+func (b *Buffer) reset(packet *rtp.Packet, arrival time.Time) {
+	b.started = true
+	b.next = int64(packet.SequenceNumber)
+	b.highest = b.next
+	b.highestTimestamp = int64(packet.Timestamp)
+	b.baseTimestamp = b.highestTimestamp
+	b.lastTimestamp = b.highestTimestamp
+	b.baseTime = arrival
+	b.lastTransit = 0
+	b.packets = b.packets[:0]
+}
+
+// extend converts a 16 bit sequence number to a 64 bit one which doesn't wrap around.
+func (b *Buffer) extend(seq uint16) int64 {
+	return b.highest + int64(int16(seq-uint16(b.highest)))
+}
+
+// extendTimestamp converts a 32 bit RTP timestamp to a 64 bit one which doesn't wrap around,
+// the same way as sequence numbers. timestamps wrap every 24 hours at 48 kHz.
+func (b *Buffer) extendTimestamp(timestamp uint32) int64 {
+	return b.highestTimestamp + int64(int32(timestamp-uint32(b.highestTimestamp)))
+}
+
+// measure updates jitter and target delay with a new packet's arrival. (RFC 3550 A.8)
+func (b *Buffer) measure(timestamp int64, arrival time.Time) {
+	transit := b.ticks(arrival.Sub(b.baseTime)) - b.relative(timestamp)
+
+	// a packet which arrived faster than expected moves the base, so it isn't counted as late.
+	if transit < 0 {
+		b.baseTime = b.baseTime.Add(b.duration(transit))
+		b.lastTransit -= transit
+		transit = 0
+	}
+
+	d := transit - b.lastTransit
+	if d < 0 {
+		d = -d
+	}
+
+	b.lastTransit = transit
+	b.jitter += (float64(d) - b.jitter) / jitterGain
+
+	b.stats.Jitter = time.Duration(b.jitter * float64(time.Second) / float64(b.clockRate))
+
+	target := b.stats.Jitter * jitterMultiplier
+	if target < b.minDelay {
+		target = b.minDelay
+	}
+
+	if target > b.maxDelay {
+		target = b.maxDelay
+	}
+
+	b.stats.TargetDelay = target
+}
+
+// trim drops the oldest packets when the buffer holds more than the maximum delay.
+func (b *Buffer) trim() {
+	for len(b.packets) > 1 {
+		first := b.packets[0].timestamp
+		last := b.packets[len(b.packets)-1].timestamp
+
+		if b.duration(last-first) <= b.maxDelay {
+			return
+		}
+
+		b.next = b.packets[0].seq + 1
+		b.lastTimestamp = b.packets[0].timestamp
+		b.packets = b.packets[1:]
+		b.stats.Discarded++
+	}
+}
+
+// relative returns an extended timestamp relative to the base timestamp.
+func (b *Buffer) relative(timestamp int64) int64 {
+	return timestamp - b.baseTimestamp
+}
+
+func (b *Buffer) playoutTime(timestamp int64) time.Time {
+	return b.baseTime.Add(b.duration(b.relative(timestamp)) + b.stats.TargetDelay)
+}
+
+// ticks converts d to clock rate units. whole seconds are converted separately,
+// so nanoseconds times clock rate doesn't overflow in long calls.
+func (b *Buffer) ticks(d time.Duration) int64 {
+	rate := int64(b.clockRate)
+
+	return int64(d/time.Second)*rate + int64(d%time.Second)*rate/int64(time.Second)
+}
+
+// duration converts clock rate units to a duration, without overflowing the same way as ticks.
+func (b *Buffer) duration(ticks int64) time.Duration {
+	rate := int64(b.clockRate)
+
+	return time.Duration(ticks/rate)*time.Second + time.Duration(ticks%rate)*time.Second/time.Duration(rate)
+}
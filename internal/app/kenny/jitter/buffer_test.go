@@ -0,0 +1,200 @@
+package jitter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/smf8/kenny/internal/app/kenny/jitter"
+)
+
+const (
+	clockRate = 48000
+	// frame is a 20 ms packet's duration in clock rate units.
+	frame = 960
+	// period is a 20 ms packet's duration.
+	period = 20 * time.Millisecond
+)
+
+//nolint:gochecknoglobals
+var start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func packet(seq uint16, timestamp uint32) *rtp.Packet {
+	return &rtp.Packet{Header: rtp.Header{SequenceNumber: seq, Timestamp: timestamp}}
+}
+
+// popAll returns sequence numbers of every packet which is due at now, lost packets are returned as -1.
+func popAll(b *jitter.Buffer, now time.Time) []int {
+	var popped []int
+
+	for {
+		p, lost, ok := b.Pop(now)
+		if !ok {
+			return popped
+		}
+
+		if lost {
+			popped = append(popped, -1)
+
+			continue
+		}
+
+		popped = append(popped, int(p.SequenceNumber))
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestReordering(t *testing.T) {
+	b := jitter.New(clockRate, 60*time.Millisecond, time.Second)
+
+	// packets 1 and 3 arrive after the ones following them, all of them before their playout time.
+	for i, seq := range []uint16{0, 2, 1, 4, 3, 5} {
+		b.Push(packet(seq, uint32(seq)*frame), start.Add(time.Duration(i)*period))
+	}
+
+	if popped := popAll(b, start.Add(5*period+60*time.Millisecond)); !equal(popped, []int{0, 1, 2, 3, 4, 5}) {
+		t.Fatalf("popped %v, want packets in order", popped)
+	}
+
+	if stats := b.Stats(); stats.Played != 6 || stats.Lost != 0 || stats.Late != 0 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
+
+func TestLoss(t *testing.T) {
+	b := jitter.New(clockRate, 40*time.Millisecond, time.Second)
+
+	for _, seq := range []uint16{0, 1, 3} {
+		b.Push(packet(seq, uint32(seq)*frame), start.Add(time.Duration(seq)*period))
+	}
+
+	// packet 2 is only reported lost at its own playout time, not before.
+	if popped := popAll(b, start.Add(period+40*time.Millisecond)); !equal(popped, []int{0, 1}) {
+		t.Fatalf("popped %v before packet 2's playout time", popped)
+	}
+
+	p, lost, ok := b.Pop(start.Add(2*period + 40*time.Millisecond))
+	if !ok || !lost || p != nil {
+		t.Fatalf("packet 2 isn't lost at its playout time: %v %v %v", p, lost, ok)
+	}
+
+	// the packet after a lost one carries its FEC data.
+	if next := b.Next(); next == nil || next.SequenceNumber != 3 {
+		t.Fatalf("next packet is %v, want 3", next)
+	}
+
+	if popped := popAll(b, start.Add(3*period+40*time.Millisecond)); !equal(popped, []int{3}) {
+		t.Fatalf("popped %v, want 3", popped)
+	}
+
+	if stats := b.Stats(); stats.Lost != 1 || stats.Played != 3 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
+
+func TestLatePackets(t *testing.T) {
+	b := jitter.New(clockRate, 40*time.Millisecond, time.Second)
+
+	b.Push(packet(0, 0), start)
+	b.Push(packet(2, 2*frame), start.Add(2*period))
+
+	if popped := popAll(b, start.Add(2*period+40*time.Millisecond)); !equal(popped, []int{0, -1, 2}) {
+		t.Fatalf("popped %v", popped)
+	}
+
+	// packet 1 was already concealed, so it's discarded.
+	b.Push(packet(1, frame), start.Add(3*period))
+
+	if popped := popAll(b, start.Add(time.Second)); len(popped) != 0 {
+		t.Fatalf("popped %v after a late packet", popped)
+	}
+
+	if stats := b.Stats(); stats.Late != 1 || stats.Discarded != 1 || stats.Queued != 0 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
+
+func TestDelayAdaptation(t *testing.T) {
+	b := jitter.New(clockRate, 20*time.Millisecond, 500*time.Millisecond)
+
+	seq := uint16(0)
+	push := func(delay time.Duration) {
+		b.Push(packet(seq, uint32(seq)*frame), start.Add(time.Duration(seq)*period+delay))
+		popAll(b, start.Add(time.Duration(seq)*period+delay))
+		seq++
+	}
+
+	for i := 0; i < 50; i++ {
+		push(0)
+	}
+
+	if stats := b.Stats(); stats.Jitter != 0 || stats.TargetDelay != 20*time.Millisecond {
+		t.Fatalf("a stable network has stats %+v", stats)
+	}
+
+	// every other packet is delayed 30 ms.
+	for i := 0; i < 100; i++ {
+		push(time.Duration(i%2) * 30 * time.Millisecond)
+	}
+
+	unstable := b.Stats()
+	if unstable.Jitter < 20*time.Millisecond || unstable.TargetDelay < 80*time.Millisecond {
+		t.Fatalf("an unstable network has stats %+v", unstable)
+	}
+
+	for i := 0; i < 100; i++ {
+		push(0)
+	}
+
+	if stats := b.Stats(); stats.TargetDelay >= unstable.TargetDelay/4 {
+		t.Fatalf("target delay is %s after the network became stable, it was %s",
+			stats.TargetDelay, unstable.TargetDelay)
+	}
+}
+
+// TestLongStream plays a 60 hour stream whose timestamps and sequence numbers wrap around.
+// a packet is sent every 10 seconds, i.e. the remote uses DTX, so timestamps advance quickly.
+func TestLongStream(t *testing.T) {
+	const (
+		gap     = 10 * clockRate
+		packets = 60 * 360
+	)
+
+	b := jitter.New(clockRate, 40*time.Millisecond, time.Second)
+
+	seq, timestamp := uint16(65000), uint32(1<<32-3*gap)
+
+	for i := 0; i < packets; i++ {
+		arrival := start.Add(time.Duration(i) * 10 * time.Second)
+
+		b.Push(packet(seq, timestamp), arrival)
+
+		if popped := popAll(b, arrival.Add(39*time.Millisecond)); len(popped) != 0 {
+			t.Fatalf("packet %d is played before its playout time", i)
+		}
+
+		if popped := popAll(b, arrival.Add(40*time.Millisecond)); !equal(popped, []int{int(seq)}) {
+			t.Fatalf("packet %d: popped %v at its playout time, want %d", i, popped, seq)
+		}
+
+		seq++
+		timestamp += gap
+	}
+
+	if stats := b.Stats(); stats.Played != packets || stats.Jitter != 0 || stats.Lost != 0 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
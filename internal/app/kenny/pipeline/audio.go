@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+// Source records audio from an audio.Recorder stream.
+type Source struct {
+	recorder audio.Recorder
+	streamID int
+	out      chan []int16
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// Limit is the maximum number of samples (all channels) to record. zero means no limit.
+	Limit int
+}
+
+// NewSource creates a Source stage which reads from an opened stream of recorder.
+func NewSource(recorder audio.Recorder, streamID int) *Source {
+	return &Source{
+		recorder: recorder,
+		streamID: streamID,
+		out:      make(chan []int16, portSize),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Name implements Stage.
+func (s *Source) Name() string {
+	return "source"
+}
+
+// Out returns the recorded pcm port.
+func (s *Source) Out() PCMPort {
+	return s.out
+}
+
+// Stop ends recording gracefully. unlike canceling the pipeline, next stages still process recorded data.
+func (s *Source) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Run implements Stage. it returns when the recorder reaches io.EOF, Limit is reached or Stop is called.
+func (s *Source) Run(ctx context.Context) error {
+	defer close(s.out)
+
+	recorded := 0
+
+	for s.Limit == 0 || recorded < s.Limit {
+		select {
+		case <-s.stop:
+			return nil
+		default:
+		}
+
+		data, err := s.recorder.Record(s.streamID)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if s.Limit > 0 && recorded+len(data) > s.Limit {
+			data = data[:s.Limit-recorded]
+		}
+
+		recorded += len(data)
+
+		// recorders reuse their buffer, so the data is copied before sending.
+		chunk := make([]int16, len(data))
+		copy(chunk, data)
+
+		select {
+		case s.out <- chunk:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Sink plays pcm data on an audio.Player stream.
+type Sink struct {
+	player    audio.Player
+	streamID  int
+	chunkSize int
+	in        PCMPort
+}
+
+// NewSink creates a Sink stage which plays everything from in on an opened stream of player.
+// chunkSize is the maximum data size the player accepts on each call, i.e. `FramesPerBuffer * Channels`.
+func NewSink(player audio.Player, streamID, chunkSize int, in PCMPort) *Sink {
+	return &Sink{
+		player:    player,
+		streamID:  streamID,
+		chunkSize: chunkSize,
+		in:        in,
+	}
+}
+
+// Name implements Stage.
+func (s *Sink) Name() string {
+	return "sink"
+}
+
+// Run implements Stage.
+func (s *Sink) Run(ctx context.Context) error {
+	for {
+		var (
+			data []int16
+			ok   bool
+		)
+
+		select {
+		case data, ok = <-s.in:
+			if !ok {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		for len(data) > 0 {
+			n := s.chunkSize
+			if n > len(data) {
+				n = len(data)
+			}
+
+			if err := s.player.Play(s.streamID, data[:n]); err != nil {
+				return err
+			}
+
+			data = data[n:]
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package pipeline
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+)
+
+// Encode is a stage which encodes pcm data into opus packets.
+type Encode struct {
+	encoder *encoding.Encoder
+	in      PCMPort
+	out     chan []byte
+}
+
+// NewEncode creates an Encode stage. encoder's Buffer is drained by the stage, so it shouldn't be read elsewhere.
+func NewEncode(encoder *encoding.Encoder, in PCMPort) *Encode {
+	return &Encode{
+		encoder: encoder,
+		in:      in,
+		out:     make(chan []byte, portSize),
+	}
+}
+
+// Name implements Stage.
+func (e *Encode) Name() string {
+	return "encode"
+}
+
+// Out returns the opus packet port.
+func (e *Encode) Out() PacketPort {
+	return e.out
+}
+
+// Run implements Stage. once input is closed, the last partial frame is flushed.
+func (e *Encode) Run(ctx context.Context) error {
+	defer close(e.out)
+
+	for {
+		var (
+			data []int16
+			ok   bool
+		)
+
+		select {
+		case data, ok = <-e.in:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if !ok {
+			if err := e.encoder.Flush(); err != nil {
+				return err
+			}
+
+			return e.drain(ctx)
+		}
+
+		if err := e.encoder.Write(data); err != nil {
+			return err
+		}
+
+		if err := e.drain(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *Encode) drain(ctx context.Context) error {
+	for {
+		packet, ok := e.encoder.Buffer.TryRead()
+		if !ok {
+			return nil
+		}
+
+		select {
+		case e.out <- packet:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Decode is a stage which decodes opus packets into pcm data.
+type Decode struct {
+	decoder *encoding.Decoder
+	in      PacketPort
+	frames  FramePort
+	out     chan []int16
+}
+
+// NewDecode creates a Decode stage.
+func NewDecode(decoder *encoding.Decoder, in PacketPort) *Decode {
+	return &Decode{
+		decoder: decoder,
+		in:      in,
+		out:     make(chan []int16, portSize),
+	}
+}
+
+// NewFrameDecode creates a Decode stage for received frames. lost frames are concealed.
+func NewFrameDecode(decoder *encoding.Decoder, in FramePort) *Decode {
+	return &Decode{
+		decoder: decoder,
+		frames:  in,
+		out:     make(chan []int16, portSize),
+	}
+}
+
+// Name implements Stage.
+func (d *Decode) Name() string {
+	return "decode"
+}
+
+// Out returns the decoded pcm port.
+func (d *Decode) Out() PCMPort {
+	return d.out
+}
+
+// Run implements Stage.
+func (d *Decode) Run(ctx context.Context) error {
+	defer close(d.out)
+
+	for {
+		frame, ok, err := d.receive(ctx)
+		if err != nil || !ok {
+			return err
+		}
+
+		pcm, err := d.decode(frame)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case d.out <- pcm:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// decode decodes a frame. packets received from network may be corrupted, so they are concealed
+// instead of failing the stage.
+func (d *Decode) decode(frame Frame) ([]int16, error) {
+	if frame.Gap {
+		return d.decoder.ComfortNoise()
+	}
+
+	if frame.Packet == nil {
+		return d.decoder.ConcealFrame(frame.FEC)
+	}
+
+	pcm, err := d.decoder.DecodeFrame(frame.Packet)
+	if err != nil && d.frames != nil {
+		log.Debugf("concealing a frame which can't be decoded: %s", err)
+
+		return d.decoder.ConcealFrame(nil)
+	}
+
+	return pcm, err
+}
+
+func (d *Decode) receive(ctx context.Context) (frame Frame, ok bool, err error) {
+	select {
+	case frame, ok = <-d.frames:
+	case frame.Packet, ok = <-d.in:
+	case <-ctx.Done():
+		return frame, false, ctx.Err()
+	}
+
+	return frame, ok, nil
+}
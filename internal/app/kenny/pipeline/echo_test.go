@@ -0,0 +1,121 @@
+package pipeline_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/fake"
+)
+
+// TestEchoPath records a tone from the fake backend, encodes, loops back and decodes it, and plays it on
+// the fake backend, the same way `record echo` does with sound cards.
+func TestEchoPath(t *testing.T) {
+	for _, channels := range []int{1, 2} {
+		settings := audio.StreamSettings{SampleRate: 48000, Channels: channels, FramesPerBuffer: 480}
+		frameSize := 960
+
+		recorder := fake.NewRecorder(settings, fake.SineSource(440, settings.SampleRate, channels, 8000),
+			fake.TimingInstant)
+		player := fake.NewPlayer(settings, fake.TimingInstant)
+
+		encoder, err := encoding.NewEncoder(int(settings.SampleRate), channels, frameSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoder, err := encoding.NewDecoder(int(settings.SampleRate), channels, frameSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recordID, err := recorder.OpenStream()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		playID, err := player.OpenStream()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// one second of audio is exactly 50 frames.
+		source := pipeline.NewSource(recorder, recordID)
+		source.Limit = int(settings.SampleRate) * channels
+
+		encode := pipeline.NewEncode(encoder, source.Out())
+
+		loopback, err := pipeline.NewLoopback(100, encoding.DropOldest, encode.Out())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decode := pipeline.NewDecode(decoder, loopback.Out())
+		sink := pipeline.NewSink(player, playID, settings.BufferSize(), decode.Out())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+		err = pipeline.New(source, encode, loopback, decode, sink).Run(ctx)
+
+		cancel()
+
+		if err != nil {
+			t.Fatalf("%d channels: %s", channels, err)
+		}
+
+		if err := player.CloseStream(playID); err != nil {
+			t.Fatal(err)
+		}
+
+		played, err := player.Samples(playID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(played) != source.Limit {
+			t.Fatalf("%d channels: played %d samples, want %d", channels, len(played), source.Limit)
+		}
+
+		if stats := decoder.Stats(); stats.Frames != 50 {
+			t.Fatalf("%d channels: decoded %d frames, want 50", channels, stats.Frames)
+		}
+
+		// opus is lossy and delays audio by its lookahead, so played audio is compared with the best aligned tone.
+		want := make([]int16, len(played))
+		if _, err := fake.SineSource(440, settings.SampleRate, channels, 8000).Read(want); err != nil {
+			t.Fatal(err)
+		}
+
+		if c := bestCorrelation(played, want, channels, 400); c < 0.9 {
+			t.Fatalf("%d channels: played audio correlates %.2f with the recorded tone", channels, c)
+		}
+	}
+}
+
+// bestCorrelation returns the highest normalized correlation of played and recorded audio when played audio is
+// delayed by at most maxDelay frames. the first and last 100 ms are skipped, they include encoder warm up.
+func bestCorrelation(played, recorded []int16, channels, maxDelay int) float64 {
+	skip := 4800 * channels
+	best := -1.0
+
+	for delay := 0; delay <= maxDelay; delay++ {
+		var dot, pp, rr float64
+
+		for i := skip; i < len(played)-skip; i++ {
+			p, r := float64(played[i]), float64(recorded[i-delay*channels])
+			dot += p * r
+			pp += p * p
+			rr += r * r
+		}
+
+		if pp > 0 && rr > 0 {
+			best = math.Max(best, dot/math.Sqrt(pp*rr))
+		}
+	}
+
+	return best
+}
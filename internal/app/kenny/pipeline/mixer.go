@@ -0,0 +1,278 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMixerHeadroom is the default attenuation of mixed participants, in dB.
+	DefaultMixerHeadroom = 6
+	// DefaultMixerDelay is the default audio duration an input buffers before it's mixed.
+	DefaultMixerDelay = 40 * time.Millisecond
+
+	// mixerInterval is how often Mixer outputs mixed audio.
+	mixerInterval = 10 * time.Millisecond
+	// mixerMaxDelay is the maximum audio duration an input buffers, older audio is dropped.
+	mixerMaxDelay = 200 * time.Millisecond
+	// clipThreshold is the level which soft clipping starts from, relative to full scale.
+	clipThreshold = 0.5
+	fullScale     = 32768
+)
+
+// ErrMixerClosed occurs when adding an input to a Mixer which is closed or has stopped running.
+var ErrMixerClosed = errors.New("mixer is closed")
+
+// Mixer is a stage which sums pcm streams of several participants into a single stream, so they can be
+// played on one device stream. inputs can be added and removed while it's running.
+//
+// output is paced by the clock: a chunk of every interval is mixed from whatever inputs have,
+// an input which doesn't have enough data is silent until it has buffered Delay of audio again.
+type Mixer struct {
+	sampleRate int
+	channels   int
+	out        chan []int16
+	// done is closed when Run returns, so inputs stop reading.
+	done chan struct{}
+
+	// Headroom is the attenuation in dB which is applied when more than one input is mixed.
+	// louder peaks are soft clipped instead of wrapping around.
+	Headroom float64
+	// Delay is the audio duration an input buffers before it's mixed, it absorbs timing differences of inputs.
+	Delay time.Duration
+
+	mu     sync.Mutex
+	inputs map[string]*mixerInput
+	closed bool
+	gain   float64
+}
+
+type mixerInput struct {
+	queue []int16
+	// playing is set once the input has buffered enough, it's reset when it runs out of data.
+	playing bool
+	ended   bool
+}
+
+// NewMixer creates a Mixer stage for interleaved pcm data with the given sample rate and channel count.
+func NewMixer(sampleRate, channels int) *Mixer {
+	return &Mixer{
+		sampleRate: sampleRate,
+		channels:   channels,
+		out:        make(chan []int16, portSize),
+		done:       make(chan struct{}),
+		Headroom:   DefaultMixerHeadroom,
+		Delay:      DefaultMixerDelay,
+		inputs:     make(map[string]*mixerInput),
+		gain:       1,
+	}
+}
+
+// Name implements Stage.
+func (m *Mixer) Name() string {
+	return "mixer"
+}
+
+// Out returns the mixed pcm port.
+func (m *Mixer) Out() PCMPort {
+	return m.out
+}
+
+// Add adds a participant's pcm stream. the input is removed once in is closed and its buffered audio is mixed.
+// adding an existing id replaces the previous input. it returns ErrMixerClosed after Close or once Run has returned.
+func (m *Mixer) Add(id string, in PCMPort) error {
+	input := &mixerInput{}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.done:
+		return ErrMixerClosed
+	default:
+	}
+
+	if m.closed {
+		return ErrMixerClosed
+	}
+
+	m.inputs[id] = input
+
+	go m.read(input, in)
+
+	return nil
+}
+
+// read buffers data of an input until in is closed or Run returns.
+func (m *Mixer) read(input *mixerInput, in PCMPort) {
+	maxQueue := m.samples(mixerMaxDelay)
+
+	for {
+		select {
+		case data, ok := <-in:
+			if !ok {
+				m.mu.Lock()
+				input.ended = true
+				m.mu.Unlock()
+
+				return
+			}
+
+			m.mu.Lock()
+
+			input.queue = append(input.queue, data...)
+			if extra := len(input.queue) - maxQueue; extra > 0 {
+				input.queue = input.queue[extra:]
+			}
+
+			m.mu.Unlock()
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// Inputs returns number of mixed inputs.
+func (m *Mixer) Inputs() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.inputs)
+}
+
+// Close stops accepting inputs. Run returns once every input has ended and its audio is mixed.
+func (m *Mixer) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+}
+
+// Run implements Stage. it returns when ctx is done, or after Close once every input has ended.
+func (m *Mixer) Run(ctx context.Context) error {
+	defer close(m.out)
+	defer close(m.done)
+
+	ticker := time.NewTicker(mixerInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	mixed := 0
+
+	for {
+		select {
+		case now := <-ticker.C:
+			// output follows elapsed time instead of ticks, so late ticks don't make the output fall behind.
+			due := m.samples(now.Sub(start)) - mixed
+			if due <= 0 {
+				continue
+			}
+
+			chunk, done := m.mix(due)
+			mixed += len(chunk)
+
+			select {
+			case m.out <- chunk:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			if done {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// mix mixes n samples from inputs. done is true when the mixer is closed and there are no inputs left.
+func (m *Mixer) mix(n int) (chunk []int16, done bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sum := make([]float64, n)
+	delay := m.samples(m.Delay)
+	active := 0
+
+	for id, input := range m.inputs {
+		if !input.playing && (len(input.queue) >= delay || input.ended) {
+			input.playing = true
+		}
+
+		if !input.playing {
+			continue
+		}
+
+		if len(input.queue) > 0 {
+			active++
+		}
+
+		k := copyAdd(sum, input.queue)
+		input.queue = input.queue[k:]
+
+		if len(input.queue) == 0 {
+			if input.ended {
+				delete(m.inputs, id)
+			} else {
+				// input has run out of data, it's buffered again so it doesn't stutter.
+				input.playing = false
+			}
+		}
+	}
+
+	target := 1.0
+	if active > 1 {
+		target = math.Pow(10, -m.Headroom/20) //nolint:gomnd
+	}
+
+	chunk = make([]int16, n)
+	frames := n / m.channels
+
+	// gain follows participants count linearly over the chunk, so joining and leaving doesn't click.
+	for i := range sum {
+		gain := m.gain + (target-m.gain)*float64(i/m.channels+1)/float64(frames)
+		chunk[i] = softClip(sum[i] * gain)
+	}
+
+	m.gain = target
+
+	return chunk, m.closed && len(m.inputs) == 0
+}
+
+// samples returns number of interleaved samples in d, rounded down to whole frames.
+func (m *Mixer) samples(d time.Duration) int {
+	return int(d*time.Duration(m.sampleRate)/time.Second) * m.channels
+}
+
+// copyAdd adds src to dst and returns number of added samples.
+func copyAdd(dst []float64, src []int16) int {
+	if len(src) > len(dst) {
+		src = src[:len(dst)]
+	}
+
+	for i, s := range src {
+		dst[i] += float64(s)
+	}
+
+	return len(src)
+}
+
+// softClip converts a sample to int16. levels above clipThreshold are compressed smoothly towards full scale.
+func softClip(s float64) int16 {
+	x := s / fullScale
+
+	sign := 1.0
+	if x < 0 {
+		sign, x = -1, -x
+	}
+
+	if x > clipThreshold {
+		x = clipThreshold + (1-clipThreshold)*math.Tanh((x-clipThreshold)/(1-clipThreshold))
+	}
+
+	return int16(math.Round(sign * math.Min(x*fullScale, fullScale-1)))
+}
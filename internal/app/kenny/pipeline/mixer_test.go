@@ -0,0 +1,218 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+)
+
+const mixerRate = 8000
+
+// input returns a closed pcm port with n samples of value v.
+func input(v int16, n int) pipeline.PCMPort {
+	pcm := make([]int16, n)
+	for i := range pcm {
+		pcm[i] = v
+	}
+
+	in := make(chan []int16, 1)
+	in <- pcm
+	close(in)
+
+	return in
+}
+
+// runMixer runs m in the background. the returned channel receives everything m has mixed once Run returns.
+func runMixer(t *testing.T, m *pipeline.Mixer) <-chan []int16 {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- m.Run(ctx)
+	}()
+
+	mixed := make(chan []int16, 1)
+
+	go func() {
+		defer cancel()
+
+		var out []int16
+
+		for chunk := range m.Out() {
+			out = append(out, chunk...)
+		}
+
+		if err := <-errs; err != nil {
+			t.Errorf("mixer failed: %s", err)
+		}
+
+		mixed <- out
+	}()
+
+	return mixed
+}
+
+// count returns number of samples with value v.
+func count(pcm []int16, v int16) int {
+	n := 0
+
+	for _, s := range pcm {
+		if s == v {
+			n++
+		}
+	}
+
+	return n
+}
+
+func waitInputs(t *testing.T, m *pipeline.Mixer, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); m.Inputs() != n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("mixer has %d inputs, want %d", m.Inputs(), n)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMixerSoftClips(t *testing.T) {
+	m := pipeline.NewMixer(mixerRate, 1)
+	m.Headroom = 0
+
+	// the sum is almost twice the int16 range.
+	for _, id := range []string{"a", "b"} {
+		if err := m.Add(id, input(30000, mixerRate/10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.Close()
+
+	var peak int16
+
+	for i, s := range <-runMixer(t, m) {
+		if s < 0 {
+			t.Fatalf("sample %d has wrapped around to %d", i, s)
+		}
+
+		if s > peak {
+			peak = s
+		}
+	}
+
+	if peak <= 30000 {
+		t.Fatalf("inputs weren't summed, peak is %d", peak)
+	}
+}
+
+func TestMixerInputEnds(t *testing.T) {
+	m := pipeline.NewMixer(mixerRate, 1)
+
+	if err := m.Add("a", input(1000, mixerRate/10)); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make(chan []int16)
+	if err := m.Add("b", b); err != nil {
+		t.Fatal(err)
+	}
+
+	mixed := runMixer(t, m)
+
+	// the ended input is removed once its audio is mixed, the other one keeps the mixer running.
+	waitInputs(t, m, 1)
+
+	close(b)
+	m.Close()
+
+	if n := count(<-mixed, 1000); n != mixerRate/10 {
+		t.Fatalf("mixed %d samples of the ended input, want %d", n, mixerRate/10)
+	}
+}
+
+func TestMixerLateInput(t *testing.T) {
+	m := pipeline.NewMixer(mixerRate, 1)
+
+	a := make(chan []int16)
+	if err := m.Add("a", a); err != nil {
+		t.Fatal(err)
+	}
+
+	mixed := runMixer(t, m)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := m.Add("late", input(2000, mixerRate/10)); err != nil {
+		t.Fatal(err)
+	}
+
+	waitInputs(t, m, 1)
+
+	close(a)
+	m.Close()
+
+	if err := m.Add("closed", input(3000, mixerRate/10)); !errors.Is(err, pipeline.ErrMixerClosed) {
+		t.Fatalf("adding an input after close: %v, want %v", err, pipeline.ErrMixerClosed)
+	}
+
+	out := <-mixed
+
+	if n := count(out, 2000); n != mixerRate/10 {
+		t.Fatalf("mixed %d samples of the late input, want %d", n, mixerRate/10)
+	}
+
+	if out[0] != 0 {
+		t.Fatal("late input is mixed before it has joined")
+	}
+}
+
+func TestMixerTrimsQueue(t *testing.T) {
+	m := pipeline.NewMixer(mixerRate, 1)
+
+	// a second of audio at once, only the last 200 ms is kept.
+	if err := m.Add("a", input(1000, mixerRate)); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Close()
+
+	if n := count(<-runMixer(t, m), 1000); n != mixerRate/5 {
+		t.Fatalf("mixed %d samples, want %d", n, mixerRate/5)
+	}
+}
+
+func TestMixerStopsInputs(t *testing.T) {
+	m := pipeline.NewMixer(mixerRate, 1)
+
+	in := make(chan []int16)
+	if err := m.Add("a", in); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := m.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("run: %v", err)
+	}
+
+	// the input stops reading once Run has returned, so its producer isn't drained forever.
+	time.Sleep(10 * time.Millisecond)
+
+	select {
+	case in <- make([]int16, 10):
+		t.Fatal("input is still read after the mixer has stopped")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := m.Add("b", input(1000, 10)); !errors.Is(err, pipeline.ErrMixerClosed) {
+		t.Fatalf("adding an input after run: %v, want %v", err, pipeline.ErrMixerClosed)
+	}
+}
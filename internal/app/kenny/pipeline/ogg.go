@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+)
+
+// OggSink is a stage which writes opus packets into an Ogg Opus stream.
+// the stream is closed (EOS page is written) once the input port is closed.
+type OggSink struct {
+	writer *encoding.OggWriter
+	in     PacketPort
+}
+
+// NewOggSink creates an OggSink stage.
+func NewOggSink(writer *encoding.OggWriter, in PacketPort) *OggSink {
+	return &OggSink{
+		writer: writer,
+		in:     in,
+	}
+}
+
+// Name implements Stage.
+func (o *OggSink) Name() string {
+	return "ogg-sink"
+}
+
+// Run implements Stage.
+func (o *OggSink) Run(ctx context.Context) error {
+	for {
+		select {
+		case packet, ok := <-o.in:
+			if !ok {
+				return o.writer.Close()
+			}
+
+			if err := o.writer.WritePacket(packet); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// OggSource is a stage which reads opus packets from an Ogg Opus stream.
+type OggSource struct {
+	reader *encoding.OggReader
+	out    chan []byte
+}
+
+// NewOggSource creates an OggSource stage.
+func NewOggSource(reader *encoding.OggReader) *OggSource {
+	return &OggSource{
+		reader: reader,
+		out:    make(chan []byte, portSize),
+	}
+}
+
+// Name implements Stage.
+func (o *OggSource) Name() string {
+	return "ogg-source"
+}
+
+// Out returns the opus packet port.
+func (o *OggSource) Out() PacketPort {
+	return o.out
+}
+
+// Run implements Stage.
+func (o *OggSource) Run(ctx context.Context) error {
+	defer close(o.out)
+
+	for {
+		packet, err := o.reader.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case o.out <- packet:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
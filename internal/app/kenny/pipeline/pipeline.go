@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// portSize is the capacity of channels between stages. it lets stages run a little ahead of each other.
+const portSize = 16
+
+type (
+	// PCMPort carries chunks of interleaved pcm data between stages.
+	PCMPort <-chan []int16
+	// PacketPort carries encoded opus packets between stages.
+	PacketPort <-chan []byte
+	// FramePort carries received opus packets in playout order, including the lost ones.
+	FramePort <-chan Frame
+)
+
+// Frame is a received opus packet. Packet is nil for a lost packet, then FEC is the following packet
+// if it has already arrived, so the lost one can be recovered from its in-band FEC data.
+// Gap marks a frame which fills a gap in transmission, i.e. remote DTX, with comfort noise.
+type Frame struct {
+	Packet []byte
+	FEC    []byte
+	Gap    bool
+}
+
+// Stage is a single block of a pipeline, i.e. a source, processor, encoder, transport, decoder or sink.
+// stages are connected by passing output port of one stage to the constructor of the next one.
+//
+// Run must return once ctx is done or its input port is closed, and it must close its output port before returning.
+type Stage interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// StageError is an error returned by a stage.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %s: %s", e.Stage, e.Err.Error())
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Errors contains errors of every failed stage.
+type Errors []*StageError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Error()
+	}
+
+	return "pipeline failed: " + strings.Join(messages, "; ")
+}
+
+// Unwrap returns the first stage's error, so errors.Is and errors.As work on the root cause.
+func (e Errors) Unwrap() error {
+	return e[0]
+}
+
+// Pipeline runs a group of connected stages under a single context.
+type Pipeline struct {
+	stages []Stage
+}
+
+// New creates a pipeline from given stages.
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{
+		stages: stages,
+	}
+}
+
+// Add adds stages to the pipeline. it must be called before Run.
+func (p *Pipeline) Add(stages ...Stage) {
+	p.stages = append(p.stages, stages...)
+}
+
+// Run starts every stage and blocks until all of them return.
+// the pipeline finishes normally when sources run out of data and every stage drains its input.
+// when a stage fails, the others are canceled. canceling ctx stops every stage immediately.
+//
+// the returned error is either nil or Errors.
+func (p *Pipeline) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs Errors
+	)
+
+	for _, s := range p.stages {
+		wg.Add(1)
+
+		go func(s Stage) {
+			defer wg.Done()
+
+			err := s.Run(ctx)
+			if err == nil || errors.Is(err, context.Canceled) {
+				return
+			}
+
+			mu.Lock()
+			errs = append(errs, &StageError{Stage: s.Name(), Err: err})
+			mu.Unlock()
+
+			cancel()
+		}(s)
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
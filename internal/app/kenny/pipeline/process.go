@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"context"
+)
+
+// Processor transforms pcm data, i.e. gain control or noise suppression.
+// it may modify and return the given slice.
+type Processor interface {
+	Process(pcm []int16) ([]int16, error)
+}
+
+// ProcessorFunc is an adapter to use ordinary functions as a Processor.
+type ProcessorFunc func(pcm []int16) ([]int16, error)
+
+// Process calls f(pcm).
+func (f ProcessorFunc) Process(pcm []int16) ([]int16, error) {
+	return f(pcm)
+}
+
+// Process is a stage which applies a Processor on every pcm chunk.
+type Process struct {
+	name      string
+	processor Processor
+	in        PCMPort
+	out       chan []int16
+}
+
+// NewProcess creates a Process stage. name is used for error reporting.
+func NewProcess(name string, processor Processor, in PCMPort) *Process {
+	return &Process{
+		name:      name,
+		processor: processor,
+		in:        in,
+		out:       make(chan []int16, portSize),
+	}
+}
+
+// Name implements Stage.
+func (p *Process) Name() string {
+	return p.name
+}
+
+// Out returns the processed pcm port.
+func (p *Process) Out() PCMPort {
+	return p.out
+}
+
+// Run implements Stage.
+func (p *Process) Run(ctx context.Context) error {
+	defer close(p.out)
+
+	for {
+		var (
+			data []int16
+			ok   bool
+		)
+
+		select {
+		case data, ok = <-p.in:
+			if !ok {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		data, err := p.processor.Process(data)
+		if err != nil {
+			return err
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		select {
+		case p.out <- data:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
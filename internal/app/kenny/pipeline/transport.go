@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+)
+
+// Loopback is a transport which hands packets from its input to its output through an encoding.FrameBuffer.
+// it decouples the sending and receiving side the same way a network would.
+type Loopback struct {
+	buffer *encoding.FrameBuffer
+	in     PacketPort
+	out    chan []byte
+}
+
+// NewLoopback creates a Loopback stage which queues at most size packets using given overflow policy.
+func NewLoopback(size int, policy encoding.OverflowPolicy, in PacketPort) (*Loopback, error) {
+	buffer, err := encoding.NewFrameBuffer(size, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Loopback{
+		buffer: buffer,
+		in:     in,
+		out:    make(chan []byte, portSize),
+	}, nil
+}
+
+// Name implements Stage.
+func (l *Loopback) Name() string {
+	return "loopback"
+}
+
+// Out returns the received packet port.
+func (l *Loopback) Out() PacketPort {
+	return l.out
+}
+
+// Stats returns the underlying buffer's counters.
+func (l *Loopback) Stats() encoding.FrameBufferStats {
+	return l.buffer.Stats()
+}
+
+// Run implements Stage.
+func (l *Loopback) Run(ctx context.Context) error {
+	defer close(l.out)
+
+	go func() {
+		defer l.buffer.Close()
+
+		for {
+			select {
+			case packet, ok := <-l.in:
+				if !ok {
+					return
+				}
+
+				if err := l.buffer.WriteContext(ctx, packet); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		packet, err := l.buffer.Read(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case l.out <- packet:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
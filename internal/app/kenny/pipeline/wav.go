@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/smf8/kenny/pkg/audio/wav"
+)
+
+// WavSource is a stage which reads pcm data from a wav stream as fast as next stages accept it.
+// use Source with a wav.Recorder to read a file in real time instead.
+type WavSource struct {
+	reader    *wav.Reader
+	chunkSize int
+	out       chan []int16
+}
+
+// NewWavSource creates a WavSource stage which sends chunks of at most chunkSize samples (all channels).
+func NewWavSource(reader *wav.Reader, chunkSize int) *WavSource {
+	return &WavSource{
+		reader:    reader,
+		chunkSize: chunkSize,
+		out:       make(chan []int16, portSize),
+	}
+}
+
+// Name implements Stage.
+func (w *WavSource) Name() string {
+	return "wav-source"
+}
+
+// Out returns the pcm port.
+func (w *WavSource) Out() PCMPort {
+	return w.out
+}
+
+// Run implements Stage.
+func (w *WavSource) Run(ctx context.Context) error {
+	defer close(w.out)
+
+	for {
+		chunk := make([]int16, w.chunkSize)
+
+		n, err := w.reader.Read(chunk)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case w.out <- chunk[:n]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
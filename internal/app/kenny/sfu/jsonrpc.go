@@ -0,0 +1,239 @@
+// Package sfu joins ion-sfu sessions over its JSON-RPC 2.0 websocket signaling.
+//
+// a participant has two peer connections. the publisher sends the local track to the SFU, it's offered by
+// the participant with the join request. the subscriber receives other participants' tracks, it's offered
+// by the SFU with offer notifications whenever participants join or leave:
+//
+//	-> {"jsonrpc": "2.0", "id": 1, "method": "join", "params": {"sid": "...", "uid": "...", "offer": {...}}}
+//	<- {"jsonrpc": "2.0", "id": 1, "result": {"type": "answer", "sdp": "..."}}
+//	<- {"jsonrpc": "2.0", "method": "offer", "params": {"type": "offer", "sdp": "..."}}
+//	-> {"jsonrpc": "2.0", "method": "answer", "params": {"desc": {"type": "answer", "sdp": "..."}}}
+//	<> {"jsonrpc": "2.0", "method": "trickle", "params": {"target": 0, "candidate": {...}}}
+//
+// trickle target is 0 for the publisher and 1 for the subscriber connection.
+package sfu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	jsonrpcVersion = "2.0"
+	writeTimeout   = 10 * time.Second
+
+	notificationsBufferSize = 16
+)
+
+// ErrConnClosed occurs when a call is made on a closed connection or the connection is lost before its response.
+var ErrConnClosed = errors.New("json-rpc connection is closed")
+
+// Error is an error response of a JSON-RPC call.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// Notification is a JSON-RPC request without id, it has no response.
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// request is an outgoing call or notification, notifications don't have id.
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *uint64     `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// message is an incoming response or notification.
+type message struct {
+	ID     *uint64         `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Conn is a JSON-RPC 2.0 client connection over websocket.
+type Conn struct {
+	conn          *websocket.Conn
+	notifications chan Notification
+	done          chan struct{}
+
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	nextID uint64
+	calls  map[uint64]chan message
+
+	closeOnce sync.Once
+}
+
+// Dial connects to a JSON-RPC websocket endpoint, i.e. ws://localhost:7000/ws for ion-sfu.
+func Dial(ctx context.Context, url string) (*Conn, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to sfu %s: %w", url, err)
+	}
+
+	c := &Conn{
+		conn:          conn,
+		notifications: make(chan Notification, notificationsBufferSize),
+		done:          make(chan struct{}),
+		calls:         make(map[uint64]chan message),
+	}
+
+	go c.readLoop()
+
+	return c, nil
+}
+
+// Call calls method and decodes its result into result, which can be nil.
+func (c *Conn) Call(ctx context.Context, method string, params, result interface{}) error {
+	response := make(chan message, 1)
+
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.calls[id] = response
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(request{JSONRPC: jsonrpcVersion, ID: &id, Method: method, Params: params}); err != nil {
+		return err
+	}
+
+	select {
+	case m := <-response:
+		if m.Error != nil {
+			return fmt.Errorf("%s call has failed: %w", method, m.Error)
+		}
+
+		if result == nil {
+			return nil
+		}
+
+		if err := json.Unmarshal(m.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+
+		return nil
+	case <-c.done:
+		return fmt.Errorf("%s call has failed: %w", method, ErrConnClosed)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification.
+func (c *Conn) Notify(method string, params interface{}) error {
+	return c.write(request{JSONRPC: jsonrpcVersion, Method: method, Params: params})
+}
+
+// Notifications returns notifications received from the server. it's closed when the connection is closed.
+func (c *Conn) Notifications() <-chan Notification {
+	return c.notifications
+}
+
+// Done is closed when the connection is closed or lost.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+
+	c.writeMu.Lock()
+	_ = c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+	c.writeMu.Unlock()
+
+	return c.conn.Close()
+}
+
+func (c *Conn) write(r request) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	if err := c.conn.WriteJSON(r); err != nil {
+		return fmt.Errorf("failed to send %s: %w", r.Method, err)
+	}
+
+	return nil
+}
+
+func (c *Conn) readLoop() {
+	defer close(c.notifications)
+
+	defer c.closeOnce.Do(func() {
+		close(c.done)
+	})
+
+	for {
+		var m message
+		if err := c.conn.ReadJSON(&m); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Warnf("sfu connection is lost: %s", err)
+			}
+
+			return
+		}
+
+		if m.Method == "" {
+			c.respond(m)
+
+			continue
+		}
+
+		// requests from the server with id aren't expected, they are handled as notifications.
+		select {
+		case c.notifications <- Notification{Method: m.Method, Params: m.Params}:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Conn) respond(m message) {
+	if m.ID == nil {
+		log.Warnf("ignored json-rpc response without id")
+
+		return
+	}
+
+	c.mu.Lock()
+	response, ok := c.calls[*m.ID]
+	c.mu.Unlock()
+
+	if !ok {
+		log.Warnf("ignored json-rpc response to unknown call %d", *m.ID)
+
+		return
+	}
+
+	response <- m
+}
@@ -0,0 +1,252 @@
+package sfu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	log "github.com/sirupsen/logrus"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+// trickle targets of ion-sfu.
+const (
+	TargetPublisher  = 0
+	TargetSubscriber = 1
+)
+
+type joinParams struct {
+	SID   string                    `json:"sid"`
+	UID   string                    `json:"uid"`
+	Offer webrtc.SessionDescription `json:"offer"`
+}
+
+type answerParams struct {
+	Desc webrtc.SessionDescription `json:"desc"`
+}
+
+type trickleParams struct {
+	Target    int                     `json:"target"`
+	Candidate webrtc.ICECandidateInit `json:"candidate"`
+}
+
+// connection is one of a participant's peer connections with its ICE candidate queues.
+type connection struct {
+	peer   *transport.Peer
+	target int
+
+	// described is set when local description is sent, local candidates are queued until then.
+	described bool
+	local     []webrtc.ICECandidateInit
+	// remoteDescribed is set when remote description is set, remote candidates are queued until then.
+	remoteDescribed bool
+	pending         []webrtc.ICECandidateInit
+}
+
+// Session is a participant of an ion-sfu session.
+type Session struct {
+	conn *Conn
+	sid  string
+	uid  string
+
+	mu         sync.Mutex
+	publisher  *connection
+	subscriber *connection
+
+	done chan struct{}
+}
+
+// New creates a Session which joins session sid through conn. publisher sends the local track
+// and subscriber, which should be receive only, receives the other participants' tracks.
+func New(conn *Conn, sid string, publisher, subscriber *transport.Peer) *Session {
+	s := &Session{
+		conn:       conn,
+		sid:        sid,
+		uid:        fmt.Sprintf("kenny-%08x", rand.Uint32()), //nolint:gosec
+		publisher:  &connection{peer: publisher, target: TargetPublisher},
+		subscriber: &connection{peer: subscriber, target: TargetSubscriber},
+		done:       make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+
+		select {
+		case <-conn.Done():
+		case <-publisher.Done():
+		}
+	}()
+
+	publisher.OnICECandidate(s.onICECandidate(s.publisher))
+	subscriber.OnICECandidate(s.onICECandidate(s.subscriber))
+
+	return s
+}
+
+// UID returns the participant's id in the session.
+func (s *Session) UID() string {
+	return s.uid
+}
+
+// Join joins the session and returns once the publisher connection is established.
+// signaling notifications are handled in background until the connection is closed.
+func (s *Session) Join(ctx context.Context) error {
+	go s.signal()
+
+	offer, err := s.publisher.peer.CreateOffer()
+	if err != nil {
+		return err
+	}
+
+	var answer webrtc.SessionDescription
+	if err := s.conn.Call(ctx, "join", joinParams{SID: s.sid, UID: s.uid, Offer: offer}, &answer); err != nil {
+		return fmt.Errorf("failed to join session %s: %w", s.sid, err)
+	}
+
+	if err := s.publisher.peer.SetRemoteDescription(answer); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.publisher.described = true
+	s.publisher.remoteDescribed = true
+	local, pending := s.publisher.local, s.publisher.pending
+	s.publisher.local, s.publisher.pending = nil, nil
+	s.mu.Unlock()
+
+	s.flush(s.publisher, local, pending)
+
+	select {
+	case <-s.publisher.peer.Connected():
+		return nil
+	case <-s.publisher.peer.Done():
+		return fmt.Errorf("failed to join session %s: %w", s.sid, transport.ErrPeerClosed)
+	case <-s.conn.Done():
+		return fmt.Errorf("failed to join session %s: %w", s.sid, ErrConnClosed)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done is closed when signaling connection or publisher connection is closed.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Session) signal() {
+	for n := range s.conn.Notifications() {
+		var err error
+
+		switch n.Method {
+		case "offer":
+			err = s.onOffer(n.Params)
+		case "trickle":
+			err = s.onTrickle(n.Params)
+		default:
+			log.Debugf("ignored %s notification from sfu", n.Method)
+		}
+
+		if err != nil {
+			log.Errorf("failed to handle %s notification: %s", n.Method, err)
+		}
+	}
+}
+
+// onOffer answers the SFU's offer of the subscriber connection. it's sent again whenever tracks change.
+func (s *Session) onOffer(params json.RawMessage) error {
+	var offer webrtc.SessionDescription
+	if err := json.Unmarshal(params, &offer); err != nil {
+		return fmt.Errorf("failed to decode offer: %w", err)
+	}
+
+	if err := s.subscriber.peer.SetRemoteDescription(offer); err != nil {
+		return err
+	}
+
+	answer, err := s.subscriber.peer.CreateAnswer()
+	if err != nil {
+		return err
+	}
+
+	if err := s.conn.Notify("answer", answerParams{Desc: answer}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.subscriber.described = true
+	s.subscriber.remoteDescribed = true
+	local, pending := s.subscriber.local, s.subscriber.pending
+	s.subscriber.local, s.subscriber.pending = nil, nil
+	s.mu.Unlock()
+
+	s.flush(s.subscriber, local, pending)
+
+	return nil
+}
+
+func (s *Session) onTrickle(params json.RawMessage) error {
+	var trickle trickleParams
+	if err := json.Unmarshal(params, &trickle); err != nil {
+		return fmt.Errorf("failed to decode trickle: %w", err)
+	}
+
+	c := s.publisher
+	if trickle.Target == TargetSubscriber {
+		c = s.subscriber
+	}
+
+	s.mu.Lock()
+	if !c.remoteDescribed {
+		c.pending = append(c.pending, trickle.Candidate)
+		s.mu.Unlock()
+
+		return nil
+	}
+	s.mu.Unlock()
+
+	return c.peer.AddICECandidate(trickle.Candidate)
+}
+
+func (s *Session) onICECandidate(c *connection) func(*webrtc.ICECandidate) {
+	return func(candidate *webrtc.ICECandidate) {
+		// nil means gathering is complete, ion-sfu doesn't need end of candidates.
+		if candidate == nil {
+			return
+		}
+
+		init := candidate.ToJSON()
+
+		s.mu.Lock()
+		if !c.described {
+			c.local = append(c.local, init)
+			s.mu.Unlock()
+
+			return
+		}
+		s.mu.Unlock()
+
+		s.trickle(c, init)
+	}
+}
+
+// flush sends queued local candidates and adds queued remote candidates of c.
+func (s *Session) flush(c *connection, local, pending []webrtc.ICECandidateInit) {
+	for _, candidate := range local {
+		s.trickle(c, candidate)
+	}
+
+	for _, candidate := range pending {
+		if err := c.peer.AddICECandidate(candidate); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func (s *Session) trickle(c *connection, candidate webrtc.ICECandidateInit) {
+	if err := s.conn.Notify("trickle", trickleParams{Target: c.target, Candidate: candidate}); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,311 @@
+package sfu_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
+	"github.com/smf8/kenny/internal/app/kenny/sfu"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+// standIn is an SFU stand-in with a connection of each direction. its descriptions don't contain candidates,
+// they are trickled before the descriptions, so connections are only established if queued candidates are flushed.
+type standIn struct {
+	t          *testing.T
+	publisher  *transport.Peer
+	subscriber *transport.Peer
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	// answered is closed when the subscriber's answer is received.
+	answered chan struct{}
+	// trickled counts candidates received for each target.
+	mu       sync.Mutex
+	trickled map[int]int
+}
+
+type rpc struct {
+	ID     *uint64         `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result interface{}     `json:"result,omitempty"`
+}
+
+func newStandIn(t *testing.T) *standIn {
+	t.Helper()
+
+	publisher, err := transport.NewReceiveOnlyPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber, err := transport.NewPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &standIn{
+		t:          t,
+		publisher:  publisher,
+		subscriber: subscriber,
+		answered:   make(chan struct{}),
+		trickled:   make(map[int]int),
+	}
+}
+
+func (s *standIn) close() {
+	_ = s.publisher.Close()
+	_ = s.subscriber.Close()
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.t.Error(err)
+
+		return
+	}
+
+	defer conn.Close()
+
+	s.conn = conn
+
+	for {
+		var m rpc
+		if err := conn.ReadJSON(&m); err != nil {
+			return
+		}
+
+		if err := s.handle(m); err != nil {
+			s.t.Errorf("stand-in failed to handle %s: %s", m.Method, err)
+
+			return
+		}
+	}
+}
+
+func (s *standIn) handle(m rpc) error {
+	switch m.Method {
+	case "join":
+		var params struct {
+			Offer webrtc.SessionDescription `json:"offer"`
+		}
+
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return err
+		}
+
+		if err := s.publisher.SetRemoteDescription(params.Offer); err != nil {
+			return err
+		}
+
+		answer, err := s.publisher.CreateAnswer()
+		if err != nil {
+			return err
+		}
+
+		s.trickle(s.publisher, sfu.TargetPublisher)
+		s.write(rpc{ID: m.ID, Result: answer})
+
+		return s.offer()
+	case "answer":
+		var params struct {
+			Desc webrtc.SessionDescription `json:"desc"`
+		}
+
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return err
+		}
+
+		close(s.answered)
+
+		return s.subscriber.SetRemoteDescription(params.Desc)
+	case "trickle":
+		var params struct {
+			Target    int                     `json:"target"`
+			Candidate webrtc.ICECandidateInit `json:"candidate"`
+		}
+
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.trickled[params.Target]++
+		s.mu.Unlock()
+
+		if params.Target == sfu.TargetSubscriber {
+			return s.subscriber.AddICECandidate(params.Candidate)
+		}
+
+		return s.publisher.AddICECandidate(params.Candidate)
+	}
+
+	return nil
+}
+
+// offer offers the subscriber connection, its candidates are trickled first.
+func (s *standIn) offer() error {
+	offer, err := s.subscriber.CreateOffer()
+	if err != nil {
+		return err
+	}
+
+	s.trickle(s.subscriber, sfu.TargetSubscriber)
+
+	params, err := json.Marshal(offer)
+	if err != nil {
+		return err
+	}
+
+	s.write(rpc{Method: "offer", Params: params})
+
+	return nil
+}
+
+// trickle sends every local candidate of p once gathering is complete.
+func (s *standIn) trickle(p *transport.Peer, target int) {
+	<-webrtc.GatheringCompletePromise(p.PeerConnection())
+
+	for _, line := range strings.Split(p.PeerConnection().LocalDescription().SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=candidate:") {
+			continue
+		}
+
+		mid, index := "0", uint16(0)
+
+		params, err := json.Marshal(map[string]interface{}{
+			"target": target,
+			"candidate": webrtc.ICECandidateInit{
+				Candidate:     strings.TrimPrefix(line, "a="),
+				SDPMid:        &mid,
+				SDPMLineIndex: &index,
+			},
+		})
+		if err != nil {
+			s.t.Error(err)
+
+			return
+		}
+
+		s.write(rpc{Method: "trickle", Params: params})
+	}
+}
+
+func (s *standIn) trickledBoth() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.trickled[sfu.TargetPublisher] > 0 && s.trickled[sfu.TargetSubscriber] > 0
+}
+
+func (s *standIn) write(m rpc) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	v := map[string]interface{}{"jsonrpc": "2.0"}
+	if m.ID != nil {
+		v["id"] = *m.ID
+		v["result"] = m.Result
+	} else {
+		v["method"] = m.Method
+		v["params"] = m.Params
+	}
+
+	if err := s.conn.WriteJSON(v); err != nil {
+		s.t.Error(err)
+	}
+}
+
+//nolint:funlen
+func TestSessionJoin(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	server := newStandIn(t)
+	defer server.close()
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	conn, err := sfu.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	publisher, err := transport.NewPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer publisher.Close()
+
+	subscriber, err := transport.NewReceiveOnlyPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer subscriber.Close()
+
+	session := sfu.New(conn, "room", publisher, subscriber)
+
+	// the publisher only connects if candidates trickled before the join answer are added after it.
+	if err := session.Join(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-server.answered:
+	case <-ctx.Done():
+		t.Fatal("subscriber's answer wasn't sent")
+	}
+
+	// the subscriber only connects if candidates trickled before the offer are added after it.
+	for _, p := range []*transport.Peer{subscriber, server.subscriber} {
+		select {
+		case <-p.Connected():
+		case <-ctx.Done():
+			t.Fatal("subscriber connection wasn't established")
+		}
+	}
+
+	// local candidates which were gathered before the descriptions were sent are flushed.
+	for !server.trickledBoth() {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("stand-in didn't receive candidates of both connections")
+		}
+	}
+
+	// audio forwarded by the SFU arrives on the subscriber.
+	go func() {
+		for i := 0; i < 10; i++ {
+			_ = server.subscriber.WriteFrame([]byte{0xF8, byte(i), 0xAA})
+
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	select {
+	case track := <-subscriber.Tracks():
+		if packet := <-track.Received(); packet == nil || packet.Payload[0] != 0xF8 {
+			t.Fatalf("subscriber received %v", packet)
+		}
+	case <-ctx.Done():
+		t.Fatal("subscriber didn't receive the forwarded track")
+	}
+}
@@ -0,0 +1,152 @@
+package signaling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrUnexpectedMessage occurs when server doesn't respond to join with a welcome message.
+var ErrUnexpectedMessage = errors.New("unexpected message from signaling server")
+
+// Client is a signaling client which has joined a room.
+type Client struct {
+	conn     *websocket.Conn
+	id       string
+	room     string
+	peers    []string
+	messages chan Message
+	done     chan struct{}
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// Dial connects to the signaling server at url (i.e. ws://localhost:7000/ws) and joins room.
+func Dial(ctx context.Context, url, room string) (*Client, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to signaling server %s: %w", url, err)
+	}
+
+	c := &Client{
+		conn:     conn,
+		messages: make(chan Message, sendBufferSize),
+		done:     make(chan struct{}),
+	}
+
+	if err := c.join(ctx, room); err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	go c.readLoop()
+
+	return c, nil
+}
+
+func (c *Client) join(ctx context.Context, room string) error {
+	if err := c.Send(Message{Type: TypeJoin, Room: room}); err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.conn.SetReadDeadline(deadline)
+		defer c.conn.SetReadDeadline(time.Time{}) //nolint:errcheck
+	}
+
+	var m Message
+	if err := c.conn.ReadJSON(&m); err != nil {
+		return fmt.Errorf("failed to join room %s: %w", room, err)
+	}
+
+	switch m.Type {
+	case TypeWelcome:
+	case TypeError:
+		return fmt.Errorf("failed to join room %s: %s", room, m.Error)
+	default:
+		return fmt.Errorf("failed to join room %s: %w: %s", room, ErrUnexpectedMessage, m.Type)
+	}
+
+	c.id = m.ID
+	c.room = m.Room
+	c.peers = m.Peers
+
+	return nil
+}
+
+// ID returns the client's id in its room.
+func (c *Client) ID() string {
+	return c.id
+}
+
+// Room returns the joined room's name.
+func (c *Client) Room() string {
+	return c.room
+}
+
+// Peers returns ids of clients which were in the room when this client joined.
+func (c *Client) Peers() []string {
+	return c.peers
+}
+
+// Messages returns messages received from the server. it's closed when the connection is closed.
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// Send sends a message to the server. it's safe to call from multiple goroutines.
+func (c *Client) Send(m Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	if err := c.conn.WriteJSON(m); err != nil {
+		return fmt.Errorf("failed to send %s message: %w", m.Type, err)
+	}
+
+	return nil
+}
+
+// Close leaves the room and closes the connection.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+
+	c.writeMu.Lock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_ = c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+	c.writeMu.Unlock()
+
+	return c.conn.Close()
+}
+
+func (c *Client) readLoop() {
+	defer close(c.messages)
+
+	for {
+		var m Message
+		if err := c.conn.ReadJSON(&m); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Warnf("signaling connection is lost: %s", err)
+			}
+
+			return
+		}
+
+		select {
+		case c.messages <- m:
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package signaling implements kenny's WebSocket signaling server and client.
+//
+// Clients connect to the server's WebSocket endpoint and exchange JSON messages.
+// every message is an object with a "type" field, other fields are set depending on the type:
+//
+//	join       client -> server  {"type": "join", "room": "<room name>"}
+//	           must be the first message. a client can only be in one room.
+//	welcome    server -> client  {"type": "welcome", "id": "<client id>", "room": "<room>", "peers": ["<id>", ...]}
+//	           peers are the other clients already in the room.
+//	peer-join  server -> client  {"type": "peer-join", "from": "<id>"}
+//	peer-leave server -> client  {"type": "peer-leave", "from": "<id>"}
+//	offer      client <-> client {"type": "offer", "to": "<id>", "sdp": {"type": "offer", "sdp": "..."}}
+//	answer     client <-> client {"type": "answer", "to": "<id>", "sdp": {"type": "answer", "sdp": "..."}}
+//	candidate  client <-> client {"type": "candidate", "to": "<id>", "candidate": {"candidate": "...", "sdpMid": "0", "sdpMLineIndex": 0}}
+//	leave      client -> server  {"type": "leave"}
+//	           closing the connection has the same effect.
+//	error      server -> client  {"type": "error", "error": "<description>"}
+//
+// offer, answer and candidate messages are relayed to the client in "to", or to every other client in the room
+// if "to" is empty. the server sets "from" to the sender's id on every relayed message.
+package signaling
+
+import (
+	"errors"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// MessageType is the type of a signaling message.
+type MessageType string
+
+// Signaling message types.
+const (
+	TypeJoin      MessageType = "join"
+	TypeWelcome   MessageType = "welcome"
+	TypePeerJoin  MessageType = "peer-join"
+	TypePeerLeave MessageType = "peer-leave"
+	TypeOffer     MessageType = "offer"
+	TypeAnswer    MessageType = "answer"
+	TypeCandidate MessageType = "candidate"
+	TypeLeave     MessageType = "leave"
+	TypeError     MessageType = "error"
+)
+
+var (
+	// ErrNotJoined occurs when a client sends a message before joining a room.
+	ErrNotJoined = errors.New("join a room first")
+	// ErrAlreadyJoined occurs when a client tries to join a second room.
+	ErrAlreadyJoined = errors.New("already joined a room")
+	// ErrInvalidRoom occurs when room name is empty.
+	ErrInvalidRoom = errors.New("room name is empty")
+	// ErrUnknownType occurs on messages with an unknown or server only type.
+	ErrUnknownType = errors.New("unknown message type")
+	// ErrUnknownPeer occurs when relaying a message to a client which is not in the room.
+	ErrUnknownPeer = errors.New("peer is not in the room")
+)
+
+// Message is a signaling message. see package documentation for the fields used by each type.
+type Message struct {
+	Type      MessageType                `json:"type"`
+	ID        string                     `json:"id,omitempty"`
+	Room      string                     `json:"room,omitempty"`
+	From      string                     `json:"from,omitempty"`
+	To        string                     `json:"to,omitempty"`
+	Peers     []string                   `json:"peers,omitempty"`
+	SDP       *webrtc.SessionDescription `json:"sdp,omitempty"`
+	Candidate *webrtc.ICECandidateInit   `json:"candidate,omitempty"`
+	Error     string                     `json:"error,omitempty"`
+}
+
+// relayed reports whether m is relayed between clients.
+func (m Message) relayed() bool {
+	return m.Type == TypeOffer || m.Type == TypeAnswer || m.Type == TypeCandidate
+}
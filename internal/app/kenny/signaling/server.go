@@ -0,0 +1,288 @@
+package signaling
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	sendBufferSize = 64
+	maxMessageSize = 64 * 1024
+
+	writeTimeout = 10 * time.Second
+	pongTimeout  = 60 * time.Second
+	pingPeriod   = pongTimeout * 9 / 10
+)
+
+// Server is a signaling server which relays messages between clients in the same room.
+// it implements http.Handler and upgrades every request to a WebSocket connection.
+type Server struct {
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	rooms   map[string]map[string]*client
+	clients map[*client]struct{}
+	lastID  uint64
+}
+
+type client struct {
+	id   string
+	room string
+	conn *websocket.Conn
+	send chan Message
+	done chan struct{}
+	once sync.Once
+}
+
+// NewServer creates a signaling Server.
+func NewServer() *Server {
+	return &Server{
+		upgrader: websocket.Upgrader{
+			// clients are command line applications, not browsers.
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		rooms:   make(map[string]map[string]*client),
+		clients: make(map[*client]struct{}),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debugf("failed to upgrade connection from %s: %s", r.RemoteAddr, err)
+
+		return
+	}
+
+	s.mu.Lock()
+	s.lastID++
+	c := &client{
+		id:   strconv.FormatUint(s.lastID, 10),
+		conn: conn,
+		send: make(chan Message, sendBufferSize),
+		done: make(chan struct{}),
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	log.Debugf("client %s connected from %s", c.id, r.RemoteAddr)
+
+	go c.writeLoop()
+
+	s.readLoop(c)
+	s.leave(c)
+	c.close()
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+
+	log.Debugf("client %s disconnected", c.id)
+}
+
+// Rooms returns number of clients in each room.
+func (s *Server) Rooms() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rooms := make(map[string]int, len(s.rooms))
+	for name, members := range s.rooms {
+		rooms[name] = len(members)
+	}
+
+	return rooms
+}
+
+// Close disconnects all clients. hijacked WebSocket connections aren't closed by http.Server.Shutdown.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		c.close()
+	}
+}
+
+func (s *Server) readLoop(c *client) {
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		var m Message
+		if err := c.conn.ReadJSON(&m); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Debugf("failed to read from client %s: %s", c.id, err)
+			}
+
+			return
+		}
+
+		if m.Type == TypeLeave {
+			return
+		}
+
+		if err := s.handle(c, m); err != nil {
+			c.push(Message{Type: TypeError, Error: err.Error()})
+		}
+	}
+}
+
+func (s *Server) handle(c *client, m Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case m.Type == TypeJoin:
+		return s.join(c, m.Room)
+	case !m.relayed():
+		return ErrUnknownType
+	case c.room == "":
+		return ErrNotJoined
+	}
+
+	members := s.rooms[c.room]
+
+	relay := Message{
+		Type:      m.Type,
+		From:      c.id,
+		To:        m.To,
+		SDP:       m.SDP,
+		Candidate: m.Candidate,
+	}
+
+	if m.To != "" {
+		peer, ok := members[m.To]
+		if !ok || peer == c {
+			return ErrUnknownPeer
+		}
+
+		peer.push(relay)
+
+		return nil
+	}
+
+	for _, peer := range members {
+		if peer != c {
+			peer.push(relay)
+		}
+	}
+
+	return nil
+}
+
+// join adds c to room. s.mu must be held.
+func (s *Server) join(c *client, room string) error {
+	if room == "" {
+		return ErrInvalidRoom
+	}
+
+	if c.room != "" {
+		return ErrAlreadyJoined
+	}
+
+	members, ok := s.rooms[room]
+	if !ok {
+		members = make(map[string]*client)
+		s.rooms[room] = members
+	}
+
+	peers := make([]string, 0, len(members))
+
+	for id, peer := range members {
+		peers = append(peers, id)
+		peer.push(Message{Type: TypePeerJoin, From: c.id})
+	}
+
+	c.room = room
+	members[c.id] = c
+
+	c.push(Message{Type: TypeWelcome, ID: c.id, Room: room, Peers: peers})
+
+	log.Infof("client %s joined room %s with %d peers", c.id, room, len(peers))
+
+	return nil
+}
+
+func (s *Server) leave(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.room == "" {
+		return
+	}
+
+	members := s.rooms[c.room]
+	delete(members, c.id)
+
+	for _, peer := range members {
+		peer.push(Message{Type: TypePeerLeave, From: c.id})
+	}
+
+	if len(members) == 0 {
+		delete(s.rooms, c.room)
+	}
+
+	log.Infof("client %s left room %s", c.id, c.room)
+
+	c.room = ""
+}
+
+// push queues m to be sent to the client. a client which can't keep up is disconnected.
+func (c *client) push(m Message) {
+	select {
+	case c.send <- m:
+	case <-c.done:
+	default:
+		log.Warnf("client %s is too slow, disconnecting", c.id)
+		c.close()
+	}
+}
+
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case m := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+			if err := c.conn.WriteJSON(m); err != nil {
+				c.close()
+
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				c.close()
+
+				return
+			}
+		case <-c.done:
+			_ = c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+
+			return
+		}
+	}
+}
+
+// close stops the write loop and closes the connection, which makes the read loop return.
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		// give the write loop a chance to send the close frame before closing.
+		time.AfterFunc(writeTimeout/10, func() { //nolint:gomnd
+			_ = c.conn.Close()
+		})
+	})
+}
@@ -0,0 +1,189 @@
+package signaling_test
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
+	"github.com/smf8/kenny/internal/app/kenny/signaling"
+)
+
+// client speaks the documented message schema over a plain WebSocket connection.
+type client struct {
+	t    *testing.T
+	conn *websocket.Conn
+	id   string
+}
+
+// join connects to the server and joins room, it returns the client and its welcome message.
+func join(t *testing.T, url, room string) (*client, signaling.Message) {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &client{t: t, conn: conn}
+	c.send(signaling.Message{Type: signaling.TypeJoin, Room: room})
+
+	welcome := c.receive(signaling.TypeWelcome)
+	c.id = welcome.ID
+
+	return c, welcome
+}
+
+func (c *client) send(m signaling.Message) {
+	c.t.Helper()
+
+	if err := c.conn.WriteJSON(m); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive returns the client's next message, which must have the given type.
+func (c *client) receive(want signaling.MessageType) signaling.Message {
+	c.t.Helper()
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var m signaling.Message
+	if err := c.conn.ReadJSON(&m); err != nil {
+		c.t.Fatalf("client %s didn't receive %s: %s", c.id, want, err)
+	}
+
+	if m.Type != want {
+		c.t.Fatalf("client %s received %+v, want %s", c.id, m, want)
+	}
+
+	return m
+}
+
+func (c *client) close() {
+	_ = c.conn.Close()
+}
+
+//nolint:funlen
+func TestServerRelaysNegotiation(t *testing.T) {
+	server := signaling.NewServer()
+	ts := httptest.NewServer(server)
+
+	defer ts.Close()
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	caller, welcome := join(t, url, "room")
+	defer caller.close()
+
+	if len(welcome.Peers) != 0 || welcome.Room != "room" {
+		t.Fatalf("first client joined %s with peers %v", welcome.Room, welcome.Peers)
+	}
+
+	callee, welcome := join(t, url, "room")
+	defer callee.close()
+
+	if !reflect.DeepEqual(welcome.Peers, []string{caller.id}) {
+		t.Fatalf("second client has peers %v, want %s", welcome.Peers, caller.id)
+	}
+
+	if m := caller.receive(signaling.TypePeerJoin); m.From != callee.id {
+		t.Fatalf("peer-join is from %s, want %s", m.From, callee.id)
+	}
+
+	// a client in another room doesn't receive broadcasts.
+	other, _ := join(t, url, "other")
+	defer other.close()
+
+	offer := &webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "v=0 offer"}
+	callee.send(signaling.Message{Type: signaling.TypeOffer, To: caller.id, SDP: offer})
+
+	if m := caller.receive(signaling.TypeOffer); m.From != callee.id || !reflect.DeepEqual(m.SDP, offer) {
+		t.Fatalf("caller received offer %+v", m)
+	}
+
+	answer := &webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "v=0 answer"}
+	caller.send(signaling.Message{Type: signaling.TypeAnswer, To: callee.id, SDP: answer})
+
+	if m := callee.receive(signaling.TypeAnswer); m.From != caller.id || !reflect.DeepEqual(m.SDP, answer) {
+		t.Fatalf("callee received answer %+v", m)
+	}
+
+	// candidates are trickled both ways, an empty "to" is relayed to everyone else in the room.
+	mid, index := "0", uint16(0)
+
+	for _, c := range []struct{ from, to *client }{{caller, callee}, {callee, caller}} {
+		candidate := &webrtc.ICECandidateInit{
+			Candidate:     "candidate:1 1 udp 2130706431 127.0.0.1 5000 typ host from " + c.from.id,
+			SDPMid:        &mid,
+			SDPMLineIndex: &index,
+		}
+
+		c.from.send(signaling.Message{Type: signaling.TypeCandidate, Candidate: candidate})
+
+		m := c.to.receive(signaling.TypeCandidate)
+		if m.From != c.from.id || !reflect.DeepEqual(m.Candidate, candidate) {
+			t.Fatalf("client %s received candidate %+v", c.to.id, m)
+		}
+	}
+
+	caller.send(signaling.Message{Type: signaling.TypeOffer, To: other.id, SDP: offer})
+
+	if m := caller.receive(signaling.TypeError); m.Error != signaling.ErrUnknownPeer.Error() {
+		t.Fatalf("relaying to another room returned %q", m.Error)
+	}
+
+	if rooms := server.Rooms(); rooms["room"] != 2 || rooms["other"] != 1 {
+		t.Fatalf("server has rooms %v", rooms)
+	}
+
+	callee.send(signaling.Message{Type: signaling.TypeLeave})
+
+	if m := caller.receive(signaling.TypePeerLeave); m.From != callee.id {
+		t.Fatalf("peer-leave is from %s, want %s", m.From, callee.id)
+	}
+
+	// nothing was sent to the other room, its next message is a reply to its own mistake.
+	other.send(signaling.Message{Type: signaling.TypeAnswer, To: caller.id, SDP: answer})
+
+	if m := other.receive(signaling.TypeError); m.Error != signaling.ErrUnknownPeer.Error() {
+		t.Fatalf("relaying to another room returned %q", m.Error)
+	}
+}
+
+func TestServerRejectsInvalidJoin(t *testing.T) {
+	server := signaling.NewServer()
+	ts := httptest.NewServer(server)
+
+	defer ts.Close()
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	c, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joiner := &client{t: t, conn: c}
+	defer joiner.close()
+
+	for _, tc := range []struct {
+		message signaling.Message
+		err     error
+	}{
+		{signaling.Message{Type: signaling.TypeOffer}, signaling.ErrNotJoined},
+		{signaling.Message{Type: signaling.TypeJoin}, signaling.ErrInvalidRoom},
+		{signaling.Message{Type: signaling.TypeWelcome}, signaling.ErrUnknownType},
+	} {
+		joiner.send(tc.message)
+
+		if m := joiner.receive(signaling.TypeError); m.Error != tc.err.Error() {
+			t.Fatalf("%s returned %q, want %q", tc.message.Type, m.Error, tc.err)
+		}
+	}
+}
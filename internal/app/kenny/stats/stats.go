@@ -0,0 +1,300 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/smf8/kenny/internal/app/kenny/dsp"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/jitter"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+const (
+	// DefaultInterval is the default refresh interval of stats.
+	DefaultInterval = time.Second
+
+	// meterInterval is the refresh interval of level meters on the stats line, they're too slow on stats interval.
+	meterInterval = 100 * time.Millisecond
+	// meterRange is the range of level meter bars, in dBFS.
+	meterRange = 60
+	// meterWidth is number of characters in a level meter bar.
+	meterWidth = 12
+)
+
+// Level is peak and RMS level of an audio stream, in dBFS.
+type Level struct {
+	Peak float64 `json:"peak_dbfs"`
+	RMS  float64 `json:"rms_dbfs"`
+}
+
+// String formats l as a bar graph, i.e. `[#####---|---] -24 dB`. the bar is filled up to RMS level and
+// the held peak is marked with `|`.
+func (l Level) String() string {
+	position := func(level float64) int {
+		n := int((level + meterRange) * meterWidth / meterRange)
+		if n < 0 {
+			return 0
+		}
+
+		if n > meterWidth {
+			return meterWidth
+		}
+
+		return n
+	}
+
+	rms, peak := position(l.RMS), position(l.Peak)
+	bar := []byte(strings.Repeat("#", rms) + strings.Repeat("-", meterWidth-rms))
+
+	if peak > 0 {
+		bar[peak-1] = '|'
+	}
+
+	return fmt.Sprintf("[%s] %3.0f dB", bar, l.RMS)
+}
+
+// Report is a single sample of call statistics. rates and loss percentages are calculated over the last interval.
+type Report struct {
+	Time              time.Time `json:"time"`
+	PacketsSent       uint64    `json:"packets_sent"`
+	PacketsReceived   uint64    `json:"packets_received"`
+	PacketsLost       int64     `json:"packets_lost"`
+	LossPercent       float64   `json:"loss_percent"`
+	RemoteLossPercent float64   `json:"remote_loss_percent"`
+	JitterMs          float64   `json:"jitter_ms"`
+	RemoteJitterMs    float64   `json:"remote_jitter_ms"`
+	RTTMs             float64   `json:"rtt_ms"`
+	SendKbps          float64   `json:"send_kbps"`
+	ReceiveKbps       float64   `json:"receive_kbps"`
+	FrameSizeMs       float64   `json:"frame_size_ms"`
+	PlayoutPackets    int       `json:"playout_packets"`
+	PlayoutDelayMs    float64   `json:"playout_delay_ms"`
+	LatePackets       uint64    `json:"late_packets"`
+	DiscardedPackets  uint64    `json:"discarded_packets"`
+	FramesDecoded     uint64    `json:"frames_decoded"`
+	FramesConcealed   uint64    `json:"frames_concealed"`
+	FramesRecovered   uint64    `json:"frames_recovered"`
+	FramesComfort     uint64    `json:"frames_comfort"`
+	FramesSkipped     uint64    `json:"frames_skipped"`
+	Speaking          bool      `json:"speaking"`
+	Muted             bool      `json:"muted"`
+	Input             *Level    `json:"input,omitempty"`
+	Output            *Level    `json:"output,omitempty"`
+	AGCGainDB         float64   `json:"agc_gain_db"`
+}
+
+// String formats r as a single stats line.
+func (r Report) String() string {
+	speaking := "silent"
+
+	switch {
+	case r.Muted:
+		speaking = "muted"
+	case r.Speaking:
+		speaking = "speaking"
+	}
+
+	var levels string
+
+	if r.Input != nil {
+		levels += fmt.Sprintf("mic %s | ", r.Input)
+	}
+
+	if r.Output != nil {
+		levels += fmt.Sprintf("spk %s | ", r.Output)
+	}
+
+	return levels + fmt.Sprintf("%s | sent %d pkts %.1f kbps dtx %d | "+
+		"recv %d pkts %.1f kbps loss %.1f%% (remote %.1f%%) | jitter %.1f ms | rtt %.0f ms | frame %.1f ms | "+
+		"playout %d pkts %.0f ms late %d discarded %d | plc %d fec %d cng %d",
+		speaking, r.PacketsSent, r.SendKbps, r.FramesSkipped,
+		r.PacketsReceived, r.ReceiveKbps, r.LossPercent, r.RemoteLossPercent, r.JitterMs, r.RTTMs, r.FrameSizeMs,
+		r.PlayoutPackets, r.PlayoutDelayMs, r.LatePackets, r.DiscardedPackets, r.FramesConcealed, r.FramesRecovered,
+		r.FramesComfort)
+}
+
+// Reporter periodically samples transport and decoder counters and writes reports.
+type Reporter struct {
+	transport func() transport.Stats
+	decoder   func() encoding.DecoderStats
+	interval  time.Duration
+
+	// Line receives a refreshed stats line on every interval, i.e. os.Stderr. nil disables it.
+	Line io.Writer
+	// JSON receives every report as a single line of JSON. nil disables it.
+	JSON io.Writer
+	// Playout returns counters of the jitter buffers which received packets wait in before decoding. it can be nil.
+	Playout func() jitter.Stats
+	// VAD is the voice activity detector of outgoing audio. it can be nil.
+	VAD *dsp.VAD
+	// Input and Output are level meters of recorded and played audio, they're refreshed on Line more often than
+	// other stats. they can be nil.
+	Input  *dsp.Meter
+	Output *dsp.Meter
+	// AGC is the automatic gain control of recorded audio. it can be nil.
+	AGC *dsp.AGC
+	// Mute reports whether recorded audio is muted, including push to talk. it can be nil.
+	Mute *dsp.Mute
+
+	last     transport.Stats
+	lastTime time.Time
+}
+
+// NewReporter creates a Reporter which samples transportStats and decoderStats every interval.
+// decoderStats can be nil, i.e. encoding.Decoder.Stats of the only remote stream.
+func NewReporter(interval time.Duration, transportStats func() transport.Stats,
+	decoderStats func() encoding.DecoderStats) *Reporter {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Reporter{
+		transport: transportStats,
+		decoder:   decoderStats,
+		interval:  interval,
+	}
+}
+
+// Report samples the counters and calculates a report over the time passed since the previous call.
+func (r *Reporter) Report(now time.Time) Report {
+	s := r.transport()
+
+	report := Report{
+		Time:              now,
+		PacketsSent:       s.PacketsSent,
+		FramesSkipped:     s.FramesSkipped,
+		PacketsReceived:   s.PacketsReceived,
+		PacketsLost:       s.PacketsLost,
+		RemoteLossPercent: s.RemoteFractionLost * 100, //nolint:gomnd
+		JitterMs:          milliseconds(s.Jitter),
+		RemoteJitterMs:    milliseconds(s.RemoteJitter),
+		RTTMs:             milliseconds(s.RTT),
+		FrameSizeMs:       milliseconds(s.FrameDuration),
+		PlayoutPackets:    s.Queued,
+	}
+
+	if r.decoder != nil {
+		decoder := r.decoder()
+
+		report.FramesDecoded = decoder.Frames
+		report.FramesConcealed = decoder.Concealed
+		report.FramesRecovered = decoder.Recovered
+		report.FramesComfort = decoder.Comfort
+	}
+
+	if r.VAD != nil {
+		report.Speaking = r.VAD.Speaking()
+	}
+
+	if r.Mute != nil {
+		report.Muted = !r.Mute.Open()
+	}
+
+	r.levels(&report)
+
+	if r.Playout != nil {
+		playout := r.Playout()
+
+		report.PlayoutPackets += playout.Queued
+		report.PlayoutDelayMs = milliseconds(playout.TargetDelay)
+		report.LatePackets = playout.Late
+		report.DiscardedPackets = playout.Discarded
+	}
+
+	if !r.lastTime.IsZero() {
+		elapsed := now.Sub(r.lastTime).Seconds()
+
+		if elapsed > 0 {
+			report.SendKbps = float64(s.BytesSent-r.last.BytesSent) * 8 / elapsed / 1000            //nolint:gomnd
+			report.ReceiveKbps = float64(s.BytesReceived-r.last.BytesReceived) * 8 / elapsed / 1000 //nolint:gomnd
+		}
+
+		received := int64(s.PacketsReceived - r.last.PacketsReceived)
+		lost := s.PacketsLost - r.last.PacketsLost
+
+		if expected := received + lost; expected > 0 && lost > 0 {
+			report.LossPercent = float64(lost) * 100 / float64(expected) //nolint:gomnd
+		}
+	}
+
+	r.last = s
+	r.lastTime = now
+
+	return report
+}
+
+// levels updates meter levels and AGC gain of report.
+func (r *Reporter) levels(report *Report) {
+	if r.Input != nil {
+		peak, rms := r.Input.Levels()
+		report.Input = &Level{Peak: peak, RMS: rms}
+	}
+
+	if r.Output != nil {
+		peak, rms := r.Output.Levels()
+		report.Output = &Level{Peak: peak, RMS: rms}
+	}
+
+	if r.AGC != nil {
+		report.AGCGainDB = r.AGC.Gain()
+	}
+}
+
+// Run writes a report every interval until ctx is done.
+func (r *Reporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var encoder *json.Encoder
+	if r.JSON != nil {
+		encoder = json.NewEncoder(r.JSON)
+	}
+
+	// meters redraw the last report with new levels in between reports.
+	var meters <-chan time.Time
+
+	if r.Line != nil && (r.Input != nil || r.Output != nil) {
+		meterTicker := time.NewTicker(meterInterval)
+		defer meterTicker.Stop()
+
+		meters = meterTicker.C
+	}
+
+	var report Report
+
+	for {
+		select {
+		case now := <-ticker.C:
+			report = r.Report(now)
+
+			if r.Line != nil {
+				// carriage return and erase line, so the line is refreshed in place.
+				fmt.Fprintf(r.Line, "\r\033[K%s", report)
+			}
+
+			if encoder != nil {
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to write stats: %w", err)
+				}
+			}
+		case <-meters:
+			r.levels(&report)
+			fmt.Fprintf(r.Line, "\r\033[K%s", report)
+		case <-ctx.Done():
+			if r.Line != nil {
+				fmt.Fprintln(r.Line)
+			}
+
+			return nil
+		}
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,100 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/stats"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+func TestReporterReport(t *testing.T) {
+	samples := []transport.Stats{
+		{PacketsReceived: 100, BytesReceived: 10000, PacketsSent: 50, BytesSent: 5000, PacketsLost: 5},
+		{
+			PacketsReceived:    190,
+			BytesReceived:      20000,
+			PacketsSent:        100,
+			BytesSent:          7500,
+			PacketsLost:        15,
+			FramesSkipped:      3,
+			Jitter:             2500 * time.Microsecond,
+			RemoteFractionLost: 0.25,
+			RTT:                40 * time.Millisecond,
+			FrameDuration:      20 * time.Millisecond,
+		},
+	}
+
+	var i int
+
+	r := stats.NewReporter(0, func() transport.Stats {
+		s := samples[i]
+		i++
+
+		return s
+	}, func() encoding.DecoderStats {
+		return encoding.DecoderStats{Frames: 200, Concealed: 4, Recovered: 2, Comfort: 6}
+	})
+
+	start := time.Now()
+
+	if first := r.Report(start); first.LossPercent != 0 || first.ReceiveKbps != 0 {
+		t.Fatalf("first report has rates without a previous report: %+v", first)
+	}
+
+	report := r.Report(start.Add(2 * time.Second))
+
+	// 90 packets arrived and 10 were lost since the first report.
+	if report.LossPercent != 10 {
+		t.Fatalf("loss is %.2f%%, want 10%%", report.LossPercent)
+	}
+
+	if report.ReceiveKbps != 40 || report.SendKbps != 10 {
+		t.Fatalf("rates are %.1f kbps received and %.1f kbps sent, want 40 and 10", report.ReceiveKbps, report.SendKbps)
+	}
+
+	if report.JitterMs != 2.5 || report.RTTMs != 40 || report.FrameSizeMs != 20 || report.RemoteLossPercent != 25 {
+		t.Fatalf("report: %+v", report)
+	}
+
+	if report.FramesSkipped != 3 || report.FramesConcealed != 4 || report.FramesRecovered != 2 ||
+		report.FramesComfort != 6 {
+		t.Fatalf("frame counters: %+v", report)
+	}
+
+	line := report.String()
+	for _, want := range []string{"dtx 3", "loss 10.0%", "jitter 2.5 ms", "plc 4 fec 2 cng 6"} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("stats line %q doesn't contain %q", line, want)
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	for field, want := range map[string]float64{
+		"loss_percent":   10,
+		"jitter_ms":      2.5,
+		"frames_skipped": 3,
+		"frames_comfort": 6,
+	} {
+		if fields[field] != want {
+			t.Fatalf("json field %s is %v, want %v", field, fields[field], want)
+		}
+	}
+
+	// meters are only reported when they're set.
+	if _, ok := fields["input"]; ok {
+		t.Fatal("json has an input level without an input meter")
+	}
+}
@@ -0,0 +1,112 @@
+package terminal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Key represents a key press. printable keys are represented by their rune,
+// special keys use negative values.
+type Key rune
+
+// Special keys.
+const (
+	KeyUp Key = -(iota + 1)
+	KeyDown
+	KeyRight
+	KeyLeft
+	// KeyInterrupt is Ctrl-C. in raw mode it doesn't raise SIGINT, so it's reported as a key.
+	KeyInterrupt
+	KeyEscape
+)
+
+const (
+	ctrlC  = 0x03
+	escape = 0x1b
+)
+
+// ErrNotTerminal occurs when stdin is not a terminal, i.e. it's piped.
+var ErrNotTerminal = errors.New("stdin is not a terminal")
+
+// Keys switches stdin into raw mode and sends every key press on the returned channel until ctx is done.
+// call restore to put the terminal back into its previous state.
+func Keys(ctx context.Context) (keys <-chan Key, restore func(), err error) {
+	fd := int(os.Stdin.Fd())
+
+	if !term.IsTerminal(fd) {
+		return nil, nil, ErrNotTerminal
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to put terminal into raw mode: %w", err)
+	}
+
+	restore = func() {
+		_ = term.Restore(fd, state)
+	}
+
+	ch := make(chan Key)
+
+	go readKeys(ctx, bufio.NewReader(os.Stdin), ch)
+
+	return ch, restore, nil
+}
+
+// readKeys decodes bytes of r into keys. the reading goroutine may outlive ctx
+// because reading from stdin can't be interrupted.
+func readKeys(ctx context.Context, r *bufio.Reader, ch chan<- Key) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		key := Key(b)
+
+		switch b {
+		case ctrlC:
+			key = KeyInterrupt
+		case escape:
+			key = readEscape(r)
+		}
+
+		select {
+		case ch <- key:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readEscape decodes arrow keys ANSI escape sequences (ESC [ A..D).
+func readEscape(r *bufio.Reader) Key {
+	if r.Buffered() < 2 {
+		return KeyEscape
+	}
+
+	if b, _ := r.Peek(1); b[0] != '[' {
+		return KeyEscape
+	}
+
+	_, _ = r.ReadByte()
+	b, _ := r.ReadByte()
+
+	switch b {
+	case 'A':
+		return KeyUp
+	case 'B':
+		return KeyDown
+	case 'C':
+		return KeyRight
+	case 'D':
+		return KeyLeft
+	}
+
+	return KeyEscape
+}
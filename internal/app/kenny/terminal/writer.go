@@ -0,0 +1,26 @@
+package terminal
+
+import (
+	"bytes"
+	"io"
+)
+
+// lineWriter converts line feeds into carriage return and line feed.
+type lineWriter struct {
+	w io.Writer
+}
+
+// NewLineWriter returns a writer which converts `\n` into `\r\n`, so lines written in raw mode, i.e. logs,
+// start at the beginning of the line. it doesn't change the output in normal mode.
+func NewLineWriter(w io.Writer) io.Writer {
+	return lineWriter{w: w}
+}
+
+// Write implements io.Writer. it reports length of p on success, not of the converted data.
+func (l lineWriter) Write(p []byte) (int, error) {
+	if _, err := l.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+)
+
+const (
+	rtpVersion = 2
+	// maxDTXPacketSize is the maximum size of opus packets which only signal DTX silence.
+	maxDTXPacketSize = 2
+)
+
+// Packetizer wraps opus frames into RTP packets. (RFC 7587)
+// each frame is sent in its own packet and timestamps advance by frame duration in 48 kHz clock.
+type Packetizer struct {
+	// PayloadType is the RTP payload type of opus, it's negotiated by the session description.
+	PayloadType uint8
+
+	sequence  uint16
+	timestamp uint32
+	// talkspurt is set when the next packet starts a talkspurt, i.e. the first packet or the first after DTX.
+	talkspurt bool
+}
+
+// NewPacketizer creates a Packetizer with random initial sequence number and timestamp. (RFC 3550 section 5.1)
+func NewPacketizer() *Packetizer {
+	r := rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec
+
+	return &Packetizer{
+		PayloadType: OpusPayloadType,
+		sequence:    uint16(r.Uint32()),
+		timestamp:   r.Uint32(),
+		talkspurt:   true,
+	}
+}
+
+// Packetize creates the next RTP packet for frame. SSRC is left for the transport to fill.
+// opus DTX frames (not more than 2 bytes) aren't sent, so it returns nil for them. their duration still
+// advances the timestamp, and the next packet has the marker bit as the start of a talkspurt. (RFC 7587 section 3.3)
+func (p *Packetizer) Packetize(frame []byte) (*rtp.Packet, error) {
+	samples, err := encoding.PacketSamples(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(frame) <= maxDTXPacketSize {
+		p.timestamp += uint32(samples)
+		p.talkspurt = true
+
+		return nil, nil
+	}
+
+	packet := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			PayloadType:    p.PayloadType,
+			SequenceNumber: p.sequence,
+			Timestamp:      p.timestamp,
+			Marker:         p.talkspurt,
+		},
+		Payload: frame,
+	}
+
+	p.talkspurt = false
+	p.sequence++
+	p.timestamp += uint32(samples)
+
+	return packet, nil
+}
@@ -0,0 +1,127 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pion/sdp/v3"
+)
+
+var (
+	// ErrNoAudio occurs when a session description doesn't have an audio media.
+	ErrNoAudio = errors.New("session description doesn't have audio media")
+	// ErrNoOpus occurs when a session description's audio media doesn't offer opus.
+	ErrNoOpus = errors.New("session description doesn't offer opus")
+	// ErrNoAddress occurs when a session description doesn't have a connection address.
+	ErrNoAddress = errors.New("session description doesn't have a connection address")
+)
+
+// RTPDescription describes one side of a plain RTP opus session. it's written to and read from SDP files,
+// so the session can be shared with other tools, i.e. `ffplay -protocol_whitelist file,udp,rtp session.sdp`.
+type RTPDescription struct {
+	// Addr is the address which RTP packets should be sent to. RTCP uses the next port.
+	Addr        *net.UDPAddr
+	PayloadType uint8
+}
+
+// Marshal creates an SDP session description with a single opus audio media. (RFC 7587 section 7)
+func (d RTPDescription) Marshal() ([]byte, error) {
+	addressType := "IP4"
+	if d.Addr.IP.To4() == nil {
+		addressType = "IP6"
+	}
+
+	address := d.Addr.IP.String()
+	connection := &sdp.ConnectionInformation{
+		NetworkType: "IN",
+		AddressType: addressType,
+		Address:     &sdp.Address{Address: address},
+	}
+
+	media := &sdp.MediaDescription{
+		MediaName: sdp.MediaName{
+			Media:  "audio",
+			Port:   sdp.RangedPort{Value: d.Addr.Port},
+			Protos: []string{"RTP", "AVP"},
+		},
+	}
+
+	media.WithCodec(d.PayloadType, "opus", OpusClockRate, 2, "minptime=10;useinbandfec=1").
+		WithPropertyAttribute(sdp.AttrKeySendRecv)
+
+	description := &sdp.SessionDescription{
+		Origin: sdp.Origin{
+			Username:       "-",
+			SessionID:      uint64(time.Now().Unix()),
+			SessionVersion: uint64(time.Now().Unix()),
+			NetworkType:    "IN",
+			AddressType:    addressType,
+			UnicastAddress: address,
+		},
+		SessionName:           "kenny",
+		ConnectionInformation: connection,
+		TimeDescriptions:      []sdp.TimeDescription{{}},
+		MediaDescriptions:     []*sdp.MediaDescription{media},
+	}
+
+	data, err := description.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal session description: %w", err)
+	}
+
+	return data, nil
+}
+
+// UnmarshalRTPDescription reads the first audio media of an SDP session description which offers opus.
+func UnmarshalRTPDescription(data []byte) (RTPDescription, error) {
+	var description sdp.SessionDescription
+	if err := description.Unmarshal(data); err != nil {
+		return RTPDescription{}, fmt.Errorf("failed to parse session description: %w", err)
+	}
+
+	payloadType, err := description.GetPayloadTypeForCodec(sdp.Codec{Name: "opus"})
+	if err != nil {
+		return RTPDescription{}, ErrNoOpus
+	}
+
+	for _, media := range description.MediaDescriptions {
+		if media.MediaName.Media != "audio" || !offers(media, payloadType) {
+			continue
+		}
+
+		// media level connection overrides session level one.
+		connection := description.ConnectionInformation
+		if media.ConnectionInformation != nil {
+			connection = media.ConnectionInformation
+		}
+
+		if connection == nil || connection.Address == nil {
+			return RTPDescription{}, ErrNoAddress
+		}
+
+		ip := net.ParseIP(connection.Address.Address)
+		if ip == nil {
+			return RTPDescription{}, fmt.Errorf("%w: %s", ErrNoAddress, connection.Address.Address)
+		}
+
+		return RTPDescription{
+			Addr:        &net.UDPAddr{IP: ip, Port: media.MediaName.Port.Value},
+			PayloadType: payloadType,
+		}, nil
+	}
+
+	return RTPDescription{}, ErrNoAudio
+}
+
+func offers(media *sdp.MediaDescription, payloadType uint8) bool {
+	for _, format := range media.MediaName.Formats {
+		if format == strconv.Itoa(int(payloadType)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/jitter"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+)
+
+// FrameWriter sends opus frames to a remote peer.
+type FrameWriter interface {
+	WriteFrame(frame []byte) error
+}
+
+// PacketReceiver provides RTP packets received from a remote peer, i.e. a RemoteTrack.
+// the channel is closed when the remote track or peer is gone.
+type PacketReceiver interface {
+	Received() <-chan *rtp.Packet
+}
+
+// Sender is a pipeline stage which sends opus packets to the remote peer.
+type Sender struct {
+	writer FrameWriter
+	in     pipeline.PacketPort
+}
+
+// NewSender creates a Sender stage.
+func NewSender(writer FrameWriter, in pipeline.PacketPort) *Sender {
+	return &Sender{
+		writer: writer,
+		in:     in,
+	}
+}
+
+// Name implements pipeline.Stage.
+func (s *Sender) Name() string {
+	return "send"
+}
+
+// Run implements pipeline.Stage. it returns when input port is closed.
+func (s *Sender) Run(ctx context.Context) error {
+	for {
+		select {
+		case frame, ok := <-s.in:
+			if !ok {
+				return nil
+			}
+
+			if err := s.writer.WriteFrame(frame); err != nil {
+				if errors.Is(err, ErrPeerClosed) {
+					return nil
+				}
+
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// playoutInterval is how often Receiver checks the jitter buffer for due packets.
+const playoutInterval = 5 * time.Millisecond
+
+// Receiver is a pipeline stage which passes received RTP packets through a jitter buffer
+// and outputs their payloads at playout time. lost packets are sent as frames without packet.
+type Receiver struct {
+	receiver PacketReceiver
+	buffer   *jitter.Buffer
+	out      chan pipeline.Frame
+
+	// frameDuration is the duration of the last received packet.
+	frameDuration time.Duration
+	// playedUntil is the time which the sent frames have covered.
+	playedUntil time.Time
+}
+
+// NewReceiver creates a Receiver stage.
+func NewReceiver(receiver PacketReceiver, buffer *jitter.Buffer) *Receiver {
+	return &Receiver{
+		receiver: receiver,
+		buffer:   buffer,
+		out:      make(chan pipeline.Frame),
+	}
+}
+
+// Name implements pipeline.Stage.
+func (r *Receiver) Name() string {
+	return "receive"
+}
+
+// Out returns the received opus frame port.
+func (r *Receiver) Out() pipeline.FramePort {
+	return r.out
+}
+
+// Run implements pipeline.Stage. it returns when the remote peer is gone.
+func (r *Receiver) Run(ctx context.Context) error {
+	defer close(r.out)
+
+	ticker := time.NewTicker(playoutInterval)
+	defer ticker.Stop()
+
+	received := r.receiver.Received()
+
+	for {
+		select {
+		case packet, ok := <-received:
+			if !ok {
+				return nil
+			}
+
+			r.buffer.Push(packet, time.Now())
+		case now := <-ticker.C:
+			if err := r.playout(ctx, now); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// playout sends every due packet. a lost packet carries the next one if it's available for FEC.
+// when no packet is due for more than a frame, i.e. remote peer is silent with DTX, a gap frame is sent.
+// packets without payload are DTX frames too, so they're sent as gap frames instead of being concealed.
+func (r *Receiver) playout(ctx context.Context, now time.Time) error {
+	for {
+		packet, lost, ok := r.buffer.Pop(now)
+		if !ok {
+			break
+		}
+
+		var frame pipeline.Frame
+
+		switch {
+		case !lost && len(packet.Payload) > 0:
+			frame.Packet = packet.Payload
+		case !lost:
+			frame.Gap = true
+		case lost:
+			if next := r.buffer.Next(); next != nil && len(next.Payload) > 0 {
+				frame.FEC = next.Payload
+			}
+		}
+
+		if samples, err := encoding.PacketSamples(frame.Packet); err == nil {
+			r.frameDuration = time.Duration(samples) * time.Second / OpusClockRate
+		}
+
+		if err := r.send(ctx, now, frame); err != nil {
+			return err
+		}
+	}
+
+	if r.frameDuration > 0 && now.Sub(r.playedUntil) > r.frameDuration {
+		return r.send(ctx, now, pipeline.Frame{Gap: true})
+	}
+
+	return nil
+}
+
+func (r *Receiver) send(ctx context.Context, now time.Time, frame pipeline.Frame) error {
+	if r.playedUntil.Before(now) {
+		r.playedUntil = now
+	}
+
+	r.playedUntil = r.playedUntil.Add(r.frameDuration)
+
+	select {
+	case r.out <- frame:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
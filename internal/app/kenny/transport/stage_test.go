@@ -0,0 +1,169 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+	"github.com/smf8/kenny/internal/app/kenny/jitter"
+	"github.com/smf8/kenny/internal/app/kenny/pipeline"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+type packetReceiver chan *rtp.Packet
+
+func (r packetReceiver) Received() <-chan *rtp.Packet {
+	return r
+}
+
+// rtpPacket returns the i-th packet of a 20 ms stream with the given payload.
+func rtpPacket(i int, payload []byte) *rtp.Packet {
+	return &rtp.Packet{
+		Header: rtp.Header{
+			PayloadType:    transport.OpusPayloadType,
+			SequenceNumber: uint16(i),
+			Timestamp:      uint32(i * 960),
+		},
+		Payload: payload,
+	}
+}
+
+// playout passes packets through a Receiver and returns its frames until the frame of the last packet.
+func playout(t *testing.T, packets ...*rtp.Packet) []pipeline.Frame {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(packetReceiver, len(packets))
+	for _, packet := range packets {
+		received <- packet
+	}
+
+	r := transport.NewReceiver(received, jitter.New(transport.OpusClockRate, 20*time.Millisecond, time.Second))
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- r.Run(ctx)
+	}()
+
+	last := packets[len(packets)-1].Payload
+
+	var frames []pipeline.Frame
+
+	for {
+		select {
+		case frame := <-r.Out():
+			frames = append(frames, frame)
+
+			if bytes.Equal(frame.Packet, last) {
+				cancel()
+				<-errs
+
+				return frames
+			}
+		case err := <-errs:
+			t.Fatalf("receiver stopped: %v", err)
+		case <-ctx.Done():
+			t.Fatalf("last packet wasn't played, frames: %+v", frames)
+		}
+	}
+}
+
+func TestReceiverPlaysEmptyPayloadAsGap(t *testing.T) {
+	frames := playout(t, rtpPacket(0, opusFrame(0)), rtpPacket(1, nil), rtpPacket(2, opusFrame(2)))
+
+	var gaps int
+
+	for i, frame := range frames {
+		switch {
+		case frame.Gap:
+			gaps++
+		case frame.Packet == nil:
+			t.Fatalf("frame %d is concealed as a lost packet: %+v", i, frame)
+		}
+	}
+
+	if gaps == 0 {
+		t.Fatalf("packet without payload wasn't played as a gap, frames: %+v", frames)
+	}
+
+	if !bytes.Equal(frames[0].Packet, opusFrame(0)) {
+		t.Fatalf("first frame is %+v, want packet 0", frames[0])
+	}
+}
+
+func TestReceiverRecoversLostPackets(t *testing.T) {
+	e, err := encoding.NewEncoder(48000, 1, 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcm := make([]int16, 960)
+	payloads := make([][]byte, 4)
+
+	for i := range payloads {
+		for j := range pcm {
+			pcm[j] = int16(8000 * math.Sin(2*math.Pi*440*float64(i*960+j)/48000))
+		}
+
+		if err := e.Write(pcm); err != nil {
+			t.Fatal(err)
+		}
+
+		payload, ok := e.Buffer.TryRead()
+		if !ok {
+			t.Fatalf("frame %d wasn't encoded", i)
+		}
+
+		payloads[i] = payload
+	}
+
+	// packets 1 and 2 are dropped. packet 1 is concealed, since packet 2 isn't there to carry its FEC data,
+	// and packet 2 is recovered from FEC data of packet 3.
+	frames := playout(t, rtpPacket(0, payloads[0]), rtpPacket(3, payloads[3]))
+
+	var lost []pipeline.Frame
+
+	for _, frame := range frames {
+		if frame.Packet == nil && !frame.Gap {
+			lost = append(lost, frame)
+		}
+	}
+
+	if len(lost) != 2 || lost[0].FEC != nil || !bytes.Equal(lost[1].FEC, payloads[3]) {
+		t.Fatalf("lost frames are %+v, want one without and one with FEC data of packet 3", lost)
+	}
+
+	d, err := encoding.NewDecoder(48000, 1, 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan pipeline.Frame, len(frames))
+	for _, frame := range frames {
+		in <- frame
+	}
+
+	close(in)
+
+	decode := pipeline.NewFrameDecode(d, in)
+
+	go func() {
+		for range decode.Out() {
+		}
+	}()
+
+	if err := decode.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := d.Stats(); stats.Concealed != 1 || stats.Recovered != 1 || stats.Frames != 2 {
+		t.Fatalf("decoder stats: %+v", stats)
+	}
+}
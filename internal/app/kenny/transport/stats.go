@@ -0,0 +1,240 @@
+package transport
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/smf8/kenny/internal/app/kenny/encoding"
+)
+
+const (
+	seqModulo   = 1 << 16
+	seqMaxJump  = 3000
+	ntpFraction = 1 << 16
+)
+
+// Stats is a snapshot of RTP/RTCP counters of a transport.
+type Stats struct {
+	PacketsSent     uint64
+	BytesSent       uint64
+	PacketsReceived uint64
+	BytesReceived   uint64
+	// FramesSkipped is number of DTX frames which weren't sent.
+	FramesSkipped uint64
+	// PacketsLost is number of remote packets which didn't arrive, based on sequence numbers. (RFC 3550 A.3)
+	PacketsLost int64
+	// Jitter is the interarrival jitter of remote packets. (RFC 3550 A.8)
+	Jitter time.Duration
+
+	// RemotePacketsLost, RemoteFractionLost and RemoteJitter describe the local stream
+	// from the remote peer's point of view. they are taken from the last RTCP receiver report.
+	RemotePacketsLost  int64
+	RemoteFractionLost float64
+	RemoteJitter       time.Duration
+	// RTT is the round trip time calculated from the last RTCP receiver report, zero if it's not known yet.
+	RTT time.Duration
+
+	// FrameDuration is the duration of the last sent opus frame.
+	FrameDuration time.Duration
+	// Queued is number of received packets waiting to be decoded.
+	Queued int
+}
+
+// CombineStats adds receive statistics of a remote track to stats, i.e. to sum up every participant of an SFU session.
+// counters are summed, jitter is the worst of both.
+func CombineStats(stats, track Stats) Stats {
+	stats.PacketsReceived += track.PacketsReceived
+	stats.BytesReceived += track.BytesReceived
+	stats.PacketsLost += track.PacketsLost
+	stats.Queued += track.Queued
+
+	if track.Jitter > stats.Jitter {
+		stats.Jitter = track.Jitter
+	}
+
+	return stats
+}
+
+// rtpStats collects Stats from the packets which are sent and received.
+type rtpStats struct {
+	mu    sync.Mutex
+	stats Stats
+
+	// receiver state.
+	started     bool
+	baseSeq     uint32
+	maxSeq      uint16
+	cycles      uint32
+	transit     int64
+	jitter      float64
+	arrivalBase time.Time
+
+	// reception report state. (RFC 3550 A.3)
+	expectedPrior uint32
+	receivedPrior uint32
+	// lastSR is the middle 32 bits of the last remote sender report's NTP timestamp, lastSRTime is its arrival.
+	lastSR     uint32
+	lastSRTime time.Time
+}
+
+func (s *rtpStats) sent(packet *rtp.Packet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.PacketsSent++
+	s.stats.BytesSent += uint64(len(packet.Payload))
+
+	if samples, err := encoding.PacketSamples(packet.Payload); err == nil {
+		s.stats.FrameDuration = time.Duration(samples) * time.Second / OpusClockRate
+	}
+}
+
+func (s *rtpStats) skipped() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.FramesSkipped++
+}
+
+func (s *rtpStats) received(packet *rtp.Packet, arrival time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.PacketsReceived++
+	s.stats.BytesReceived += uint64(len(packet.Payload))
+
+	seq := packet.SequenceNumber
+
+	if !s.started {
+		s.started = true
+		s.baseSeq = uint32(seq)
+		s.maxSeq = seq
+		s.arrivalBase = arrival
+		s.transit = -int64(packet.Timestamp)
+
+		return
+	}
+
+	if delta := seq - s.maxSeq; delta > 0 && delta < seqMaxJump {
+		if seq < s.maxSeq {
+			s.cycles += seqModulo
+		}
+
+		s.maxSeq = seq
+	}
+
+	expected := int64(s.cycles+uint32(s.maxSeq)) - int64(s.baseSeq) + 1
+	s.stats.PacketsLost = expected - int64(s.stats.PacketsReceived)
+
+	// transit time in RTP timestamp units, relative to the first packet's arrival.
+	transit := int64(arrival.Sub(s.arrivalBase)*OpusClockRate/time.Second) - int64(packet.Timestamp)
+
+	// int32 conversion handles timestamp wrap around.
+	d := int32(transit - s.transit)
+	s.transit = transit
+
+	if d < 0 {
+		d = -d
+	}
+
+	s.jitter += (float64(d) - s.jitter) / 16 //nolint:gomnd
+	s.stats.Jitter = time.Duration(s.jitter * float64(time.Second) / OpusClockRate)
+}
+
+// receiverReport updates remote statistics from an RTCP receiver report about the local stream.
+func (s *rtpStats) receiverReport(report rtcp.ReceptionReport, arrival time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.RemotePacketsLost = int64(report.TotalLost)
+	s.stats.RemoteFractionLost = float64(report.FractionLost) / 256 //nolint:gomnd
+	s.stats.RemoteJitter = time.Duration(report.Jitter) * time.Second / OpusClockRate
+
+	// RTT = A - LSR - DLSR, all in middle 32 bits of NTP timestamp. (RFC 3550 section 6.4.1)
+	if report.LastSenderReport == 0 {
+		return
+	}
+
+	rtt := ntpMiddle(arrival) - report.LastSenderReport - report.Delay
+	if int32(rtt) > 0 {
+		s.stats.RTT = time.Duration(rtt) * time.Second / ntpFraction
+	}
+}
+
+// senderReport keeps the NTP timestamp of a remote sender report, so the next reception report refers to it.
+func (s *rtpStats) senderReport(ntpTime uint64, arrival time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSR = uint32(ntpTime >> 16) //nolint:gomnd
+	s.lastSRTime = arrival
+}
+
+// receptionReport creates an RTCP reception report about the received stream with ssrc. (RFC 3550 A.3)
+// ok is false if nothing is received yet.
+func (s *rtpStats) receptionReport(ssrc uint32, now time.Time) (report rtcp.ReceptionReport, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return report, false
+	}
+
+	extended := s.cycles + uint32(s.maxSeq)
+	expected := extended - s.baseSeq + 1
+	received := uint32(s.stats.PacketsReceived)
+
+	expectedInterval := int64(expected - s.expectedPrior)
+	lostInterval := expectedInterval - int64(received-s.receivedPrior)
+
+	s.expectedPrior = expected
+	s.receivedPrior = received
+
+	report = rtcp.ReceptionReport{
+		SSRC:               ssrc,
+		LastSequenceNumber: extended,
+		Jitter:             uint32(s.jitter),
+		LastSenderReport:   s.lastSR,
+	}
+
+	if expectedInterval > 0 && lostInterval > 0 {
+		report.FractionLost = uint8(lostInterval << 8 / expectedInterval) //nolint:gomnd
+	}
+
+	// cumulative number of packets lost is a 24 bit field.
+	if lost := s.stats.PacketsLost; lost > 0 {
+		report.TotalLost = uint32(lost) & 0xffffff //nolint:gomnd
+	}
+
+	if !s.lastSRTime.IsZero() {
+		report.Delay = uint32(now.Sub(s.lastSRTime) * ntpFraction / time.Second)
+	}
+
+	return report, true
+}
+
+func (s *rtpStats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
+
+// ntpMiddle returns the middle 32 bits of t as an NTP timestamp.
+func ntpMiddle(t time.Time) uint32 {
+	return uint32(ntpTime(t) >> 16) //nolint:gomnd
+}
+
+// ntpTime returns t as a 64 bit NTP timestamp.
+func ntpTime(t time.Time) uint64 {
+	// seconds between 1900 (NTP epoch) and 1970 (unix epoch).
+	const ntpEpochOffset = 2208988800
+
+	nanos := t.UnixNano()
+	seconds := uint64(nanos/int64(time.Second)) + ntpEpochOffset
+	fraction := uint64(nanos%int64(time.Second)) << 32 / uint64(time.Second) //nolint:gomnd
+
+	return seconds<<32 | fraction //nolint:gomnd
+}
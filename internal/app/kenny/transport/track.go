@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// RemoteTrack is an opus track received from a remote peer.
+type RemoteTrack struct {
+	id       string
+	streamID string
+	received chan *rtp.Packet
+	stats    rtpStats
+}
+
+func newRemoteTrack(id, streamID string) *RemoteTrack {
+	return &RemoteTrack{
+		id:       id,
+		streamID: streamID,
+		received: make(chan *rtp.Packet, receiveBufferSize),
+	}
+}
+
+// ID returns the track's id.
+func (t *RemoteTrack) ID() string {
+	return t.id
+}
+
+// StreamID returns the track's stream id. each participant has a different stream id.
+func (t *RemoteTrack) StreamID() string {
+	return t.streamID
+}
+
+// Received returns the track's RTP packets. it's closed once the track ends.
+func (t *RemoteTrack) Received() <-chan *rtp.Packet {
+	return t.received
+}
+
+// Stats returns the track's receive statistics.
+func (t *RemoteTrack) Stats() Stats {
+	stats := t.stats.snapshot()
+	stats.Queued = len(t.received)
+
+	return stats
+}
+
+// read reads packets from track until it ends.
+func (t *RemoteTrack) read(track *webrtc.TrackRemote) {
+	defer t.end()
+
+	for {
+		packet, _, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+
+		t.push(packet, time.Now())
+	}
+}
+
+// push adds a received packet. packets are dropped if the receive buffer is full.
+func (t *RemoteTrack) push(packet *rtp.Packet, arrival time.Time) {
+	t.stats.received(packet, arrival)
+
+	select {
+	case t.received <- packet:
+	default:
+		log.Debugf("dropped rtp packet %d, receive buffer is full", packet.SequenceNumber)
+	}
+}
+
+// end closes the track's packets channel, push must not be called after it.
+func (t *RemoteTrack) end() {
+	close(t.received)
+}
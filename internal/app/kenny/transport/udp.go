@@ -0,0 +1,431 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// rtcpInterval is how often RTCP sender or receiver reports are sent.
+	rtcpInterval = time.Second
+	// maxPacketSize is the maximum size of a received RTP or RTCP packet.
+	maxPacketSize = 1500
+	cname         = "kenny"
+)
+
+// ErrNoRemote occurs when an RTP session is created without remote address.
+var ErrNoRemote = errors.New("remote rtp address is required")
+
+// RTPSession sends and receives opus over plain RTP/RTCP on UDP, without ICE, DTLS or SRTP,
+// i.e. for local networks or tools like ffmpeg and GStreamer. RTCP uses the port after the RTP port. (RFC 3550 section 11)
+//
+// remote streams are told apart by their SSRC, each one is a remote track.
+type RTPSession struct {
+	rtpConn    *net.UDPConn
+	rtcpConn   *net.UDPConn
+	remote     *net.UDPAddr
+	remoteRTCP *net.UDPAddr
+
+	ssrc        uint32
+	payloadType uint8
+
+	sendMu        sync.Mutex
+	rtp           *Packetizer
+	lastTimestamp uint32
+	lastSent      time.Time
+	stats         rtpStats
+
+	tracks    chan *RemoteTrack
+	remotesMu sync.Mutex
+	remotes   map[uint32]*RemoteTrack
+
+	hangup     chan struct{}
+	hangupOnce sync.Once
+	done       chan struct{}
+	closeOnce  sync.Once
+	wg         sync.WaitGroup
+}
+
+// ListenRTP creates an RTPSession which listens on listen address (i.e. :5004) and sends to remote.
+// payloadType is the RTP payload type of opus in both directions, usually negotiated by an SDP file.
+func ListenRTP(listen string, remote *net.UDPAddr, payloadType uint8) (*RTPSession, error) {
+	if remote == nil {
+		return nil, ErrNoRemote
+	}
+
+	local, err := net.ResolveUDPAddr("udp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve rtp address %s: %w", listen, err)
+	}
+
+	rtpConn, err := net.ListenUDP("udp", local)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for rtp on %s: %w", listen, err)
+	}
+
+	local = rtpConn.LocalAddr().(*net.UDPAddr)
+
+	rtcpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: local.IP, Port: local.Port + 1, Zone: local.Zone})
+	if err != nil {
+		_ = rtpConn.Close()
+
+		return nil, fmt.Errorf("failed to listen for rtcp on port %d: %w", local.Port+1, err)
+	}
+
+	s := &RTPSession{
+		rtpConn:     rtpConn,
+		rtcpConn:    rtcpConn,
+		remote:      remote,
+		remoteRTCP:  &net.UDPAddr{IP: remote.IP, Port: remote.Port + 1, Zone: remote.Zone},
+		ssrc:        rand.Uint32(), //nolint:gosec
+		payloadType: payloadType,
+		rtp:         NewPacketizer(),
+		tracks:      make(chan *RemoteTrack, tracksBufferSize),
+		remotes:     make(map[uint32]*RemoteTrack),
+		hangup:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	s.rtp.PayloadType = payloadType
+
+	s.wg.Add(3) //nolint:gomnd
+
+	go s.readRTP()
+	go s.readRTCP()
+	go s.sendReports()
+
+	return s, nil
+}
+
+// LocalAddr returns the local RTP address.
+func (s *RTPSession) LocalAddr() *net.UDPAddr {
+	return s.rtpConn.LocalAddr().(*net.UDPAddr)
+}
+
+// Description returns the session description which the remote side should use to reach this session.
+// if the session listens on every interface, the address which routes to the remote address is used.
+func (s *RTPSession) Description() RTPDescription {
+	addr := *s.LocalAddr()
+
+	if addr.IP == nil || addr.IP.IsUnspecified() {
+		addr.IP = net.IPv4(127, 0, 0, 1) //nolint:gomnd
+
+		// connecting a udp socket doesn't send anything, it only picks the outgoing interface.
+		if conn, err := net.DialUDP("udp", nil, s.remote); err == nil {
+			addr.IP = conn.LocalAddr().(*net.UDPAddr).IP
+			_ = conn.Close()
+		}
+	}
+
+	return RTPDescription{
+		Addr:        &addr,
+		PayloadType: s.payloadType,
+	}
+}
+
+// WriteFrame sends an opus frame. it implements FrameWriter.
+func (s *RTPSession) WriteFrame(frame []byte) error {
+	// done is closed under sendMu, so a frame is either sent before the connection is closed or not at all.
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	select {
+	case <-s.done:
+		return ErrPeerClosed
+	default:
+	}
+
+	packet, err := s.rtp.Packetize(frame)
+	if err != nil {
+		return err
+	}
+
+	if packet == nil {
+		s.stats.skipped()
+
+		return nil
+	}
+
+	packet.SSRC = s.ssrc
+
+	data, err := packet.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal rtp packet: %w", err)
+	}
+
+	if _, err := s.rtpConn.WriteToUDP(data, s.remote); err != nil {
+		return fmt.Errorf("failed to write rtp packet: %w", err)
+	}
+
+	s.lastTimestamp = packet.Timestamp
+	s.lastSent = time.Now()
+	s.stats.sent(packet)
+
+	return nil
+}
+
+// Tracks returns remote streams as they start. a stream starts when its first packet arrives.
+func (s *RTPSession) Tracks() <-chan *RemoteTrack {
+	return s.tracks
+}
+
+// Hangup is closed when the remote side sends an RTCP BYE.
+func (s *RTPSession) Hangup() <-chan struct{} {
+	return s.hangup
+}
+
+// Done is closed when the session is closed.
+func (s *RTPSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Stats returns a snapshot of the session's RTP/RTCP statistics. receive statistics are the sum of remote streams.
+func (s *RTPSession) Stats() Stats {
+	stats := s.stats.snapshot()
+
+	s.remotesMu.Lock()
+	defer s.remotesMu.Unlock()
+
+	for _, t := range s.remotes {
+		stats = CombineStats(stats, t.Stats())
+	}
+
+	return stats
+}
+
+// Close sends an RTCP BYE and closes the session.
+func (s *RTPSession) Close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		s.writeRTCP(&rtcp.Goodbye{Sources: []uint32{s.ssrc}})
+
+		s.sendMu.Lock()
+		close(s.done)
+		s.sendMu.Unlock()
+
+		err = s.rtpConn.Close()
+		if rtcpErr := s.rtcpConn.Close(); err == nil {
+			err = rtcpErr
+		}
+
+		s.wg.Wait()
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to close rtp session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RTPSession) readRTP() {
+	defer s.wg.Done()
+
+	// tracks are ended when the session is closed, push is only called by this goroutine.
+	defer func() {
+		s.remotesMu.Lock()
+		defer s.remotesMu.Unlock()
+
+		for _, t := range s.remotes {
+			t.end()
+		}
+	}()
+
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, _, err := s.rtpConn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(append([]byte(nil), buf[:n]...)); err != nil {
+			log.Debugf("ignored invalid rtp packet: %s", err)
+
+			continue
+		}
+
+		if packet.PayloadType != s.payloadType {
+			log.Debugf("ignored rtp packet with payload type %d", packet.PayloadType)
+
+			continue
+		}
+
+		s.track(packet.SSRC).push(packet, time.Now())
+	}
+}
+
+// track returns the remote track of ssrc, it's created for the first packet of each stream.
+func (s *RTPSession) track(ssrc uint32) *RemoteTrack {
+	s.remotesMu.Lock()
+	defer s.remotesMu.Unlock()
+
+	if t, ok := s.remotes[ssrc]; ok {
+		return t
+	}
+
+	id := strconv.FormatUint(uint64(ssrc), 10) //nolint:gomnd
+	t := newRemoteTrack(id, id)
+	s.remotes[ssrc] = t
+
+	log.Debugf("remote rtp stream %s has started", id)
+
+	select {
+	case s.tracks <- t:
+	default:
+		log.Warnf("ignored remote stream %s, too many streams are waiting", id)
+	}
+
+	return t
+}
+
+func (s *RTPSession) readRTCP() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, _, err := s.rtcpConn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		packets, err := rtcp.Unmarshal(buf[:n])
+		if err != nil {
+			log.Debugf("ignored invalid rtcp packet: %s", err)
+
+			continue
+		}
+
+		now := time.Now()
+
+		for _, packet := range packets {
+			switch packet := packet.(type) {
+			case *rtcp.SenderReport:
+				s.remoteStats(packet.SSRC, func(stats *rtpStats) {
+					stats.senderReport(packet.NTPTime, now)
+				})
+				s.receptionReports(packet.Reports, now)
+			case *rtcp.ReceiverReport:
+				s.receptionReports(packet.Reports, now)
+			case *rtcp.Goodbye:
+				s.hangupOnce.Do(func() {
+					close(s.hangup)
+				})
+			}
+		}
+	}
+}
+
+// remoteStats calls f with receive statistics of ssrc's stream, if it has started.
+func (s *RTPSession) remoteStats(ssrc uint32, f func(*rtpStats)) {
+	s.remotesMu.Lock()
+	t, ok := s.remotes[ssrc]
+	s.remotesMu.Unlock()
+
+	if ok {
+		f(&t.stats)
+	}
+}
+
+// receptionReports updates remote statistics from the reports about the local stream.
+func (s *RTPSession) receptionReports(reports []rtcp.ReceptionReport, arrival time.Time) {
+	for _, report := range reports {
+		if report.SSRC == s.ssrc {
+			s.stats.receiverReport(report, arrival)
+		}
+	}
+}
+
+// sendReports sends a sender report (or a receiver report before anything is sent) every rtcpInterval.
+func (s *RTPSession) sendReports() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(rtcpInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.writeRTCP(s.report(now))
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// report creates a sender or receiver report with a reception report for every remote stream.
+func (s *RTPSession) report(now time.Time) rtcp.Packet {
+	var reports []rtcp.ReceptionReport
+
+	s.remotesMu.Lock()
+
+	for ssrc, t := range s.remotes {
+		if report, ok := t.stats.receptionReport(ssrc, now); ok {
+			reports = append(reports, report)
+		}
+	}
+
+	s.remotesMu.Unlock()
+
+	s.sendMu.Lock()
+	lastTimestamp, lastSent := s.lastTimestamp, s.lastSent
+	s.sendMu.Unlock()
+
+	if lastSent.IsZero() {
+		return &rtcp.ReceiverReport{SSRC: s.ssrc, Reports: reports}
+	}
+
+	sent := s.stats.snapshot()
+
+	return &rtcp.SenderReport{
+		SSRC:    s.ssrc,
+		NTPTime: ntpTime(now),
+		// RTP timestamp of the same instant as NTPTime, extrapolated from the last sent packet.
+		RTPTime:     lastTimestamp + uint32(now.Sub(lastSent)*OpusClockRate/time.Second),
+		PacketCount: uint32(sent.PacketsSent),
+		OctetCount:  uint32(sent.BytesSent),
+		Reports:     reports,
+	}
+}
+
+// writeRTCP sends packet in a compound packet with the CNAME source description. (RFC 3550 section 6.1)
+func (s *RTPSession) writeRTCP(packet rtcp.Packet) {
+	description := &rtcp.SourceDescription{
+		Chunks: []rtcp.SourceDescriptionChunk{{
+			Source: s.ssrc,
+			Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: cname}},
+		}},
+	}
+
+	var packets []rtcp.Packet
+
+	if _, bye := packet.(*rtcp.Goodbye); bye {
+		// a BYE packet still starts with an empty receiver report.
+		packets = []rtcp.Packet{&rtcp.ReceiverReport{SSRC: s.ssrc}, description, packet}
+	} else {
+		packets = []rtcp.Packet{packet, description}
+	}
+
+	data, err := rtcp.Marshal(packets)
+	if err != nil {
+		log.Errorf("failed to marshal rtcp packet: %s", err)
+
+		return
+	}
+
+	if _, err := s.rtcpConn.WriteToUDP(data, s.remoteRTCP); err != nil {
+		log.Debugf("failed to write rtcp packet: %s", err)
+	}
+}
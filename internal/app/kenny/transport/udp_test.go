@@ -0,0 +1,193 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// freePort returns a local port whose next port is free too, for RTP and RTCP.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		rtpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		port := rtpConn.LocalAddr().(*net.UDPAddr).Port
+
+		rtcpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port + 1})
+
+		_ = rtpConn.Close()
+
+		if err == nil {
+			_ = rtcpConn.Close()
+
+			return port
+		}
+	}
+
+	t.Fatal("failed to find a free port pair")
+
+	return 0
+}
+
+// waitFor polls condition until it's true or timeout is elapsed.
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool, message string) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//nolint:funlen
+func TestRTPSession(t *testing.T) {
+	localhost := net.IPv4(127, 0, 0, 1)
+	callerPort, calleePort := freePort(t), freePort(t)
+
+	caller, err := ListenRTP(net.JoinHostPort("127.0.0.1", strconv.Itoa(callerPort)),
+		&net.UDPAddr{IP: localhost, Port: calleePort}, OpusPayloadType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer caller.Close()
+
+	// the callee reads the caller's address from its session description.
+	sdp, err := caller.Description().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	description, err := UnmarshalRTPDescription(sdp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !description.Addr.IP.Equal(localhost) || description.Addr.Port != callerPort ||
+		description.PayloadType != OpusPayloadType {
+		t.Fatalf("session description round trip returned %+v", description)
+	}
+
+	callee, err := ListenRTP(net.JoinHostPort("127.0.0.1", strconv.Itoa(calleePort)), description.Addr, description.PayloadType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer callee.Close()
+
+	frames := [][]byte{{0xF8, 1, 0xAA}, {0xF8, 2, 0xAA}, {0xF8, 3, 0xAA}, {0xF8, 4, 0xAA}}
+
+	for i, frame := range frames {
+		// the third packet is lost.
+		if i == 2 {
+			caller.rtp.sequence++
+
+			continue
+		}
+
+		if err := caller.WriteFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var track *RemoteTrack
+
+	select {
+	case track = <-callee.Tracks():
+	case <-time.After(5 * time.Second):
+		t.Fatal("callee didn't receive the caller's stream")
+	}
+
+	var lastSeq uint16
+
+	for _, i := range []int{0, 1, 3} {
+		packet := <-track.Received()
+
+		if !bytes.Equal(packet.Payload, frames[i]) || packet.SSRC != caller.ssrc {
+			t.Fatalf("callee received %+v, want frame %d", packet, i)
+		}
+
+		if i > 0 && packet.SequenceNumber != lastSeq+uint16(1+i/3) {
+			t.Fatalf("frame %d has sequence number %d after %d", i, packet.SequenceNumber, lastSeq)
+		}
+
+		lastSeq = packet.SequenceNumber
+	}
+
+	if stats := callee.Stats(); stats.PacketsReceived != 3 || stats.PacketsLost != 1 {
+		t.Fatalf("callee stats: %+v", stats)
+	}
+
+	// the caller sends sender reports, the callee doesn't send anything, so it sends receiver reports
+	// which refer to the last sender report.
+	waitFor(t, 5*time.Second, func() bool {
+		track.stats.mu.Lock()
+		defer track.stats.mu.Unlock()
+
+		return track.stats.lastSR != 0
+	}, "callee didn't receive a sender report")
+
+	waitFor(t, 5*time.Second, func() bool {
+		return caller.Stats().RemotePacketsLost == 1 && caller.Stats().RTT > 0
+	}, "caller didn't receive a receiver report about the lost packet")
+
+	if rtt := caller.Stats().RTT; rtt > 100*time.Millisecond {
+		t.Fatalf("round trip time on localhost is %s", rtt)
+	}
+
+	if err := caller.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-callee.Hangup():
+	case <-time.After(5 * time.Second):
+		t.Fatal("callee didn't receive the caller's BYE")
+	}
+}
+
+func TestRTPSessionWriteWhileClosing(t *testing.T) {
+	localhost := net.IPv4(127, 0, 0, 1)
+
+	s, err := ListenRTP(net.JoinHostPort("127.0.0.1", strconv.Itoa(freePort(t))),
+		&net.UDPAddr{IP: localhost, Port: freePort(t)}, OpusPayloadType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		for {
+			if err := s.WriteFrame([]byte{0xF8, 1, 0xAA}); err != nil {
+				errs <- err
+
+				return
+			}
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a frame which races Close doesn't reach the closed connection.
+	if err := <-errs; !errors.Is(err, ErrPeerClosed) {
+		t.Fatalf("writing while closing: %v, want %v", err, ErrPeerClosed)
+	}
+}
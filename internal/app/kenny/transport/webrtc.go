@@ -0,0 +1,380 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// OpusPayloadType is the dynamic RTP payload type used for opus.
+	OpusPayloadType = 111
+	// OpusClockRate is the RTP clock rate of opus which is always 48 kHz. (RFC 7587)
+	OpusClockRate = 48000
+
+	receiveBufferSize = 64
+	tracksBufferSize  = 16
+)
+
+var (
+	// ErrPeerClosed occurs when sending on a closed Peer.
+	ErrPeerClosed = errors.New("peer connection is closed")
+	// ErrReceiveOnly occurs when sending on a Peer without a local track.
+	ErrReceiveOnly = errors.New("peer connection is receive only")
+)
+
+// Peer is a WebRTC peer connection which sends a local opus track and receives remote opus tracks.
+// in a one to one call there is a single remote track, but an SFU forwards a track for each participant.
+type Peer struct {
+	pc     *webrtc.PeerConnection
+	track  *webrtc.TrackLocalStaticRTP
+	sender *webrtc.RTPSender
+	rtp    *Packetizer
+	stats  rtpStats
+
+	tracks        chan *RemoteTrack
+	remotesMu     sync.Mutex
+	remotes       []*RemoteTrack
+	connected     chan struct{}
+	connectedOnce sync.Once
+	done          chan struct{}
+	doneOnce      sync.Once
+}
+
+// NewPeer creates a Peer. iceServers is a list of STUN/TURN urls, it can be empty for local networks.
+func NewPeer(iceServers []string) (*Peer, error) {
+	return newPeer(iceServers, true)
+}
+
+// NewReceiveOnlyPeer creates a Peer without a local track, i.e. to answer an SFU's offer of its tracks.
+func NewReceiveOnlyPeer(iceServers []string) (*Peer, error) {
+	return newPeer(iceServers, false)
+}
+
+//nolint:funlen
+func newPeer(iceServers []string, send bool) (*Peer, error) {
+	m := &webrtc.MediaEngine{}
+
+	if err := m.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:    webrtc.MimeTypeOpus,
+			ClockRate:   OpusClockRate,
+			Channels:    2,
+			SDPFmtpLine: "minptime=10;useinbandfec=1",
+		},
+		PayloadType: OpusPayloadType,
+	}, webrtc.RTPCodecTypeAudio); err != nil {
+		return nil, fmt.Errorf("failed to register opus codec: %w", err)
+	}
+
+	i := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(m, i); err != nil {
+		return nil, fmt.Errorf("failed to register interceptors: %w", err)
+	}
+
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithInterceptorRegistry(i))
+
+	config := webrtc.Configuration{}
+	if len(iceServers) > 0 {
+		config.ICEServers = []webrtc.ICEServer{{URLs: iceServers}}
+	}
+
+	pc, err := api.NewPeerConnection(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create peer connection: %w", err)
+	}
+
+	p := &Peer{
+		pc:        pc,
+		tracks:    make(chan *RemoteTrack, tracksBufferSize),
+		connected: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if send {
+		if err := p.addLocalTrack(); err != nil {
+			_ = pc.Close()
+
+			return nil, err
+		}
+
+		go p.readRTCP()
+	}
+
+	pc.OnTrack(p.onTrack)
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		log.Debugf("peer connection state has changed: %s", state)
+
+		if state == webrtc.PeerConnectionStateConnected {
+			p.connectedOnce.Do(func() {
+				close(p.connected)
+			})
+		}
+
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			p.doneOnce.Do(func() {
+				close(p.done)
+			})
+		}
+	})
+
+	return p, nil
+}
+
+func (p *Peer) addLocalTrack() error {
+	// every participant has its own stream id, so an SFU can tell their tracks apart.
+	streamID := fmt.Sprintf("kenny-%08x", rand.Uint32()) //nolint:gosec
+
+	track, err := webrtc.NewTrackLocalStaticRTP(
+		webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: OpusClockRate, Channels: 2},
+		"audio", streamID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create local track: %w", err)
+	}
+
+	sender, err := p.pc.AddTrack(track)
+	if err != nil {
+		return fmt.Errorf("failed to add local track: %w", err)
+	}
+
+	p.track = track
+	p.sender = sender
+	p.rtp = NewPacketizer()
+
+	return nil
+}
+
+// PeerConnection returns the underlying pion peer connection for signaling.
+func (p *Peer) PeerConnection() *webrtc.PeerConnection {
+	return p.pc
+}
+
+// Connected is closed once the connection is established.
+func (p *Peer) Connected() <-chan struct{} {
+	return p.connected
+}
+
+// Done is closed when the connection is failed or closed.
+func (p *Peer) Done() <-chan struct{} {
+	return p.done
+}
+
+// CreateOffer creates an SDP offer and sets it as local description.
+func (p *Peer) CreateOffer() (webrtc.SessionDescription, error) {
+	offer, err := p.pc.CreateOffer(nil)
+	if err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("failed to create offer: %w", err)
+	}
+
+	if err := p.pc.SetLocalDescription(offer); err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("failed to set local description: %w", err)
+	}
+
+	return offer, nil
+}
+
+// CreateAnswer creates an SDP answer for the remote offer and sets it as local description.
+func (p *Peer) CreateAnswer() (webrtc.SessionDescription, error) {
+	answer, err := p.pc.CreateAnswer(nil)
+	if err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("failed to create answer: %w", err)
+	}
+
+	if err := p.pc.SetLocalDescription(answer); err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("failed to set local description: %w", err)
+	}
+
+	return answer, nil
+}
+
+// SetRemoteDescription sets remote peer's offer or answer.
+func (p *Peer) SetRemoteDescription(desc webrtc.SessionDescription) error {
+	if err := p.pc.SetRemoteDescription(desc); err != nil {
+		return fmt.Errorf("failed to set remote description: %w", err)
+	}
+
+	return nil
+}
+
+// AddICECandidate adds a trickled ICE candidate of the remote peer.
+func (p *Peer) AddICECandidate(candidate webrtc.ICECandidateInit) error {
+	if err := p.pc.AddICECandidate(candidate); err != nil {
+		return fmt.Errorf("failed to add ice candidate: %w", err)
+	}
+
+	return nil
+}
+
+// OnICECandidate sets a handler for local ICE candidates which should be trickled to the remote peer.
+// the handler is called with nil once gathering is complete.
+func (p *Peer) OnICECandidate(f func(*webrtc.ICECandidate)) {
+	p.pc.OnICECandidate(f)
+}
+
+// WriteFrame sends an opus frame on the local track. RTP timestamp is advanced by the frame's duration.
+func (p *Peer) WriteFrame(frame []byte) error {
+	if p.track == nil {
+		return ErrReceiveOnly
+	}
+
+	select {
+	case <-p.done:
+		return ErrPeerClosed
+	default:
+	}
+
+	packet, err := p.rtp.Packetize(frame)
+	if err != nil {
+		return err
+	}
+
+	if packet == nil {
+		p.stats.skipped()
+
+		return nil
+	}
+
+	if err := p.track.WriteRTP(packet); err != nil {
+		return fmt.Errorf("failed to write rtp packet: %w", err)
+	}
+
+	p.stats.sent(packet)
+
+	return nil
+}
+
+// Tracks returns remote audio tracks as they start. a track starts when its first packet arrives.
+func (p *Peer) Tracks() <-chan *RemoteTrack {
+	return p.tracks
+}
+
+// Close closes the peer connection.
+func (p *Peer) Close() error {
+	if err := p.pc.Close(); err != nil {
+		return fmt.Errorf("failed to close peer connection: %w", err)
+	}
+
+	return nil
+}
+
+// Stats returns a snapshot of the peer's RTP/RTCP statistics. receive statistics are the sum of remote tracks.
+func (p *Peer) Stats() Stats {
+	stats := p.stats.snapshot()
+
+	p.remotesMu.Lock()
+	defer p.remotesMu.Unlock()
+
+	for _, t := range p.remotes {
+		stats = CombineStats(stats, t.Stats())
+	}
+
+	return stats
+}
+
+func (p *Peer) onTrack(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+	if track.Kind() != webrtc.RTPCodecTypeAudio {
+		return
+	}
+
+	log.Debugf("remote track %s of stream %s has started, codec: %s",
+		track.ID(), track.StreamID(), track.Codec().MimeType)
+
+	t := newRemoteTrack(track.ID(), track.StreamID())
+
+	p.remotesMu.Lock()
+	p.remotes = append(p.remotes, t)
+	p.remotesMu.Unlock()
+
+	select {
+	case p.tracks <- t:
+	default:
+		log.Warnf("ignored remote track %s, too many tracks are waiting", track.ID())
+	}
+
+	// remote sender reports must be read, so receiver reports contain their timestamp for RTT calculation.
+	go func() {
+		for {
+			if _, _, err := receiver.ReadRTCP(); err != nil {
+				return
+			}
+		}
+	}()
+
+	t.read(track)
+}
+
+// readRTCP reads incoming RTCP packets about the local track and collects remote receiver reports.
+// compound packets can report on other sources too, i.e. through an SFU, so reports are matched by SSRC.
+func (p *Peer) readRTCP() {
+	ssrc := uint32(p.sender.GetParameters().Encodings[0].SSRC)
+
+	for {
+		packets, _, err := p.sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+
+		now := time.Now()
+
+		for _, packet := range packets {
+			var reports []rtcp.ReceptionReport
+
+			switch packet := packet.(type) {
+			case *rtcp.ReceiverReport:
+				reports = packet.Reports
+			case *rtcp.SenderReport:
+				reports = packet.Reports
+			}
+
+			for _, report := range reports {
+				if report.SSRC == ssrc {
+					p.stats.receiverReport(report, now)
+				}
+			}
+		}
+	}
+}
+
+// ConnectPeers negotiates a connection between two in-process peers, i.e. for loopback tests.
+// it returns once both peers have gathered their ICE candidates and exchanged descriptions.
+func ConnectPeers(ctx context.Context, offerer, answerer *Peer) error {
+	if _, err := offerer.CreateOffer(); err != nil {
+		return err
+	}
+
+	if err := waitGathering(ctx, offerer); err != nil {
+		return err
+	}
+
+	if err := answerer.SetRemoteDescription(*offerer.pc.LocalDescription()); err != nil {
+		return err
+	}
+
+	if _, err := answerer.CreateAnswer(); err != nil {
+		return err
+	}
+
+	if err := waitGathering(ctx, answerer); err != nil {
+		return err
+	}
+
+	return offerer.SetRemoteDescription(*answerer.pc.LocalDescription())
+}
+
+func waitGathering(ctx context.Context, p *Peer) error {
+	select {
+	case <-webrtc.GatheringCompletePromise(p.pc):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
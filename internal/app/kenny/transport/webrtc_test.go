@@ -0,0 +1,170 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"github.com/smf8/kenny/internal/app/kenny/transport"
+)
+
+// opusFrame returns a 20 ms CELT fullband mono opus packet, its payload identifies it.
+func opusFrame(i int) []byte {
+	return []byte{0xF8, byte(i), byte(i >> 8), 0xAA}
+}
+
+func TestConnectPeers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	offerer, err := transport.NewPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer offerer.Close()
+
+	answerer, err := transport.NewPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer answerer.Close()
+
+	if err := transport.ConnectPeers(ctx, offerer, answerer); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []*transport.Peer{offerer, answerer} {
+		for p.PeerConnection().ConnectionState() != webrtc.PeerConnectionStateConnected {
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-ctx.Done():
+				t.Fatal("peers didn't connect")
+			}
+		}
+	}
+
+	const frames = 50
+
+	go func() {
+		for i := 0; i < frames; i++ {
+			if err := offerer.WriteFrame(opusFrame(i)); err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	var track *transport.RemoteTrack
+
+	select {
+	case track = <-answerer.Tracks():
+	case <-ctx.Done():
+		t.Fatal("remote track didn't start")
+	}
+
+	var first, timestamp uint32
+
+	for i := 0; i < frames; i++ {
+		select {
+		case packet := <-track.Received():
+			if i == 0 {
+				first, timestamp = uint32(packet.SequenceNumber), packet.Timestamp
+
+				if !packet.Marker {
+					t.Fatal("first packet doesn't start a talkspurt")
+				}
+			}
+
+			if seq := uint16(first + uint32(i)); packet.SequenceNumber != seq {
+				t.Fatalf("packet %d has sequence number %d, want %d", i, packet.SequenceNumber, seq)
+			}
+
+			if want := timestamp + uint32(i*960); packet.Timestamp != want {
+				t.Fatalf("packet %d has timestamp %d, want %d", i, packet.Timestamp, want)
+			}
+
+			if packet.PayloadType != transport.OpusPayloadType {
+				t.Fatalf("packet %d has payload type %d", i, packet.PayloadType)
+			}
+
+			if !bytes.Equal(packet.Payload, opusFrame(i)) {
+				t.Fatalf("packet %d has payload %x, want %x", i, packet.Payload, opusFrame(i))
+			}
+		case <-ctx.Done():
+			t.Fatalf("received %d of %d frames", i, frames)
+		}
+	}
+
+	if stats := answerer.Stats(); stats.PacketsReceived != frames || stats.PacketsLost != 0 {
+		t.Fatalf("answerer stats: %+v", stats)
+	}
+}
+
+func TestPeerMatchesReceiverReports(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	offerer, err := transport.NewPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer offerer.Close()
+
+	answerer, err := transport.NewPeer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer answerer.Close()
+
+	if err := transport.ConnectPeers(ctx, offerer, answerer); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []*transport.Peer{offerer, answerer} {
+		select {
+		case <-p.Connected():
+		case <-ctx.Done():
+			t.Fatal("peers didn't connect")
+		}
+	}
+
+	ssrc := uint32(offerer.PeerConnection().GetSenders()[0].GetParameters().Encodings[0].SSRC)
+
+	// a compound report, i.e. from an SFU, which reports on another source first.
+	report := &rtcp.ReceiverReport{
+		SSRC: 1,
+		Reports: []rtcp.ReceptionReport{
+			{SSRC: ssrc + 1, TotalLost: 100},
+			{SSRC: ssrc, TotalLost: 7},
+		},
+	}
+
+	if err := answerer.PeerConnection().WriteRTCP([]rtcp.Packet{report}); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		switch lost := offerer.Stats().RemotePacketsLost; lost {
+		case 7:
+			return
+		case 100:
+			t.Fatal("report about another source was collected")
+		}
+
+		select {
+		case <-time.After(time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("receiver report wasn't collected")
+		}
+	}
+}
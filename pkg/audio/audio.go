@@ -1,5 +1,30 @@
 package audio
 
+import "errors"
+
+var (
+	// ErrStreamNotFound occurs when given stream ID is not opened by the device.
+	ErrStreamNotFound = errors.New("stream not found")
+	// ErrStreamClosed occurs when using a stream after closing it.
+	ErrStreamClosed = errors.New("stream is closed")
+	// ErrStreamPaused occurs when reading from or writing to a paused stream.
+	ErrStreamPaused = errors.New("stream is paused")
+	// ErrStreamNotPaused occurs when resuming a stream which is already running.
+	ErrStreamNotPaused = errors.New("stream is not paused")
+)
+
+// StreamSettings represent audio stream settings shared by every backend.
+type StreamSettings struct {
+	SampleRate      float64
+	Channels        int
+	FramesPerBuffer int
+}
+
+// BufferSize returns number of int16 samples in a single buffer (all channels interleaved).
+func (s StreamSettings) BufferSize() int {
+	return s.FramesPerBuffer * s.Channels
+}
+
 // API represents the wrapper for playback and recording.
 type API struct {
 	Player   Player
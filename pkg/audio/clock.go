@@ -0,0 +1,41 @@
+package audio
+
+import "time"
+
+// Clock paces audio buffers by their duration, the same way a sound card does. a zero Clock doesn't pace.
+type Clock struct {
+	period time.Duration
+	next   time.Time
+}
+
+// NewClock creates a Clock for buffers of settings.FramesPerBuffer frames at settings.SampleRate.
+func NewClock(settings StreamSettings) Clock {
+	if settings.SampleRate <= 0 {
+		return Clock{}
+	}
+
+	return Clock{
+		period: time.Duration(float64(settings.FramesPerBuffer) / settings.SampleRate * float64(time.Second)),
+	}
+}
+
+// Advance accounts a buffer and returns how long to wait until its duration is elapsed.
+// devices call it with their lock held and wait after unlocking, so a long wait doesn't block other streams.
+func (c *Clock) Advance() time.Duration {
+	if c.period == 0 {
+		return 0
+	}
+
+	if c.next.IsZero() {
+		c.next = time.Now()
+	}
+
+	c.next = c.next.Add(c.period)
+
+	return time.Until(c.next)
+}
+
+// Reset starts pacing over from the next buffer, i.e. after a stream is resumed.
+func (c *Clock) Reset() {
+	c.next = time.Time{}
+}
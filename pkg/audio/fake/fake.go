@@ -0,0 +1,74 @@
+// Package fake provides in-memory audio.Player and audio.Recorder implementations.
+// it doesn't need any audio device, so it can be used on headless machines and in tests.
+package fake
+
+import (
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+// Timing controls how fake devices pace audio buffers.
+type Timing int
+
+const (
+	// TimingInstant returns and accepts audio buffers as fast as possible.
+	TimingInstant Timing = iota
+	// TimingRealtime blocks for the duration of each buffer, the same way a real sound card does.
+	TimingRealtime
+)
+
+type stream struct {
+	buffer      []int16
+	bufferIndex int
+	paused      bool
+	closed      bool
+	clock       audio.Clock
+}
+
+func (s *stream) check() error {
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	if s.paused {
+		return audio.ErrStreamPaused
+	}
+
+	return nil
+}
+
+func (s *stream) pause() error {
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	if s.paused {
+		return audio.ErrStreamPaused
+	}
+
+	s.paused = true
+
+	return nil
+}
+
+func (s *stream) resume() error {
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	if !s.paused {
+		return audio.ErrStreamNotPaused
+	}
+
+	s.paused = false
+	s.clock.Reset()
+
+	return nil
+}
+
+func newClock(settings audio.StreamSettings, timing Timing) audio.Clock {
+	if timing != TimingRealtime {
+		return audio.Clock{}
+	}
+
+	return audio.NewClock(settings)
+}
@@ -0,0 +1,296 @@
+package fake_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/fake"
+)
+
+func settings(framesPerBuffer int) audio.StreamSettings {
+	return audio.StreamSettings{
+		SampleRate:      48000,
+		Channels:        1,
+		FramesPerBuffer: framesPerBuffer,
+	}
+}
+
+func TestRecorderServesSource(t *testing.T) {
+	recorder := fake.NewRecorder(settings(4), fake.NewSliceSource([]int16{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}),
+		fake.TimingInstant)
+
+	id, err := recorder.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last chunk is padded with silence.
+	for _, want := range [][]int16{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10, 0, 0}} {
+		data, err := recorder.Record(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(data, want) {
+			t.Fatalf("recorded %v, want %v", data, want)
+		}
+	}
+
+	if _, err := recorder.Record(id); !errors.Is(err, io.EOF) {
+		t.Fatalf("recording a drained source returned %v, want io.EOF", err)
+	}
+}
+
+func TestRecorderEOFIsPerStream(t *testing.T) {
+	// take serves the same clip to every stream, it rewinds once it has reported io.EOF.
+	clip := []int16{1, 2, 3}
+	cursor := 0
+
+	take := fake.SourceFunc(func(pcm []int16) (int, error) {
+		if cursor == len(clip) {
+			cursor = 0
+
+			return 0, io.EOF
+		}
+
+		n := copy(pcm, clip[cursor:])
+		cursor += n
+
+		return n, nil
+	})
+
+	recorder := fake.NewRecorder(settings(4), take, fake.TimingInstant)
+
+	for i := 0; i < 2; i++ {
+		id, err := recorder.OpenStream()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := recorder.Record(id)
+		if err != nil {
+			t.Fatalf("stream %d: %s", id, err)
+		}
+
+		if want := []int16{1, 2, 3, 0}; !reflect.DeepEqual(data, want) {
+			t.Fatalf("stream %d recorded %v, want %v", id, data, want)
+		}
+
+		if _, err := recorder.Record(id); !errors.Is(err, io.EOF) {
+			t.Fatalf("stream %d returned %v after its last chunk, want io.EOF", id, err)
+		}
+	}
+}
+
+func TestRecorderPause(t *testing.T) {
+	recorder := fake.NewRecorder(settings(4), fake.SilenceSource(), fake.TimingInstant)
+
+	id, err := recorder.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recorder.PauseRecord(id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := recorder.Record(id); !errors.Is(err, audio.ErrStreamPaused) {
+		t.Fatalf("recording a paused stream returned %v, want %v", err, audio.ErrStreamPaused)
+	}
+
+	if err := recorder.ResumeRecord(id); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recorder.ResumeRecord(id); !errors.Is(err, audio.ErrStreamNotPaused) {
+		t.Fatalf("resuming a running stream returned %v, want %v", err, audio.ErrStreamNotPaused)
+	}
+
+	if err := recorder.CloseStream(id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := recorder.Record(id); !errors.Is(err, audio.ErrStreamClosed) {
+		t.Fatalf("recording a closed stream returned %v, want %v", err, audio.ErrStreamClosed)
+	}
+
+	if _, err := recorder.Record(id + 1); !errors.Is(err, audio.ErrStreamNotFound) {
+		t.Fatalf("recording an unknown stream returned %v, want %v", err, audio.ErrStreamNotFound)
+	}
+}
+
+func TestPlayerStoresPlayedSamples(t *testing.T) {
+	player := fake.NewPlayer(settings(4), fake.TimingInstant)
+
+	id, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []int16
+
+	for _, chunk := range [][]int16{{1, 2, 3}, {4, 5}, {6, 7, 8, 9}, {10}} {
+		if err := player.Play(id, chunk); err != nil {
+			t.Fatal(err)
+		}
+
+		want = append(want, chunk...)
+	}
+
+	if err := player.Play(id, make([]int16, 5)); err == nil {
+		t.Fatal("playing more than a buffer succeeded")
+	}
+
+	// only whole buffers are played until the stream is closed.
+	played, err := player.Samples(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(played, want[:8]) {
+		t.Fatalf("played %v before closing, want %v", played, want[:8])
+	}
+
+	if err := player.CloseStream(id); err != nil {
+		t.Fatal(err)
+	}
+
+	if played, _ = player.Samples(id); !reflect.DeepEqual(played, want) {
+		t.Fatalf("played %v, want %v", played, want)
+	}
+}
+
+func TestRealtimeTiming(t *testing.T) {
+	// 10 buffers of 10 ms.
+	recorder := fake.NewRecorder(settings(480), fake.SilenceSource(), fake.TimingRealtime)
+	player := fake.NewPlayer(settings(480), fake.TimingRealtime)
+
+	recordID, err := recorder.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playID, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	for i := 0; i < 10; i++ {
+		data, err := recorder.Record(recordID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := player.Play(playID, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// recorder and player run side by side like a sound card, so it's 100 ms rather than 200 ms.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > 180*time.Millisecond {
+		t.Fatalf("10 buffers of 10 ms took %s", elapsed)
+	}
+}
+
+func TestPlayerPauseWhilePlaying(t *testing.T) {
+	player := fake.NewPlayer(settings(48), fake.TimingRealtime)
+
+	id, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+			// paused stream errors are expected.
+			_ = player.Play(id, make([]int16, 48))
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		if err := player.PausePlay(id); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := player.ResumePlay(id); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	wg.Wait()
+}
+
+func TestSineSource(t *testing.T) {
+	pcm := make([]int16, 8)
+
+	// without channels, it's mono instead of dividing by zero.
+	n, err := fake.SineSource(12000, 48000, 0, 1000).Read(pcm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int16{0, 1000, 0, -1000, 0, 1000, 0, -1000}; n != len(pcm) || !reflect.DeepEqual(pcm, want) {
+		t.Fatalf("read %d samples %v, want %v", n, pcm, want)
+	}
+
+	n, err = fake.SineSource(12000, 48000, 2, 1000).Read(pcm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int16{0, 0, 1000, 1000, 0, 0, -1000, -1000}; n != len(pcm) || !reflect.DeepEqual(pcm, want) {
+		t.Fatalf("read %d stereo samples %v, want %v", n, pcm, want)
+	}
+}
+
+func TestEchoSource(t *testing.T) {
+	player := fake.NewPlayer(settings(4), fake.TimingInstant)
+
+	playID, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the microphone picks up the speakers 2 samples later at half level.
+	recorder := fake.NewRecorder(settings(4), fake.EchoSource(fake.SilenceSource(), player, playID, 2, 0.5),
+		fake.TimingInstant)
+
+	recordID, err := recorder.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var recorded []int16
+
+	for _, chunk := range [][]int16{{100, 200, 300, 400}, {500, 600, 700, 800}} {
+		if err := player.Play(playID, chunk); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := recorder.Record(recordID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorded = append(recorded, data...)
+	}
+
+	if want := []int16{0, 0, 50, 100, 150, 200, 250, 300}; !reflect.DeepEqual(recorded, want) {
+		t.Fatalf("recorded %v, want %v", recorded, want)
+	}
+}
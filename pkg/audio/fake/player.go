@@ -0,0 +1,183 @@
+package fake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+// Player is an in-memory player which stores everything it was given. it implements audio.Player interface.
+type Player struct {
+	settings audio.StreamSettings
+	timing   Timing
+
+	mu      sync.Mutex
+	streams []*playStream
+}
+
+type playStream struct {
+	stream
+	played []int16
+}
+
+// NewPlayer creates a new Player instance.
+// like portaudio.POPlayer, each call to Play accepts at most `FramesPerBuffer * Channels` samples.
+func NewPlayer(settings audio.StreamSettings, timing Timing) *Player {
+	return &Player{
+		settings: settings,
+		timing:   timing,
+	}
+}
+
+// OpenStream opens a new stream and assigns an ID to it. the stream is started right away.
+func (p *Player) OpenStream() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.streams = append(p.streams, &playStream{
+		stream: stream{
+			buffer: make([]int16, p.settings.BufferSize()),
+			clock:  newClock(p.settings, p.timing),
+		},
+	})
+
+	return len(p.streams) - 1, nil
+}
+
+// CloseStream closes the stream. samples which didn't fill a whole buffer are flushed.
+// played samples are still accessible using Samples.
+func (p *Player) CloseStream(streamID int) error {
+	s, err := p.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	s.played = append(s.played, s.buffer[:s.bufferIndex]...)
+	s.bufferIndex = 0
+	s.closed = true
+
+	return nil
+}
+
+// PausePlay pauses the stream, making next calls to Play return audio.ErrStreamPaused.
+func (p *Player) PausePlay(streamID int) error {
+	s, err := p.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return s.pause()
+}
+
+// ResumePlay resumes a paused stream.
+func (p *Player) ResumePlay(streamID int) error {
+	s, err := p.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return s.resume()
+}
+
+// Play adds given data chunk to the output buffer. once the buffer is full, it is "played" and stored.
+// data is copied into local buffer so using the same slice in multiple calls is safe.
+func (p *Player) Play(streamID int, data []int16) error {
+	s, err := p.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+
+	if err := s.check(); err != nil {
+		p.mu.Unlock()
+
+		return err
+	}
+
+	if len(data) > len(s.buffer) {
+		p.mu.Unlock()
+
+		return fmt.Errorf("audio data size is larger than audio buffer. maximum allowed size is: %d", len(s.buffer))
+	}
+
+	n := copy(s.buffer[s.bufferIndex:], data)
+	s.bufferIndex += n
+
+	var wait time.Duration
+
+	if s.bufferIndex == len(s.buffer) {
+		s.played = append(s.played, s.buffer...)
+		s.bufferIndex = copy(s.buffer, data[n:])
+		wait = s.clock.Advance()
+	}
+
+	p.mu.Unlock()
+
+	time.Sleep(wait)
+
+	return nil
+}
+
+// Samples returns a copy of every sample played on the stream so far.
+func (p *Player) Samples(streamID int) ([]int16, error) {
+	s, err := p.stream(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]int16, len(s.played))
+	copy(result, s.played)
+
+	return result, nil
+}
+
+func (p *Player) stream(streamID int) (*playStream, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if streamID < 0 || streamID >= len(p.streams) {
+		return nil, audio.ErrStreamNotFound
+	}
+
+	return p.streams[streamID], nil
+}
+
+// playedRange copies n played samples of a stream from index start. missing samples are zero.
+func (p *Player) playedRange(streamID, start, n int) []int16 {
+	out := make([]int16, n)
+
+	s, err := p.stream(streamID)
+	if err != nil {
+		return out
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range out {
+		if j := start + i; j >= 0 && j < len(s.played) {
+			out[i] = s.played[j]
+		}
+	}
+
+	return out
+}
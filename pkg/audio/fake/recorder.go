@@ -0,0 +1,166 @@
+package fake
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+// Recorder is an in-memory recorder which serves PCM data from a Source. it implements audio.Recorder interface.
+// all streams share the same Source, each stream reads the chunks which other streams haven't read.
+type Recorder struct {
+	settings audio.StreamSettings
+	timing   Timing
+	source   Source
+
+	mu      sync.Mutex
+	streams []*recordStream
+}
+
+type recordStream struct {
+	stream
+	// eof is set once the stream has returned the last chunk of Source.
+	eof bool
+}
+
+// NewRecorder creates a new Recorder which reads from source.
+// each call to Record returns `FramesPerBuffer * Channels` samples.
+func NewRecorder(settings audio.StreamSettings, source Source, timing Timing) *Recorder {
+	return &Recorder{
+		settings: settings,
+		timing:   timing,
+		source:   source,
+	}
+}
+
+// OpenStream opens a new stream and assigns an ID to it. the stream is started right away.
+func (r *Recorder) OpenStream() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.streams = append(r.streams, &recordStream{
+		stream: stream{
+			buffer: make([]int16, r.settings.BufferSize()),
+			clock:  newClock(r.settings, r.timing),
+		},
+	})
+
+	return len(r.streams) - 1, nil
+}
+
+// CloseStream closes the stream. further calls using streamID will fail.
+func (r *Recorder) CloseStream(streamID int) error {
+	s, err := r.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	s.closed = true
+
+	return nil
+}
+
+// PauseRecord pauses the stream, making next calls to Record return audio.ErrStreamPaused.
+func (r *Recorder) PauseRecord(streamID int) error {
+	s, err := r.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return s.pause()
+}
+
+// ResumeRecord resumes a paused stream.
+func (r *Recorder) ResumeRecord(streamID int) error {
+	s, err := r.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return s.resume()
+}
+
+// Record reads next audio chunk from Source and returns it.
+// when the Source is drained, the last chunk is padded with silence and next calls return io.EOF.
+//
+// the returned slice will be changed in next calls to Record, So use copy to store it.
+func (r *Recorder) Record(streamID int) ([]int16, error) {
+	s, err := r.stream(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+
+	if err := s.check(); err != nil {
+		r.mu.Unlock()
+
+		return nil, err
+	}
+
+	n, err := r.read(s)
+	if n == 0 && err != nil {
+		r.mu.Unlock()
+
+		return nil, err
+	}
+
+	wait := s.clock.Advance()
+
+	r.mu.Unlock()
+
+	time.Sleep(wait)
+
+	return s.buffer, nil
+}
+
+func (r *Recorder) read(s *recordStream) (int, error) {
+	if s.eof {
+		return 0, io.EOF
+	}
+
+	total := 0
+
+	for total < len(s.buffer) {
+		n, err := r.source.Read(s.buffer[total:])
+		total += n
+
+		if err != nil {
+			s.eof = true
+
+			for i := total; i < len(s.buffer); i++ {
+				s.buffer[i] = 0
+			}
+
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (r *Recorder) stream(streamID int) (*recordStream, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if streamID < 0 || streamID >= len(r.streams) {
+		return nil, audio.ErrStreamNotFound
+	}
+
+	return r.streams[streamID], nil
+}
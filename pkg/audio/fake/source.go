@@ -0,0 +1,103 @@
+package fake
+
+import (
+	"io"
+	"math"
+)
+
+// Source provides PCM data for a Recorder. Read fills pcm with interleaved samples and returns
+// the number of samples written. it returns io.EOF once there is no more data.
+type Source interface {
+	Read(pcm []int16) (int, error)
+}
+
+// SourceFunc is an adapter to use ordinary functions as a Source.
+type SourceFunc func(pcm []int16) (int, error)
+
+// Read calls f(pcm).
+func (f SourceFunc) Read(pcm []int16) (int, error) {
+	return f(pcm)
+}
+
+// SliceSource serves the given interleaved samples and then returns io.EOF.
+type SliceSource struct {
+	samples []int16
+	cursor  int
+}
+
+// NewSliceSource creates a Source from samples. samples are not copied.
+func NewSliceSource(samples []int16) *SliceSource {
+	return &SliceSource{
+		samples: samples,
+	}
+}
+
+// Read copies next chunk of samples into pcm.
+func (s *SliceSource) Read(pcm []int16) (int, error) {
+	if s.cursor >= len(s.samples) {
+		return 0, io.EOF
+	}
+
+	n := copy(pcm, s.samples[s.cursor:])
+	s.cursor += n
+
+	return n, nil
+}
+
+// SilenceSource returns an endless Source of zero samples.
+func SilenceSource() Source {
+	return SourceFunc(func(pcm []int16) (int, error) {
+		for i := range pcm {
+			pcm[i] = 0
+		}
+
+		return len(pcm), nil
+	})
+}
+
+// SineSource returns an endless Source of a sine wave with given frequency and amplitude.
+// the same sample is written to every channel, less than one channel is treated as mono.
+func SineSource(frequency, sampleRate float64, channels int, amplitude int16) Source {
+	var n int
+
+	if channels < 1 {
+		channels = 1
+	}
+
+	step := 2 * math.Pi * frequency / sampleRate
+
+	return SourceFunc(func(pcm []int16) (int, error) {
+		frames := len(pcm) / channels
+
+		for i := 0; i < frames; i++ {
+			sample := int16(float64(amplitude) * math.Sin(step*float64(n)))
+			n++
+
+			for c := 0; c < channels; c++ {
+				pcm[i*channels+c] = sample
+			}
+		}
+
+		return frames * channels, nil
+	})
+}
+
+// EchoSource returns a Source which mixes near with the audio played on a stream of player, delayed by delay
+// samples (all channels) and scaled by gain. it simulates a microphone which picks up the speakers,
+// i.e. to test echo cancellation. player and recorder should use TimingRealtime, so they run side by side.
+func EchoSource(near Source, player *Player, streamID, delay int, gain float64) Source {
+	var n int
+
+	return SourceFunc(func(pcm []int16) (int, error) {
+		read, err := near.Read(pcm)
+
+		for i, s := range player.playedRange(streamID, n-delay, read) {
+			mixed := float64(pcm[i]) + gain*float64(s)
+			pcm[i] = int16(math.Max(math.Min(mixed, math.MaxInt16), math.MinInt16))
+		}
+
+		n += read
+
+		return read, err
+	})
+}
@@ -3,8 +3,11 @@ package portaudio
 import (
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/gordonklaus/portaudio"
+	"github.com/smf8/kenny/pkg/audio"
 )
 
 // DeviceType represents audio device type. it's either RecordDeviceType or PlayDeviceType.
@@ -18,26 +21,53 @@ const (
 
 	// DeviceNameDefault is the default devicename which lets port audio api to choose default audio device.
 	DeviceNameDefault = "default"
+
+	stereo = 2
 )
 
 var (
 	// ErrDeviceNotFound occurs when device with given info does not exist in the system.
 	ErrDeviceNotFound = errors.New("failed to find audio device")
+	// ErrNoChannels occurs when device doesn't have any channel in the requested direction,
+	// i.e. recording from an output only device.
+	ErrNoChannels = errors.New("audio device has no channels for this stream type")
+	// ErrPartialSample occurs when playing data which doesn't contain a whole number of interleaved samples.
+	ErrPartialSample = errors.New("audio data length must be a multiple of channel count")
+	// ErrInvalidSampleRate occurs when stream settings don't have a positive sample rate.
+	ErrInvalidSampleRate = errors.New("sample rate must be positive")
 )
 
-// StreamSettings represent audio stream settings used for opening a portaudio stream
-type StreamSettings struct {
-	SampleRate      float64
-	Channels        int
-	FramesPerBuffer int
-}
+// StreamSettings represent audio stream settings used for opening a portaudio stream.
+type StreamSettings = audio.StreamSettings
 
 // because we may have multiple audio sources(i.e multiple speakers),
 // we represent each one with a audioStream instance.
+//
+// buffer holds data in device's channel layout. remixed holds data between the device's layout and the one
+// requested by StreamSettings, before resampling to or after resampling from device's sample rate.
+// resampler converts between device's sample rate and the requested one, it's nil when they're equal.
+// resampled recordings vary in length, pending holds them until a whole chunk is recorded.
 type audioStream struct {
 	stream      *portaudio.Stream
 	buffer      []int16
+	remixed     []int16
+	resampler   *audio.Resampler
+	pending     []int16
+	chunk       []int16
 	bufferIndex int
+	closed      bool
+}
+
+func getStream(streams []*audioStream, streamID int) (*audioStream, error) {
+	if streamID < 0 || streamID >= len(streams) {
+		return nil, audio.ErrStreamNotFound
+	}
+
+	if streams[streamID].closed {
+		return nil, audio.ErrStreamClosed
+	}
+
+	return streams[streamID], nil
 }
 
 // Init must be called before any further usage on portaudio package
@@ -59,9 +89,75 @@ func Cleanup() error {
 	return nil
 }
 
+// Devices returns the list of available audio devices.
+func Devices() ([]*portaudio.DeviceInfo, error) {
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// DefaultDevice returns system's default recording or playback device.
+func DefaultDevice(deviceType DeviceType) (*portaudio.DeviceInfo, error) {
+	var (
+		device *portaudio.DeviceInfo
+		err    error
+	)
+
+	if deviceType == RecordDeviceType {
+		device, err = portaudio.DefaultInputDevice()
+	} else {
+		device, err = portaudio.DefaultOutputDevice()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default audio device: %w", err)
+	}
+
+	return device, nil
+}
+
+// FormatDeviceInfo formats portaudio.DeviceInfo in a human readable form.
+func FormatDeviceInfo(info *portaudio.DeviceInfo) string {
+	sb := new(strings.Builder)
+
+	fmt.Fprintf(sb, "==========================================\n")
+	fmt.Fprintf(sb, "[Name]: %s\n", info.Name)
+	fmt.Fprintf(sb, "[Max input Channels]: %d\n", info.MaxInputChannels)
+	fmt.Fprintf(sb, "[Max output Channels]: %d\n", info.MaxOutputChannels)
+	fmt.Fprintf(sb, "[Default Low input Latency]: %s\n", info.DefaultLowInputLatency)
+	fmt.Fprintf(sb, "[Default High input Latency]: %s\n", info.DefaultHighInputLatency)
+	fmt.Fprintf(sb, "[Default Low output Latency]: %s\n", info.DefaultLowOutputLatency)
+	fmt.Fprintf(sb, "[Default High output Latency]: %s\n", info.DefaultHighOutputLatency)
+	fmt.Fprintf(sb, "[Default Sample Rate]: %f\n", info.DefaultSampleRate)
+
+	if info.HostApi != nil {
+		fmt.Fprintf(sb, "[HOST API Info]: \n")
+		fmt.Fprintf(sb, "\t[Type]: %s\n", info.HostApi.Type)
+		fmt.Fprintf(sb, "\t[Name]: %s\n", info.HostApi.Name)
+
+		if info.HostApi.DefaultInputDevice != nil {
+			fmt.Fprintf(sb, "\t[Default InputDevice Name]: %s\n", info.HostApi.DefaultInputDevice.Name)
+		}
+
+		if info.HostApi.DefaultOutputDevice != nil {
+			fmt.Fprintf(sb, "\t[Default OutputDevice Name]: %s\n", info.HostApi.DefaultOutputDevice.Name)
+		}
+	}
+
+	return sb.String()
+}
+
 func newStreamParam(
 	deviceName string, deviceType DeviceType, settings StreamSettings,
 ) (*portaudio.StreamParameters, error) {
+	// buffer duration is kept at the device's rate, so it's calculated from the requested rate.
+	if settings.SampleRate <= 0 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSampleRate, settings.SampleRate)
+	}
+
 	defaultHostAPI, err := portaudio.DefaultHostApi()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default host api: %w", err)
@@ -87,13 +183,30 @@ func newStreamParam(
 		return nil, ErrDeviceNotFound
 	}
 
+	maxChannels := device.MaxOutputChannels
+	if deviceType == RecordDeviceType {
+		maxChannels = device.MaxInputChannels
+	}
+
+	if maxChannels == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoChannels, device.Name)
+	}
+
+	channels := deviceChannels(deviceType, settings.Channels, maxChannels)
+
+	// devices are opened at their native rate and resampled, buffers keep the requested duration.
+	sampleRate := settings.SampleRate
+	if device.DefaultSampleRate > 0 {
+		sampleRate = device.DefaultSampleRate
+	}
+
 	deviceParameters := portaudio.StreamDeviceParameters{
 		Device:   device,
-		Channels: settings.Channels,
+		Channels: channels,
 	}
 	sp := portaudio.StreamParameters{
-		SampleRate:      settings.SampleRate,
-		FramesPerBuffer: settings.FramesPerBuffer,
+		SampleRate:      sampleRate,
+		FramesPerBuffer: int(math.Round(float64(settings.FramesPerBuffer) * sampleRate / settings.SampleRate)),
 		Flags:           portaudio.ClipOff,
 	}
 
@@ -109,28 +222,29 @@ func newStreamParam(
 	return &sp, nil
 }
 
-// NewStreamParam creates a new output/input stream which is ready to use.
-// For temporarily pausing record/play use Stop() and to finish using stream call Close().
-func NewStreamParam(s *StreamSettings, device *portaudio.DeviceInfo,
-	deviceType DeviceType) (portaudio.StreamParameters, error) {
-	deviceParameters := portaudio.StreamDeviceParameters{
-		Device:   device,
-		Channels: s.Channels,
-		Latency:  device.DefaultLowInputLatency,
+// deviceChannels returns number of channels the device is opened with, audio is remixed between it and
+// the requested channel count. devices with less channels than requested are opened with all of their channels.
+// output devices are opened with at least 2 channels, so mono is played on both speakers of a stereo device.
+// sound servers report dozens of output channels, so they aren't opened with more than requested.
+func deviceChannels(deviceType DeviceType, channels, maxChannels int) int {
+	if deviceType == PlayDeviceType && channels < stereo {
+		channels = stereo
 	}
-	sp := portaudio.StreamParameters{
-		SampleRate:      s.SampleRate,
-		FramesPerBuffer: s.FramesPerBuffer,
-		Flags:           portaudio.ClipOff,
+
+	if channels > maxChannels {
+		channels = maxChannels
 	}
 
-	if deviceType == RecordDeviceType {
-		sp.Input = deviceParameters
-	} else if deviceType == PlayDeviceType {
-		sp.Output = deviceParameters
+	return channels
+}
+
+// newResampler creates a resampler between the rates, or returns nil when they're equal.
+func newResampler(inRate, outRate float64, channels int) *audio.Resampler {
+	if inRate == outRate {
+		return nil
 	}
 
-	return sp, nil
+	return audio.NewResampler(int(math.Round(inRate)), int(math.Round(outRate)), channels)
 }
 
 func openStream(buffer []int16, params portaudio.StreamParameters) (*portaudio.Stream, error) {
@@ -0,0 +1,78 @@
+package portaudio
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeviceChannels(t *testing.T) {
+	for _, c := range []struct {
+		deviceType  DeviceType
+		channels    int
+		maxChannels int
+		want        int
+	}{
+		// mono is remixed to both channels of stereo speakers.
+		{PlayDeviceType, 1, 2, 2},
+		{PlayDeviceType, 1, 1, 1},
+		{PlayDeviceType, 2, 1, 1},
+		{PlayDeviceType, 2, 6, 2},
+		// sound servers report many channels.
+		{PlayDeviceType, 1, 32, 2},
+		{RecordDeviceType, 1, 2, 1},
+		{RecordDeviceType, 2, 1, 1},
+	} {
+		if got := deviceChannels(c.deviceType, c.channels, c.maxChannels); got != c.want {
+			t.Fatalf("%d channels on a device with %d opened %d channels, want %d",
+				c.channels, c.maxChannels, got, c.want)
+		}
+	}
+}
+
+func TestNewStreamParamRejectsSampleRate(t *testing.T) {
+	settings := StreamSettings{SampleRate: 0, Channels: 1, FramesPerBuffer: 480}
+
+	if _, err := newStreamParam(DeviceNameDefault, RecordDeviceType, settings); !errors.Is(err, ErrInvalidSampleRate) {
+		t.Fatalf("opening a stream at %v Hz: %v, want %v", settings.SampleRate, err, ErrInvalidSampleRate)
+	}
+}
+
+func TestStreamFillsChunks(t *testing.T) {
+	s := &audioStream{chunk: make([]int16, 6)}
+
+	// resampled reads vary in length around the chunk size.
+	var next int16
+
+	read := func(n int) func() ([]int16, error) {
+		return func() ([]int16, error) {
+			data := make([]int16, n)
+			for i := range data {
+				data[i] = next
+				next++
+			}
+
+			return data, nil
+		}
+	}
+
+	var want int16
+
+	for _, n := range []int{6, 5, 7, 4, 8, 6, 1} {
+		chunk, err := s.fill(read(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(chunk) != 6 {
+			t.Fatalf("chunk has %d samples, want 6", len(chunk))
+		}
+
+		for _, sample := range chunk {
+			if sample != want {
+				t.Fatalf("chunk %v isn't continuous at sample %d", chunk, want)
+			}
+
+			want++
+		}
+	}
+}
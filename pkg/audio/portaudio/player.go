@@ -4,17 +4,23 @@ import (
 	"fmt"
 
 	"github.com/gordonklaus/portaudio"
+	"github.com/smf8/kenny/pkg/audio"
 )
 
 // POPlayer represents portaudio player. it implements audio.Player interface.
 type POPlayer struct {
-	streamParams portaudio.StreamParameters
-	streams      []*audioStream
+	streamParams    portaudio.StreamParameters
+	channels        int
+	sampleRate      float64
+	framesPerBuffer int
+	streams         []*audioStream
 }
 
 // NewPlayer creates a new POPlayer instance with given StreamSettings and deviceName.
 // deviceName must be a valid portaudio device name from devices command.
 // use DeviceNameDefault as deviceName to use system's default device.
+// played audio is remixed to the device's channels, i.e. mono is played on both channels of a stereo device.
+// the device is opened at its default sample rate, played audio is resampled from settings.SampleRate.
 func NewPlayer(deviceName string, settings StreamSettings) (*POPlayer, error) {
 	param, err := newStreamParam(deviceName, PlayDeviceType, settings)
 	if err != nil {
@@ -22,7 +28,10 @@ func NewPlayer(deviceName string, settings StreamSettings) (*POPlayer, error) {
 	}
 
 	return &POPlayer{
-		streamParams: *param,
+		streamParams:    *param,
+		channels:        settings.Channels,
+		sampleRate:      settings.SampleRate,
+		framesPerBuffer: settings.FramesPerBuffer,
 	}, nil
 }
 
@@ -41,8 +50,10 @@ func (p *POPlayer) OpenStream() (int, error) {
 
 	id := len(p.streams)
 	s := &audioStream{
-		stream: stream,
-		buffer: buffer,
+		stream:    stream,
+		buffer:    buffer,
+		remixed:   make([]int16, p.streamParams.Output.Channels*p.framesPerBuffer),
+		resampler: newResampler(p.sampleRate, p.streamParams.SampleRate, p.streamParams.Output.Channels),
 	}
 	p.streams = append(p.streams, s)
 
@@ -55,7 +66,14 @@ func (p *POPlayer) OpenStream() (int, error) {
 
 // CloseStream closes the stream and it's data channel.
 func (p *POPlayer) CloseStream(streamID int) error {
-	if err := p.streams[streamID].stream.Close(); err != nil {
+	s, err := getStream(p.streams, streamID)
+	if err != nil {
+		return err
+	}
+
+	s.closed = true
+
+	if err := s.stream.Close(); err != nil {
 		return fmt.Errorf("failed to close stream %d: %w", streamID, err)
 	}
 
@@ -64,7 +82,12 @@ func (p *POPlayer) CloseStream(streamID int) error {
 
 // PausePlay pauses The play making next call to stream.Write() return an error.
 func (p *POPlayer) PausePlay(streamID int) error {
-	if err := p.streams[streamID].stream.Stop(); err != nil {
+	s, err := getStream(p.streams, streamID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.stream.Stop(); err != nil {
 		return fmt.Errorf("failed to pause play %d: %w", streamID, err)
 	}
 
@@ -75,7 +98,12 @@ func (p *POPlayer) PausePlay(streamID int) error {
 //
 // calling this function for an already started stream will cause error.
 func (p *POPlayer) ResumePlay(streamID int) error {
-	if err := p.streams[streamID].stream.Start(); err != nil {
+	s, err := getStream(p.streams, streamID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.stream.Start(); err != nil {
 		return fmt.Errorf("failed to start play %d: %w", streamID, err)
 	}
 
@@ -83,27 +111,41 @@ func (p *POPlayer) ResumePlay(streamID int) error {
 }
 
 // Play will add given data chunk to the output buffer. It will write the buffer to the output device once
-// it is completely filled with data. use maximum chunk of `FramesPerBuffer`.
+// it is completely filled with data. use maximum chunk of `FramesPerBuffer * Channels` interleaved samples.
 // data is copied into local buffer so using the same slice in multiple calls is safe.
 func (p *POPlayer) Play(streamID int, data []int16) error {
-	playStream := p.streams[streamID]
+	playStream, err := getStream(p.streams, streamID)
+	if err != nil {
+		return err
+	}
 
-	if len(data) > len(playStream.buffer) {
-		return fmt.Errorf("audio data size is larger than audio buffer. maximum allowed size is: %d", len(playStream.buffer))
+	if size := p.channels * p.framesPerBuffer; len(data) > size {
+		return fmt.Errorf("audio data size is larger than audio buffer. maximum allowed size is: %d", size)
 	}
 
-	if playStream.bufferIndex+len(data) >= len(playStream.buffer) {
-		overflow := len(playStream.buffer) - playStream.bufferIndex
-		copy(playStream.buffer[playStream.bufferIndex:], data)
+	if len(data)%p.channels != 0 {
+		return ErrPartialSample
+	}
 
-		if err := playStream.stream.Write(); err != nil {
-			return fmt.Errorf("failed to write to output stream %d: %w", streamID, err)
-		}
+	data = audio.Remix(playStream.remixed, data, p.channels, p.streamParams.Output.Channels)
 
-		playStream.bufferIndex = copy(playStream.buffer, data[overflow:])
-	} else {
-		copy(playStream.buffer[playStream.bufferIndex:], data)
-		playStream.bufferIndex += len(data)
+	if playStream.resampler != nil {
+		data = playStream.resampler.Resample(data)
+	}
+
+	// resampled data can be longer than what's left of the buffer more than once.
+	for len(data) > 0 {
+		n := copy(playStream.buffer[playStream.bufferIndex:], data)
+		playStream.bufferIndex += n
+		data = data[n:]
+
+		if playStream.bufferIndex == len(playStream.buffer) {
+			if err := playStream.stream.Write(); err != nil {
+				return fmt.Errorf("failed to write to output stream %d: %w", streamID, err)
+			}
+
+			playStream.bufferIndex = 0
+		}
 	}
 
 	return nil
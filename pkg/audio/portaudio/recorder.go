@@ -4,17 +4,23 @@ import (
 	"fmt"
 
 	"github.com/gordonklaus/portaudio"
+	"github.com/smf8/kenny/pkg/audio"
 )
 
 // PORecorder represents portaudio recorder. it implements audio.Recorder interface.
 type PORecorder struct {
-	streamParams portaudio.StreamParameters
-	streams      []*audioStream
+	streamParams    portaudio.StreamParameters
+	channels        int
+	sampleRate      float64
+	framesPerBuffer int
+	streams         []*audioStream
 }
 
 // NewRecorder creates a new PORecorder instance with given StreamSettings.
 // deviceName must be a valid portaudio device name from devices command.
 // use DeviceNameDefault as deviceName to use system's default device.
+// if the device has less channels than settings.Channels, recorded audio is upmixed.
+// the device is opened at its default sample rate, recorded audio is resampled to settings.SampleRate.
 func NewRecorder(deviceName string, settings StreamSettings) (*PORecorder, error) {
 	param, err := newStreamParam(deviceName, RecordDeviceType, settings)
 	if err != nil {
@@ -22,7 +28,10 @@ func NewRecorder(deviceName string, settings StreamSettings) (*PORecorder, error
 	}
 
 	return &PORecorder{
-		streamParams: *param,
+		streamParams:    *param,
+		channels:        settings.Channels,
+		sampleRate:      settings.SampleRate,
+		framesPerBuffer: settings.FramesPerBuffer,
 	}, nil
 }
 
@@ -39,8 +48,11 @@ func (p *PORecorder) OpenStream() (int, error) {
 
 	id := len(p.streams)
 	s := &audioStream{
-		stream: stream,
-		buffer: buffer,
+		stream:    stream,
+		buffer:    buffer,
+		remixed:   make([]int16, p.channels*p.streamParams.FramesPerBuffer),
+		resampler: newResampler(p.streamParams.SampleRate, p.sampleRate, p.channels),
+		chunk:     make([]int16, p.channels*p.framesPerBuffer),
 	}
 	p.streams = append(p.streams, s)
 
@@ -53,7 +65,14 @@ func (p *PORecorder) OpenStream() (int, error) {
 
 // CloseStream closes the stream and it's data channel.
 func (p *PORecorder) CloseStream(streamID int) error {
-	if err := p.streams[streamID].stream.Close(); err != nil {
+	s, err := getStream(p.streams, streamID)
+	if err != nil {
+		return err
+	}
+
+	s.closed = true
+
+	if err := s.stream.Close(); err != nil {
 		return fmt.Errorf("failed to close stream %d: %w", streamID, err)
 	}
 
@@ -64,7 +83,12 @@ func (p *PORecorder) CloseStream(streamID int) error {
 //
 //
 func (p *PORecorder) PauseRecord(streamID int) error {
-	if err := p.streams[streamID].stream.Stop(); err != nil {
+	s, err := getStream(p.streams, streamID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.stream.Stop(); err != nil {
 		return fmt.Errorf("failed to pause record %d: %w", streamID, err)
 	}
 
@@ -75,22 +99,56 @@ func (p *PORecorder) PauseRecord(streamID int) error {
 //
 // calling this function for an already started stream will cause error.
 func (p *PORecorder) ResumeRecord(streamID int) error {
-	if err := p.streams[streamID].stream.Start(); err != nil {
+	s, err := getStream(p.streams, streamID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.stream.Start(); err != nil {
 		return fmt.Errorf("failed to start record %d: %w", streamID, err)
 	}
 
 	return nil
 }
 
-// Record reads an audio chunk from device and returns it.
+// Record reads an audio chunk of `FramesPerBuffer * Channels` samples from device and returns it.
+// when the device is resampled, the device is read until a whole chunk is available.
 //
 // the returned slice will be changed in next calls to Record, So use copy to store it.
 func (p *PORecorder) Record(streamID int) ([]int16, error) {
-	recordStream := p.streams[streamID]
+	recordStream, err := getStream(p.streams, streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordStream.fill(func() ([]int16, error) {
+		if err := recordStream.stream.Read(); err != nil {
+			return nil, fmt.Errorf("failed to read input stream %d: %w", streamID, err)
+		}
+
+		data := audio.Remix(recordStream.remixed, recordStream.buffer, p.streamParams.Input.Channels, p.channels)
 
-	if err := recordStream.stream.Read(); err != nil {
-		return nil, fmt.Errorf("failed to read input stream %d: %w", streamID, err)
+		if recordStream.resampler != nil {
+			data = recordStream.resampler.Resample(data)
+		}
+
+		return data, nil
+	})
+}
+
+// fill calls read until pending data fills s.chunk and returns the chunk, the rest is kept for the next chunk.
+func (s *audioStream) fill(read func() ([]int16, error)) ([]int16, error) {
+	for len(s.pending) < len(s.chunk) {
+		data, err := read()
+		if err != nil {
+			return nil, err
+		}
+
+		s.pending = append(s.pending, data...)
 	}
 
-	return recordStream.buffer, nil
+	copy(s.chunk, s.pending)
+	s.pending = s.pending[:copy(s.pending, s.pending[len(s.chunk):])]
+
+	return s.chunk, nil
 }
@@ -0,0 +1,47 @@
+package audio
+
+// Remix converts interleaved pcm data from srcChannels to dstChannels and writes it into dst.
+// dst must have room for `len(src) / srcChannels * dstChannels` samples. it returns the written part of dst.
+//
+// mono is duplicated into every channel when upmixing and every channel is averaged when downmixing to mono.
+// other layouts keep the channels they have in common, downmixed channels are averaged into
+// `channel % dstChannels` and missing channels are copied from `channel % srcChannels`.
+func Remix(dst, src []int16, srcChannels, dstChannels int) []int16 {
+	frames := len(src) / srcChannels
+	dst = dst[:frames*dstChannels]
+
+	if srcChannels == dstChannels {
+		copy(dst, src)
+
+		return dst
+	}
+
+	for f := 0; f < frames; f++ {
+		in := src[f*srcChannels : (f+1)*srcChannels]
+		out := dst[f*dstChannels : (f+1)*dstChannels]
+
+		if srcChannels < dstChannels {
+			for c := range out {
+				out[c] = in[c%srcChannels]
+			}
+
+			continue
+		}
+
+		for c := range out {
+			var (
+				sum   int
+				count int
+			)
+
+			for i := c; i < srcChannels; i += dstChannels {
+				sum += int(in[i])
+				count++
+			}
+
+			out[c] = int16(sum / count)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,50 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+func equal(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestRemix(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		src         []int16
+		srcChannels int
+		dstChannels int
+		want        []int16
+	}{
+		{"mono to stereo", []int16{1, 2, 3}, 1, 2, []int16{1, 1, 2, 2, 3, 3}},
+		{"stereo to mono", []int16{1, 3, -4, 2, 100, 100}, 2, 1, []int16{2, -1, 100}},
+		{"stereo to stereo", []int16{1, 2, 3, 4}, 2, 2, []int16{1, 2, 3, 4}},
+		{"partial frame", []int16{1, 3, 5}, 2, 1, []int16{2}},
+	} {
+		dst := make([]int16, 16)
+
+		if got := audio.Remix(dst, c.src, c.srcChannels, c.dstChannels); !equal(got, c.want) {
+			t.Fatalf("%s: remixed %v to %v, want %v", c.name, c.src, got, c.want)
+		}
+	}
+
+	// mono survives a round trip through stereo.
+	mono := []int16{5, -7, 32767, -32768}
+	stereo := audio.Remix(make([]int16, 8), mono, 1, 2)
+
+	if back := audio.Remix(make([]int16, 4), stereo, 2, 1); !equal(back, mono) {
+		t.Fatalf("round trip through stereo returned %v, want %v", back, mono)
+	}
+}
@@ -0,0 +1,172 @@
+package audio
+
+import (
+	"math"
+)
+
+const (
+	// resampleTaps is number of filter taps of every output sample, when the rate isn't lowered.
+	// input is delayed by half of it.
+	resampleTaps = 64
+	// resampleCutoff is the filter's cutoff relative to the lower nyquist frequency.
+	resampleCutoff = 0.91
+	// resampleBeta is the kaiser window's shape, it attenuates aliasing by about 80 dB.
+	resampleBeta = 8
+)
+
+// Resampler converts interleaved pcm data from one sample rate to another with a polyphase windowed sinc filter.
+// it's streaming: chunks of any length are converted one after another and filter history is kept between them,
+// so output is continuous and delayed by half of the filter, about 0.7 ms.
+//
+// the rate ratio is reduced to up/down, i.e. 160/147 for 44100 to 48000. output sample n is at input
+// position n*down/up and its fractional part selects one of up precalculated filter phases.
+type Resampler struct {
+	channels int
+	up       int
+	down     int
+	taps     int
+	// phases holds filter coefficients of every fractional position, phase p is at p/up of an input sample.
+	phases [][]float64
+
+	// history holds input frames which are still needed, frame position is the next output's integer position in it.
+	history  []int16
+	position int
+	phase    int
+	out      []int16
+}
+
+// NewResampler creates a Resampler from inRate to outRate for interleaved pcm data with the given channel count.
+func NewResampler(inRate, outRate, channels int) *Resampler {
+	g := gcd(inRate, outRate)
+	up, down := outRate/g, inRate/g
+
+	// lowering the rate needs a proportionally longer filter for the same transition band.
+	taps := resampleTaps
+	if down > up {
+		taps = resampleTaps * ((down + up - 1) / up)
+	}
+
+	// cutoff is in cycles per input sample, it's below the output nyquist frequency when the rate is lowered.
+	cutoff := 0.5 * resampleCutoff //nolint:gomnd
+	if down > up {
+		cutoff *= float64(up) / float64(down)
+	}
+
+	phases := make([][]float64, up)
+
+	for p := range phases {
+		coefficients := make([]float64, taps)
+		offset := float64(p) / float64(up)
+
+		var sum float64
+
+		for k := range coefficients {
+			// tap k is input sample position-taps/2+1+k, x is its distance from the output position.
+			x := float64(k-taps/2+1) - offset
+			coefficients[k] = 2 * cutoff * sinc(2*cutoff*x) * kaiser(x/(float64(taps)/2), resampleBeta)
+			sum += coefficients[k]
+		}
+
+		// every phase has unity gain, so constant signals don't get a ripple of the phase pattern.
+		for k := range coefficients {
+			coefficients[k] /= sum
+		}
+
+		phases[p] = coefficients
+	}
+
+	r := &Resampler{
+		channels: channels,
+		up:       up,
+		down:     down,
+		taps:     taps,
+		phases:   phases,
+		// silent history before the first input.
+		history:  make([]int16, (taps/2-1)*channels),
+		position: taps/2 - 1,
+	}
+
+	return r
+}
+
+// Resample converts src and returns the output which is ready. its length varies around
+// `len(src) * outRate / inRate` as filter history is kept.
+// the returned slice will be changed in next calls to Resample, So use copy to store it.
+func (r *Resampler) Resample(src []int16) []int16 {
+	if r.up == r.down {
+		return src
+	}
+
+	r.history = append(r.history, src...)
+	frames := len(r.history) / r.channels
+	r.out = r.out[:0]
+
+	for r.position+r.taps/2 < frames {
+		coefficients := r.phases[r.phase]
+		first := (r.position - r.taps/2 + 1) * r.channels
+
+		for c := 0; c < r.channels; c++ {
+			var sum float64
+
+			for k, h := range coefficients {
+				sum += h * float64(r.history[first+k*r.channels+c])
+			}
+
+			r.out = append(r.out, int16(math.Max(math.Min(math.Round(sum), math.MaxInt16), math.MinInt16)))
+		}
+
+		r.phase += r.down
+		r.position += r.phase / r.up
+		r.phase %= r.up
+	}
+
+	// frames before the next output's filter aren't needed anymore.
+	if consumed := r.position - r.taps/2 + 1; consumed > 0 {
+		if consumed > frames {
+			consumed = frames
+		}
+
+		n := copy(r.history, r.history[consumed*r.channels:])
+		r.history = r.history[:n]
+		r.position -= consumed
+	}
+
+	return r.out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser returns the kaiser window at x, which is between -1 and 1.
+func kaiser(x, beta float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+
+	return bessel(beta*math.Sqrt(1-x*x)) / bessel(beta)
+}
+
+// bessel is the zeroth order modified bessel function of the first kind.
+func bessel(x float64) float64 {
+	sum, term := 1.0, 1.0
+
+	for k := 1; term > sum*1e-12; k++ {
+		term *= (x / 2 / float64(k)) * (x / 2 / float64(k))
+		sum += term
+	}
+
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
@@ -0,0 +1,109 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+// resample passes src through r in chunks of the given size and returns the whole output.
+func resample(r *audio.Resampler, src []int16, chunk int) []int16 {
+	var out []int16
+
+	for start := 0; start < len(src); start += chunk {
+		end := start + chunk
+		if end > len(src) {
+			end = len(src)
+		}
+
+		out = append(out, r.Resample(src[start:end])...)
+	}
+
+	return out
+}
+
+func tone(frequency float64, rate, n int, amplitude float64) []int16 {
+	pcm := make([]int16, n)
+	for i := range pcm {
+		pcm[i] = int16(amplitude * math.Sin(2*math.Pi*frequency*float64(i)/float64(rate)))
+	}
+
+	return pcm
+}
+
+func TestResamplerLength(t *testing.T) {
+	for _, c := range []struct {
+		in, out int
+	}{
+		{44100, 48000},
+		{48000, 44100},
+		{16000, 48000},
+		{48000, 8000},
+	} {
+		// a second in 10 ms chunks.
+		out := resample(audio.NewResampler(c.in, c.out, 1), make([]int16, c.in), c.in/100)
+
+		// the last few samples are still in the filter.
+		if len(out) > c.out || len(out) < c.out-c.out/100 {
+			t.Fatalf("%d Hz to %d Hz returned %d samples for a second", c.in, c.out, len(out))
+		}
+	}
+}
+
+func TestResamplerRoundTrip(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		src  []int16
+	}{
+		{"dc", func() []int16 {
+			pcm := make([]int16, 48000)
+			for i := range pcm {
+				pcm[i] = 10000
+			}
+
+			return pcm
+		}()},
+		{"tone", tone(1000, 48000, 48000, 10000)},
+	} {
+		down := resample(audio.NewResampler(48000, 44100, 1), c.src, 480)
+		back := resample(audio.NewResampler(44100, 48000, 1), down, 441)
+
+		// the filters delay audio by about 1.4 ms, it's found by correlation.
+		settled := back[4800:]
+		best, lag := 0.0, 0
+
+		for l := 0; l < 200; l++ {
+			var sum float64
+
+			for i := range settled {
+				sum += float64(settled[i]) * float64(c.src[4800+i-l])
+			}
+
+			if sum > best {
+				best, lag = sum, l
+			}
+		}
+
+		var errPower, power float64
+
+		for i := range settled {
+			d := float64(settled[i]) - float64(c.src[4800+i-lag])
+			errPower += d * d
+			power += float64(c.src[4800+i-lag]) * float64(c.src[4800+i-lag])
+		}
+
+		if snr := 10 * math.Log10(power/errPower); snr < 40 {
+			t.Fatalf("%s round trip has %.1f dB SNR with %d samples of delay", c.name, snr, lag)
+		}
+	}
+}
+
+func TestResamplerSameRate(t *testing.T) {
+	src := tone(1000, 48000, 480, 10000)
+
+	out := audio.NewResampler(48000, 48000, 1).Resample(src)
+	if len(out) != len(src) || &out[0] != &src[0] {
+		t.Fatal("resampler doesn't pass audio of the same rate through")
+	}
+}
@@ -0,0 +1,197 @@
+// Package wav provides audio.Recorder and audio.Player implementations backed by RIFF/WAV files.
+// only 16 bit PCM files are supported.
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	formatPCM        = 1
+	formatExtensible = 0xFFFE
+	bitsPerSample    = 16
+	bytesPerSample   = bitsPerSample / 8
+
+	// size of a canonical wav header written by Player.
+	headerSize = 44
+)
+
+var (
+	// ErrInvalidFile occurs when file is not a valid RIFF/WAV file.
+	ErrInvalidFile = errors.New("invalid wav file")
+	// ErrUnsupportedFormat occurs when file contains audio other than 16 bit PCM.
+	ErrUnsupportedFormat = errors.New("unsupported wav format, only 16 bit PCM is supported")
+	// ErrStreamBusy occurs when opening a second stream on a Player. a wav file can only have one writer.
+	ErrStreamBusy = errors.New("wav file already has an open stream")
+	// ErrPartialFrame occurs when played data doesn't have a sample for every channel of its last frame.
+	ErrPartialFrame = errors.New("audio data has a partial frame")
+	// ErrFileTooLarge occurs when played data doesn't fit in the 4 GiB which wav chunk sizes can describe.
+	ErrFileTooLarge = errors.New("wav file is too large")
+)
+
+// Header represents the audio format of a wav file.
+type Header struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	// DataSize is size of the PCM data in bytes.
+	DataSize uint32
+}
+
+// Frames returns number of audio frames (samples per channel) in the file.
+func (h Header) Frames() int {
+	return int(h.DataSize) / (h.Channels * h.BitsPerSample / 8)
+}
+
+// ReadHeader reads the audio format of the wav file located at path.
+func ReadHeader(path string) (Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, fmt.Errorf("failed to open wav file: %w", err)
+	}
+
+	defer f.Close()
+
+	return readHeader(bufio.NewReader(f))
+}
+
+// readHeader parses RIFF chunks until it reaches the data chunk. after a successful call,
+// r is positioned at the beginning of PCM data.
+func readHeader(r io.Reader) (Header, error) {
+	var riff struct {
+		ID     [4]byte
+		Size   uint32
+		Format [4]byte
+	}
+
+	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
+		return Header{}, fmt.Errorf("%w: %s", ErrInvalidFile, err.Error())
+	}
+
+	if string(riff.ID[:]) != "RIFF" || string(riff.Format[:]) != "WAVE" {
+		return Header{}, ErrInvalidFile
+	}
+
+	var (
+		h      Header
+		hasFmt bool
+	)
+
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+
+		if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+			return Header{}, fmt.Errorf("%w: data chunk not found", ErrInvalidFile)
+		}
+
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			var format struct {
+				AudioFormat   uint16
+				Channels      uint16
+				SampleRate    uint32
+				ByteRate      uint32
+				BlockAlign    uint16
+				BitsPerSample uint16
+			}
+
+			if chunk.Size < uint32(binary.Size(format)) {
+				return Header{}, ErrInvalidFile
+			}
+
+			if err := binary.Read(r, binary.LittleEndian, &format); err != nil {
+				return Header{}, fmt.Errorf("%w: %s", ErrInvalidFile, err.Error())
+			}
+
+			if format.AudioFormat != formatPCM && format.AudioFormat != formatExtensible {
+				return Header{}, ErrUnsupportedFormat
+			}
+
+			if format.BitsPerSample != bitsPerSample || format.Channels == 0 {
+				return Header{}, ErrUnsupportedFormat
+			}
+
+			h.SampleRate = int(format.SampleRate)
+			h.Channels = int(format.Channels)
+			h.BitsPerSample = int(format.BitsPerSample)
+			hasFmt = true
+
+			if err := skip(r, chunk.Size-uint32(binary.Size(format))); err != nil {
+				return Header{}, err
+			}
+		case "data":
+			if !hasFmt {
+				return Header{}, fmt.Errorf("%w: data chunk before fmt chunk", ErrInvalidFile)
+			}
+
+			h.DataSize = chunk.Size
+
+			return h, nil
+		default:
+			if err := skip(r, chunk.Size); err != nil {
+				return Header{}, err
+			}
+		}
+	}
+}
+
+// skip discards a chunk body. chunks are padded to an even size.
+func skip(r io.Reader, size uint32) error {
+	if size%2 == 1 {
+		size++
+	}
+
+	if _, err := io.CopyN(ioutil.Discard, r, int64(size)); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidFile, err.Error())
+	}
+
+	return nil
+}
+
+// writeHeader writes a canonical 44 byte wav header.
+func writeHeader(w io.Writer, sampleRate, channels int, dataSize uint32) error {
+	header := struct {
+		RiffID        [4]byte
+		RiffSize      uint32
+		Format        [4]byte
+		FmtID         [4]byte
+		FmtSize       uint32
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		DataID        [4]byte
+		DataSize      uint32
+	}{
+		RiffID:        [4]byte{'R', 'I', 'F', 'F'},
+		RiffSize:      headerSize - 8 + dataSize,
+		Format:        [4]byte{'W', 'A', 'V', 'E'},
+		FmtID:         [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		AudioFormat:   formatPCM,
+		Channels:      uint16(channels),
+		SampleRate:    uint32(sampleRate),
+		ByteRate:      uint32(sampleRate * channels * bytesPerSample),
+		BlockAlign:    uint16(channels * bytesPerSample),
+		BitsPerSample: bitsPerSample,
+		DataID:        [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      dataSize,
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("failed to write wav header: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+// maxDataSize is the largest PCM data which RIFF chunk sizes can describe.
+const maxDataSize = math.MaxUint32 - (headerSize - 8)
+
+// Player writes played PCM data into a wav file. it implements audio.Player interface.
+// the file header is finalized when the stream is closed.
+type Player struct {
+	path     string
+	settings audio.StreamSettings
+
+	// mu is held during every stream operation, so Play doesn't race CloseStream.
+	mu     sync.Mutex
+	stream *playStream
+}
+
+type playStream struct {
+	file        *os.File
+	writer      *bufio.Writer
+	written     uint32
+	buffer      []int16
+	bufferIndex int
+	paused      bool
+	closed      bool
+}
+
+// NewPlayer creates a new Player which writes to path. the file is created on OpenStream.
+func NewPlayer(path string, settings audio.StreamSettings) (*Player, error) {
+	if settings.SampleRate <= 0 || settings.Channels <= 0 || settings.FramesPerBuffer <= 0 {
+		return nil, fmt.Errorf("invalid stream settings for wav player: %+v", settings)
+	}
+
+	return &Player{
+		path:     path,
+		settings: settings,
+	}, nil
+}
+
+// OpenStream creates the wav file. only one stream can be opened on a Player, so the stream ID is always 0.
+//
+// Make sure to close the stream after you're done using it, otherwise the file will be unplayable.
+func (p *Player) OpenStream() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stream != nil {
+		return -1, ErrStreamBusy
+	}
+
+	f, err := os.Create(p.path)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create wav file: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+
+	// sizes are unknown at this point and will be fixed in CloseStream.
+	if err := writeHeader(w, int(p.settings.SampleRate), p.settings.Channels, 0); err != nil {
+		f.Close()
+
+		return -1, err
+	}
+
+	p.stream = &playStream{
+		file:   f,
+		writer: w,
+		buffer: make([]int16, p.settings.BufferSize()),
+	}
+
+	return 0, nil
+}
+
+// CloseStream flushes remaining samples, fixes wav header sizes and closes the file.
+func (p *Player) CloseStream(streamID int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, err := p.get(streamID)
+	if err != nil {
+		return err
+	}
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	s.closed = true
+
+	if err := p.finalize(s); err != nil {
+		s.file.Close()
+
+		return err
+	}
+
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close stream %d: %w", streamID, err)
+	}
+
+	return nil
+}
+
+func (p *Player) finalize(s *playStream) error {
+	if err := s.write(s.buffer[:s.bufferIndex]); err != nil {
+		return err
+	}
+
+	if err := s.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush wav file: %w", err)
+	}
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to finalize wav file: %w", err)
+	}
+
+	return writeHeader(s.file, int(p.settings.SampleRate), p.settings.Channels, s.written)
+}
+
+// PausePlay pauses the stream, making next calls to Play return audio.ErrStreamPaused.
+func (p *Player) PausePlay(streamID int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, err := p.get(streamID)
+	if err != nil {
+		return err
+	}
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	s.paused = true
+
+	return nil
+}
+
+// ResumePlay resumes a paused stream.
+func (p *Player) ResumePlay(streamID int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, err := p.get(streamID)
+	if err != nil {
+		return err
+	}
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	if !s.paused {
+		return audio.ErrStreamNotPaused
+	}
+
+	s.paused = false
+
+	return nil
+}
+
+// Play adds given data chunk to the output buffer and writes it to file once the buffer is full.
+// use maximum chunk of `FramesPerBuffer * Channels` which has whole frames.
+func (p *Player) Play(streamID int, data []int16) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, err := p.get(streamID)
+	if err != nil {
+		return err
+	}
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	if s.paused {
+		return audio.ErrStreamPaused
+	}
+
+	if len(data) > len(s.buffer) {
+		return fmt.Errorf("audio data size is larger than audio buffer. maximum allowed size is: %d", len(s.buffer))
+	}
+
+	if len(data)%p.settings.Channels != 0 {
+		return fmt.Errorf("%w: %d samples of %d channels", ErrPartialFrame, len(data), p.settings.Channels)
+	}
+
+	// data is rejected before it's buffered, so the file can still be closed with what was played so far.
+	if uint64(s.written)+uint64((s.bufferIndex+len(data))*bytesPerSample) > maxDataSize {
+		return ErrFileTooLarge
+	}
+
+	n := copy(s.buffer[s.bufferIndex:], data)
+	s.bufferIndex += n
+
+	if s.bufferIndex == len(s.buffer) {
+		if err := s.write(s.buffer); err != nil {
+			return err
+		}
+
+		s.bufferIndex = copy(s.buffer, data[n:])
+	}
+
+	return nil
+}
+
+func (s *playStream) write(data []int16) error {
+	if err := binary.Write(s.writer, binary.LittleEndian, data); err != nil {
+		return fmt.Errorf("failed to write to wav file: %w", err)
+	}
+
+	s.written += uint32(len(data) * bytesPerSample)
+
+	return nil
+}
+
+// get returns the stream with streamID. p.mu must be held.
+func (p *Player) get(streamID int) (*playStream, error) {
+	if streamID != 0 || p.stream == nil {
+		return nil, audio.ErrStreamNotFound
+	}
+
+	return p.stream, nil
+}
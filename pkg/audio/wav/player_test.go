@@ -0,0 +1,47 @@
+package wav
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+func TestPlayerRejectsOversizedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wav")
+
+	player, err := NewPlayer(path, audio.StreamSettings{SampleRate: 8000, Channels: 1, FramesPerBuffer: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writing 4 GiB takes too long, the stream pretends it's almost full instead.
+	player.stream.written = maxDataSize - 2*bytesPerSample
+
+	if err := player.Play(id, []int16{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.Play(id, []int16{3}); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("playing past maximum size returned %v, want %v", err, ErrFileTooLarge)
+	}
+
+	if err := player.CloseStream(id); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := ReadHeader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.DataSize != maxDataSize {
+		t.Fatalf("header has data size %d, want %d", h.DataSize, uint32(maxDataSize))
+	}
+}
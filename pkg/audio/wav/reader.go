@@ -0,0 +1,104 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Reader decodes PCM data of a wav stream as fast as it's read, unlike Recorder which paces it like a sound card.
+type Reader struct {
+	r         io.Reader
+	header    Header
+	remaining uint32
+	raw       []byte
+}
+
+// NewReader reads the wav header from r and returns a Reader positioned at the beginning of PCM data.
+func NewReader(r io.Reader) (*Reader, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{
+		r:         r,
+		header:    h,
+		remaining: h.DataSize,
+	}, nil
+}
+
+// Header returns the stream's audio format.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Read reads whole frames of interleaved PCM data into pcm and returns number of samples read.
+// it returns io.EOF after the data chunk is finished, a truncated file ends at its last whole frame.
+func (r *Reader) Read(pcm []int16) (int, error) {
+	frameSize := uint32(r.header.Channels * bytesPerSample)
+	if r.remaining < frameSize {
+		return 0, io.EOF
+	}
+
+	size := uint32(len(pcm)/r.header.Channels) * frameSize
+	if size > r.remaining {
+		size = r.remaining - r.remaining%frameSize
+	}
+
+	if cap(r.raw) < int(size) {
+		r.raw = make([]byte, size)
+	}
+
+	raw := r.raw[:size]
+
+	n, err := io.ReadFull(r.r, raw)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, fmt.Errorf("failed to read wav data: %w", err)
+	}
+
+	r.remaining -= size
+	if n < len(raw) {
+		// the file is truncated.
+		r.remaining = 0
+	}
+
+	samples := n / int(frameSize) * r.header.Channels
+	if samples == 0 {
+		return 0, io.EOF
+	}
+
+	for i := 0; i < samples; i++ {
+		pcm[i] = int16(binary.LittleEndian.Uint16(raw[i*bytesPerSample:]))
+	}
+
+	return samples, nil
+}
+
+// Skip discards next frames of PCM data, it stops at the end of data chunk.
+// it seeks instead of reading when the underlying reader is an io.Seeker, i.e. an unbuffered *os.File.
+func (r *Reader) Skip(frames int) error {
+	frameSize := uint32(r.header.Channels * bytesPerSample)
+
+	size := uint64(frames) * uint64(frameSize)
+	if size > uint64(r.remaining) {
+		size = uint64(r.remaining - r.remaining%frameSize)
+	}
+
+	var err error
+
+	if seeker, ok := r.r.(io.Seeker); ok {
+		_, err = seeker.Seek(int64(size), io.SeekCurrent)
+	} else {
+		_, err = io.CopyN(ioutil.Discard, r.r, int64(size))
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to skip wav data: %w", err)
+	}
+
+	r.remaining -= uint32(size)
+
+	return nil
+}
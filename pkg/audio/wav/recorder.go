@@ -0,0 +1,201 @@
+package wav
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/smf8/kenny/pkg/audio"
+)
+
+// Recorder reads PCM data from a wav file. it implements audio.Recorder interface.
+// each stream reads the file from the beginning independently.
+// if the file has a different channel count than stream settings, it is remixed, and if it has a different
+// sample rate, it is resampled. Record is paced by the buffer duration like a sound card, so a file can replace
+// a microphone. use Reader to decode a file as fast as possible.
+type Recorder struct {
+	path       string
+	settings   audio.StreamSettings
+	channels   int
+	sampleRate int
+	// frames is number of file frames in every buffer, it keeps the buffer duration when the file is resampled.
+	frames int
+
+	mu      sync.Mutex
+	streams []*recordStream
+}
+
+type recordStream struct {
+	file      *os.File
+	reader    *Reader
+	resampler *audio.Resampler
+	clock     audio.Clock
+	samples   []int16
+	buffer    []int16
+	paused    bool
+	closed    bool
+}
+
+// NewRecorder creates a new Recorder for the wav file located at path.
+func NewRecorder(path string, settings audio.StreamSettings) (*Recorder, error) {
+	if settings.SampleRate <= 0 || settings.Channels <= 0 || settings.FramesPerBuffer <= 0 {
+		return nil, fmt.Errorf("invalid stream settings for wav recorder: %+v", settings)
+	}
+
+	h, err := ReadHeader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := int(math.Round(float64(settings.FramesPerBuffer) * float64(h.SampleRate) / settings.SampleRate))
+	if frames < 1 {
+		frames = 1
+	}
+
+	return &Recorder{
+		path:       path,
+		settings:   settings,
+		channels:   h.Channels,
+		sampleRate: h.SampleRate,
+		frames:     frames,
+	}, nil
+}
+
+// OpenStream opens the file and assigns an ID to it.
+// Make sure to close the stream after you are done using it.
+func (r *Recorder) OpenStream() (int, error) {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open wav file: %w", err)
+	}
+
+	reader, err := NewReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+
+		return -1, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.streams = append(r.streams, &recordStream{
+		file:      f,
+		reader:    reader,
+		resampler: audio.NewResampler(r.sampleRate, int(r.settings.SampleRate), r.settings.Channels),
+		clock: audio.NewClock(audio.StreamSettings{
+			SampleRate:      float64(r.sampleRate),
+			FramesPerBuffer: r.frames,
+		}),
+		samples: make([]int16, r.frames*r.channels),
+		buffer:  make([]int16, r.frames*r.settings.Channels),
+	})
+
+	return len(r.streams) - 1, nil
+}
+
+// CloseStream closes the stream's file.
+func (r *Recorder) CloseStream(streamID int) error {
+	s, err := r.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	s.closed = true
+
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close stream %d: %w", streamID, err)
+	}
+
+	return nil
+}
+
+// PauseRecord pauses the stream, making next calls to Record return audio.ErrStreamPaused.
+func (r *Recorder) PauseRecord(streamID int) error {
+	s, err := r.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	s.paused = true
+
+	return nil
+}
+
+// ResumeRecord resumes a paused stream.
+func (r *Recorder) ResumeRecord(streamID int) error {
+	s, err := r.stream(streamID)
+	if err != nil {
+		return err
+	}
+
+	if s.closed {
+		return audio.ErrStreamClosed
+	}
+
+	if !s.paused {
+		return audio.ErrStreamNotPaused
+	}
+
+	s.paused = false
+	s.clock.Reset()
+
+	return nil
+}
+
+// Record reads next buffer from file, it blocks until the previous buffer's duration is elapsed.
+// buffers have `FramesPerBuffer * Channels` samples, when the file is resampled their length varies slightly.
+// the last chunk is padded with silence and next calls return io.EOF.
+//
+// the returned slice will be changed in next calls to Record, So use copy to store it.
+func (r *Recorder) Record(streamID int) ([]int16, error) {
+	s, err := r.stream(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.closed {
+		return nil, audio.ErrStreamClosed
+	}
+
+	if s.paused {
+		return nil, audio.ErrStreamPaused
+	}
+
+	n, err := s.reader.Read(s.samples)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := n; i < len(s.samples); i++ {
+		s.samples[i] = 0
+	}
+
+	data := s.resampler.Resample(audio.Remix(s.buffer, s.samples, r.channels, r.settings.Channels))
+
+	time.Sleep(s.clock.Advance())
+
+	return data, nil
+}
+
+func (r *Recorder) stream(streamID int) (*recordStream, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if streamID < 0 || streamID >= len(r.streams) {
+		return nil, audio.ErrStreamNotFound
+	}
+
+	return r.streams[streamID], nil
+}
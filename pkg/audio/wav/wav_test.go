@@ -0,0 +1,255 @@
+package wav_test
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/smf8/kenny/pkg/audio"
+	"github.com/smf8/kenny/pkg/audio/wav"
+)
+
+// writeFile plays pcm on a wav.Player and returns the file's path.
+func writeFile(t *testing.T, sampleRate, channels int, pcm []int16) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.wav")
+
+	player, err := wav.NewPlayer(path, audio.StreamSettings{
+		SampleRate:      float64(sampleRate),
+		Channels:        channels,
+		FramesPerBuffer: 64,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(pcm); i += 64 * channels {
+		end := i + 64*channels
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+
+		if err := player.Play(id, pcm[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := player.CloseStream(id); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func sine(frequency float64, sampleRate, frames int) []int16 {
+	pcm := make([]int16, frames)
+	for i := range pcm {
+		pcm[i] = int16(8000 * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+	}
+
+	return pcm
+}
+
+func TestReaderRoundTrip(t *testing.T) {
+	want := []int16{1, -1, 2, -2, 3, -3, 4, -4, 5, -5}
+	path := writeFile(t, 8000, 2, want)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	reader, err := wav.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h := reader.Header(); h.SampleRate != 8000 || h.Channels != 2 || h.Frames() != 5 {
+		t.Fatalf("read header %+v", h)
+	}
+
+	var got []int16
+
+	// 3 samples only have room for a single stereo frame.
+	chunk := make([]int16, 3)
+
+	for {
+		n, err := reader.Read(chunk)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 2 {
+			t.Fatalf("read %d samples, want a whole frame", n)
+		}
+
+		got = append(got, chunk[:n]...)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("read %v, want %v", got, want)
+	}
+}
+
+// TestRecorderResamplesInRealtime records 200 ms of a 44.1 kHz mono file as a 48 kHz stereo stream.
+func TestRecorderResamplesInRealtime(t *testing.T) {
+	path := writeFile(t, 44100, 1, sine(1000, 44100, 8820))
+
+	settings := audio.StreamSettings{SampleRate: 48000, Channels: 2, FramesPerBuffer: 480}
+
+	recorder, err := wav.NewRecorder(path, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := recorder.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer recorder.CloseStream(id)
+
+	var recorded []int16
+
+	start := time.Now()
+
+	for {
+		data, err := recorder.Record(id)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorded = append(recorded, data...)
+	}
+
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond || elapsed > 400*time.Millisecond {
+		t.Fatalf("recording 200 ms took %s", elapsed)
+	}
+
+	// 20 buffers of 441 file frames, less the resampler's delay.
+	if frames := len(recorded) / 2; frames < 9500 || frames > 9600 {
+		t.Fatalf("recorded %d frames, want about 9600", frames)
+	}
+
+	for i := 0; i < len(recorded); i += 2 {
+		if recorded[i] != recorded[i+1] {
+			t.Fatalf("frame %d isn't the same in both channels", i/2)
+		}
+	}
+
+	// the tone keeps its frequency at the new rate. the resampler delays it by about 0.7 ms.
+	want := sine(1000, 48000, len(recorded)/2)
+	best := -1.0
+
+	for delay := 0; delay < 64; delay++ {
+		var dot, rr, ww float64
+
+		for i := 100; i < len(want); i++ {
+			r, w := float64(recorded[2*i]), float64(want[i-delay])
+			dot += r * w
+			rr += r * r
+			ww += w * w
+		}
+
+		best = math.Max(best, dot/math.Sqrt(rr*ww))
+	}
+
+	if best < 0.99 {
+		t.Fatalf("recorded audio correlates %.3f with the resampled tone", best)
+	}
+}
+
+func TestPlayerRejectsPartialFrames(t *testing.T) {
+	player, err := wav.NewPlayer(filepath.Join(t.TempDir(), "test.wav"), audio.StreamSettings{
+		SampleRate:      8000,
+		Channels:        2,
+		FramesPerBuffer: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.Play(id, []int16{1, 2, 3}); !errors.Is(err, wav.ErrPartialFrame) {
+		t.Fatalf("playing 3 stereo samples returned %v, want %v", err, wav.ErrPartialFrame)
+	}
+
+	if err := player.CloseStream(id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPlayerCloseWhilePlaying closes the stream while another goroutine plays, it's meant to run with -race.
+func TestPlayerCloseWhilePlaying(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wav")
+
+	player, err := wav.NewPlayer(path, audio.StreamSettings{SampleRate: 8000, Channels: 1, FramesPerBuffer: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := player.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	played := make(chan int)
+
+	go func() {
+		n := 0
+
+		for ; ; n += 4 {
+			if err := player.Play(id, []int16{1, 2, 3, 4}); err != nil {
+				if !errors.Is(err, audio.ErrStreamClosed) {
+					t.Error(err)
+				}
+
+				break
+			}
+		}
+
+		played <- n
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	if err := player.CloseStream(id); err != nil {
+		t.Fatal(err)
+	}
+
+	n := <-played
+
+	h, err := wav.ReadHeader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.Frames() != n {
+		t.Fatalf("file has %d frames, played %d", h.Frames(), n)
+	}
+}